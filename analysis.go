@@ -0,0 +1,21 @@
+package astor
+
+import (
+	"golang.org/x/tools/go/analysis"
+)
+
+// InspectPass walks each of the files of pass with an Inspector constructed with v and opts, in order, so an analyzer
+// can be written as a Visitor: v reports what it finds with pass.Reportf, and can look up the types of expressions
+// with the Inspector's TypeOf, which uses pass.TypesInfo. The Inspector is shared by all the walks.
+//
+// The files of a pass are shared with the other analyzers, so mustn't be changed: the walks are dry runs, as with
+// InspectDryRun, and the changes v asks for (such as replacements it would suggest as fixes) are returned instead, in
+// the order they were asked for.
+func InspectPass(pass *analysis.Pass, v Visitor, opts ...Option) []Edit {
+	i := NewInspectorWithTypes(v, pass.TypesInfo, opts...)
+	var edits []Edit
+	for _, f := range pass.Files {
+		edits = append(edits, InspectDryRun(i, f)...)
+	}
+	return edits
+}
@@ -0,0 +1,56 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis"
+)
+
+func TestInspectPass(t *testing.T) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, src := range []string{
+		"package foo\n\nfunc A() string { return \"a\" + \"b\" }\n",
+		"package foo\n\nfunc B() int { return 1 + 2 }\n",
+	} {
+		f, err := parser.ParseFile(fset, fmt.Sprintf("%c.go", 'a'+len(files)), src, parserFlags)
+		assert.NoError(t, err, "Error parsing input")
+		files = append(files, f)
+	}
+	info := &types.Info{Types: map[ast.Expr]types.TypeAndValue{}}
+	pkg, err := (&types.Config{Importer: importer.Default()}).Check("foo", fset, files, info)
+	assert.NoError(t, err)
+
+	var diagnostics []analysis.Diagnostic
+	pass := &analysis.Pass{
+		Fset:      fset,
+		Files:     files,
+		Pkg:       pkg,
+		TypesInfo: info,
+		Report:    func(d analysis.Diagnostic) { diagnostics = append(diagnostics, d) },
+	}
+	// report concatenations of strings, and suggest folding them
+	edits := InspectPass(pass, func(i Inspector, n ast.Node) bool {
+		if n, ok := n.(*ast.BinaryExpr); ok && types.Identical(i.TypeOf(n), types.Typ[types.String]) {
+			pass.Reportf(n.Pos(), "constant concatenation")
+			i.Replace(&ast.BasicLit{ValuePos: n.Pos(), Kind: token.STRING, Value: `"ab"`})
+		}
+		return true
+	})
+
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, "a.go:3:26", fset.Position(diagnostics[0].Pos).String())
+	assert.Equal(t, "constant concatenation", diagnostics[0].Message)
+	assert.Len(t, edits, 1)
+	assert.Equal(t, EditReplace, edits[0].Kind)
+	assert.IsType(t, &ast.BinaryExpr{}, edits[0].Old)
+	// the files are unchanged
+	assert.IsType(t, &ast.BinaryExpr{}, files[0].Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ReturnStmt).Results[0])
+}
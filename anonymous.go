@@ -0,0 +1,39 @@
+package astor
+
+import (
+	"go/ast"
+)
+
+// IsAnonymousType reports whether the node i is visiting is an anonymous struct or interface type: an *ast.StructType
+// or *ast.InterfaceType written inline, such as the type of a variable, field or parameter, or of a composite literal,
+// rather than as the type a type declaration (including an alias) gives a name to. The parentheses around a declared
+// type, as in type T (struct{}), don't make it anonymous. Note that interface{}, and constraints such as those in
+// [T interface{ ~int }], are anonymous interface types.
+//
+// For an Inspector not constructed by this package, parentheses can't be seen through, so a parenthesised declared
+// type is reported as anonymous.
+func IsAnonymousType(i Inspector) bool {
+	switch i.Current().(type) {
+	case *ast.StructType, *ast.InterfaceType:
+	default:
+		return false
+	}
+
+	impl, ok := i.(*inspectorImpl)
+	if !ok {
+		_, named := i.Parent().(*ast.TypeSpec)
+		return !named
+	}
+	// the Type is the only field of a TypeSpec which can hold a type, so any TypeSpec holding the node names it
+	l := len(impl.ancestors) - 1
+	for ; l >= 0; l-- {
+		if _, paren := impl.ancestors[l].(*ast.ParenExpr); !paren {
+			break
+		}
+	}
+	if l < 0 {
+		return true
+	}
+	_, named := impl.ancestors[l].(*ast.TypeSpec)
+	return !named
+}
@@ -0,0 +1,42 @@
+package astor
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsAnonymousType(t *testing.T) {
+	f := parseEqualSrc(t, `package foo
+
+type A struct{ b struct{} }
+
+type B = interface{ M() }
+
+type C (struct{})
+
+var d struct{ x int }
+
+func E[T interface{ ~int }](x interface{}) {
+	_ = struct{}{}
+}
+`)
+
+	var named, anonymous int
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		switch n.(type) {
+		case *ast.StructType, *ast.InterfaceType:
+			if IsAnonymousType(i) {
+				anonymous++
+			} else {
+				named++
+			}
+		default:
+			assert.False(t, IsAnonymousType(i))
+		}
+		return true
+	}).Inspect(f)
+	assert.Equal(t, 3, named)
+	assert.Equal(t, 5, anonymous)
+}
@@ -0,0 +1,335 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// ApplyFuncs returns pre and post functions for astutil.Apply which drive v, so a Visitor can be used where code
+// expects to walk an AST with astutil. v is called with each node from pre, and with nil from post, and its Inspector
+// changes the AST through the astutil.Cursor. astutil can't do everything an Inspector can, so there are differences:
+//
+//   - a node which v replaces or deletes isn't walked any further (astutil would walk the children of the node it
+//     replaced), and nor is it visited again with nil
+//   - ReplaceAndRevisit, SkipField and Visit panic, as do the methods of an Inspector constructed with Options, since
+//     there are none; Err is always nil, ID is always 0, and Scope and TypeOf always nil
+//   - Inspect walks its node with astutil.Apply
+func ApplyFuncs(v Visitor) (pre, post astutil.ApplyFunc) {
+	i := &cursorInspector{v: v}
+	return i.pre, i.post
+}
+
+// A cursorInspector is an Inspector which changes the AST through an astutil.Cursor.
+type cursorInspector struct {
+	v        Visitor
+	c        *astutil.Cursor
+	node     ast.Node
+	original ast.Node
+	// skipping is the node whose children are being skipped, if any
+	skipping ast.Node
+	skip     bool
+	stopped  bool
+	dirty    bool
+	data     map[ast.Node]map[interface{}]interface{}
+	// funcs are the functions whose children are being walked, innermost last
+	funcs []ast.Node
+}
+
+func (i *cursorInspector) pre(c *astutil.Cursor) bool {
+	if i.stopped || i.skipping != nil {
+		return false
+	}
+	i.c, i.node, i.original, i.skip = c, c.Node(), c.Node(), false
+	result := i.v(i, c.Node())
+	switch {
+	case i.stopped || !result || i.node != i.original:
+		return false
+	case i.skip:
+		i.skipping = i.original
+	}
+	switch n := i.original.(type) {
+	case *ast.FuncDecl, *ast.FuncLit:
+		i.funcs = append(i.funcs, n)
+	}
+	return true
+}
+
+func (i *cursorInspector) post(c *astutil.Cursor) bool {
+	if i.skipping != nil && i.skipping != c.Node() {
+		return true
+	}
+	i.skipping = nil
+	if l := len(i.funcs); l > 0 && i.funcs[l-1] == c.Node() {
+		i.funcs = i.funcs[:l-1]
+	}
+	i.c, i.node, i.original = c, nil, c.Node()
+	i.v(i, nil)
+	return !i.stopped
+}
+
+func (i *cursorInspector) unsupported(method string) {
+	panic(fmt.Sprintf("astor: %s isn't supported when walking with astutil.Apply", method))
+}
+
+func (i *cursorInspector) Current() ast.Node {
+	return i.node
+}
+
+func (i *cursorInspector) Original() ast.Node {
+	return i.original
+}
+
+func (i *cursorInspector) Replace(n ast.Node) {
+	i.c.Replace(n)
+	i.node, i.dirty = n, true
+}
+
+func (i *cursorInspector) ReplaceAndRevisit(ast.Node) {
+	i.unsupported("ReplaceAndRevisit")
+}
+
+func (i *cursorInspector) WrapExpr(f func(ast.Expr) ast.Expr) {
+	i.Replace(f(i.node.(ast.Expr)))
+}
+
+func (i *cursorInspector) WrapStmt(f func(ast.Stmt) ast.Stmt) {
+	i.Replace(f(i.node.(ast.Stmt)))
+}
+
+func (i *cursorInspector) SkipChildren() {
+	i.skip = true
+}
+
+func (i *cursorInspector) SkipField(string) {
+	i.unsupported("SkipField")
+}
+
+func (i *cursorInspector) Delete() {
+	i.c.Delete()
+	i.node, i.dirty = nil, true
+}
+
+func (i *cursorInspector) InsertBefore(n ast.Node) {
+	i.c.InsertBefore(n)
+	i.dirty = true
+}
+
+func (i *cursorInspector) InsertAfter(n ast.Node) {
+	i.c.InsertAfter(n)
+	i.dirty = true
+}
+
+func (i *cursorInspector) Stop() {
+	i.stopped = true
+}
+
+func (i *cursorInspector) Err() error {
+	return nil
+}
+
+func (i *cursorInspector) Dirty() bool {
+	return i.dirty
+}
+
+func (i *cursorInspector) Parent() ast.Node {
+	return i.c.Parent()
+}
+
+func (i *cursorInspector) EnclosingFunc() ast.Node {
+	if len(i.funcs) == 0 {
+		return nil
+	}
+	return i.funcs[len(i.funcs)-1]
+}
+
+func (i *cursorInspector) Index() int {
+	return i.c.Index()
+}
+
+func (i *cursorInspector) PrevSibling() ast.Node {
+	return i.sibling(-1)
+}
+
+func (i *cursorInspector) NextSibling() ast.Node {
+	return i.sibling(1)
+}
+
+func (i *cursorInspector) sibling(offset int) ast.Node {
+	if i.c.Index() < 0 {
+		return nil
+	}
+	list := reflect.ValueOf(i.c.Parent()).Elem().FieldByName(i.c.Name())
+	l := i.c.Index() + offset
+	if l < 0 || l >= list.Len() {
+		return nil
+	}
+	n, _ := list.Index(l).Interface().(ast.Node)
+	return n
+}
+
+func (i *cursorInspector) FieldName() string {
+	if i.c.Parent() == nil {
+		return ""
+	}
+	if i.c.Index() >= 0 {
+		return fmt.Sprintf("%s[%d]", i.c.Name(), i.c.Index())
+	}
+	return i.c.Name()
+}
+
+func (i *cursorInspector) ID(ast.Node) uint64 {
+	return 0
+}
+
+func (i *cursorInspector) SetData(n ast.Node, key, value interface{}) {
+	if i.data == nil {
+		i.data = make(map[ast.Node]map[interface{}]interface{})
+	}
+	if i.data[n] == nil {
+		i.data[n] = make(map[interface{}]interface{})
+	}
+	i.data[n][key] = value
+}
+
+func (i *cursorInspector) Data(n ast.Node, key interface{}) (interface{}, bool) {
+	value, ok := i.data[n][key]
+	return value, ok
+}
+
+func (i *cursorInspector) Scope() *Scope {
+	return nil
+}
+
+func (i *cursorInspector) TypeOf(ast.Expr) types.Type {
+	return nil
+}
+
+func (i *cursorInspector) Inspect(node ast.Node) ast.Node {
+	i.stopped, i.skipping, i.funcs = false, nil, nil
+	return astutil.Apply(node, i.pre, i.post)
+}
+
+func (i *cursorInspector) Clone() Inspector {
+	return &cursorInspector{v: i.v}
+}
+
+func (i *cursorInspector) Visit(ast.Node) (ast.Node, Inspector) {
+	i.unsupported("Visit")
+	return nil, nil
+}
+
+// An ApplyFunc is called by Apply for each node, like an astutil.ApplyFunc.
+type ApplyFunc func(c *Cursor) bool
+
+// Apply walks the AST rooted at root with an Inspector, calling pre and post (either of which may be nil) like
+// astutil.Apply: pre as each node is visited, and post once its children have been. If pre returns false, the node's
+// children aren't walked and post isn't called for it; if post returns false, the walk stops. It returns the modified
+// tree. This lets code written against astutil.Apply be moved to this package by changing the type of its Cursor.
+//
+// Unlike astutil.Apply, a node replaced in pre is walked no further, rather than walking the children of the node it
+// replaced; and a node deleted in pre isn't passed to post. A node replaced in post takes the place of the node left.
+func Apply(root ast.Node, pre, post ApplyFunc) ast.Node {
+	return NewInspector(func(i Inspector, n ast.Node) bool {
+		c := &Cursor{i: i}
+		if n == nil {
+			if post != nil && !post(c) {
+				i.Stop()
+			}
+			return true
+		}
+		if pre != nil && !pre(c) {
+			return false
+		}
+		if i.Current() != n {
+			i.SkipChildren()
+		}
+		return true
+	}).Inspect(root)
+}
+
+// A CursorVisitor is called by an Inspector constructed with NewCursorInspector for each node, with a Cursor
+// describing it, and returns whether the node's children should be walked.
+type CursorVisitor func(c *Cursor) bool
+
+// NewCursorInspector constructs an Inspector with the passed Options which calls v for each node it visits, with a
+// Cursor rather than the Inspector itself, for code which would rather use the methods of an astutil.Cursor. v is only
+// called as each node is visited, like the pre function of Apply: it isn't called with nil once the node's children
+// have been walked. As with Apply, the children of a node v replaces aren't walked. The Cursor's Inspector method
+// gives the Inspector, for the things only it can do.
+func NewCursorInspector(v CursorVisitor, opts ...Option) Inspector {
+	return NewInspector(func(i Inspector, n ast.Node) bool {
+		if n == nil {
+			return true
+		}
+		if !v(&Cursor{i: i}) {
+			return false
+		}
+		if i.Current() != n {
+			i.SkipChildren()
+		}
+		return true
+	}, opts...)
+}
+
+// A Cursor describes the node being visited by Apply, or by an Inspector constructed with NewCursorInspector, and can
+// change it, with the same methods as an astutil.Cursor.
+type Cursor struct {
+	i Inspector
+}
+
+// Inspector returns the Inspector walking the AST, for the things only it can do.
+func (c *Cursor) Inspector() Inspector {
+	return c.i
+}
+
+// Node returns the node being visited. In post, this is the node as it was before pre replaced it.
+func (c *Cursor) Node() ast.Node {
+	if n := c.i.Current(); n != nil {
+		return n
+	}
+	return c.i.Original()
+}
+
+// Parent returns the parent of the node being visited.
+func (c *Cursor) Parent() ast.Node {
+	return c.i.Parent()
+}
+
+// Name returns the name of the field of the parent holding the node being visited, without its index.
+func (c *Cursor) Name() string {
+	name := c.i.FieldName()
+	if l := strings.IndexByte(name, '['); l >= 0 {
+		return name[:l]
+	}
+	return name
+}
+
+// Index returns the position of the node being visited in the slice holding it, or -1 if it isn't held in one.
+func (c *Cursor) Index() int {
+	return c.i.Index()
+}
+
+// Replace replaces the node being visited with n, as Inspector.Replace does.
+func (c *Cursor) Replace(n ast.Node) {
+	c.i.Replace(n)
+}
+
+// Delete deletes the node being visited from the slice holding it, as Inspector.Delete does.
+func (c *Cursor) Delete() {
+	c.i.Delete()
+}
+
+// InsertBefore inserts n before the node being visited in the slice holding it, as Inspector.InsertBefore does.
+func (c *Cursor) InsertBefore(n ast.Node) {
+	c.i.InsertBefore(n)
+}
+
+// InsertAfter inserts n after the node being visited in the slice holding it, as Inspector.InsertAfter does.
+func (c *Cursor) InsertAfter(n ast.Node) {
+	c.i.InsertAfter(n)
+}
@@ -0,0 +1,149 @@
+package astor
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+const applySrc = `package foo
+
+func Bar() {
+	a()
+	b()
+	func() { a() }()
+}
+`
+
+func formatApply(t *testing.T, f func(*ast.File) ast.Node) string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "apply.go", applySrc, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	var out strings.Builder
+	assert.NoError(t, format.Node(&out, fset, f(file)))
+	return out.String()
+}
+
+func TestApplyFuncs(t *testing.T) {
+	var fields []string
+	out := formatApply(t, func(f *ast.File) ast.Node {
+		pre, post := ApplyFuncs(func(i Inspector, n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				i.SkipChildren()
+			case *ast.Ident:
+				fields = append(fields, i.FieldName())
+				if n.Name == "a" {
+					i.Replace(ast.NewIdent("x"))
+				}
+			case *ast.ExprStmt:
+				if fun, ok := n.X.(*ast.CallExpr).Fun.(*ast.Ident); ok && fun.Name == "b" {
+					assert.Equal(t, 1, i.Index())
+					assert.IsType(t, &ast.FuncLit{}, i.NextSibling().(*ast.ExprStmt).X.(*ast.CallExpr).Fun)
+					i.InsertBefore(&ast.ExprStmt{X: &ast.CallExpr{Fun: &ast.Ident{NamePos: n.Pos(), Name: "c"}}})
+					i.Delete()
+				}
+			}
+			return true
+		})
+		return astutil.Apply(f, pre, post)
+	})
+	assert.Equal(t, "package foo\n\nfunc Bar() {\n\tx()\n\tc()\n\tfunc() { a() }()\n}\n", out)
+	assert.Equal(t, []string{"Name", "Name", "Fun"}, fields)
+
+	var visited int
+	pre, post := ApplyFuncs(func(i Inspector, n ast.Node) bool {
+		if n != nil {
+			visited++
+			i.Stop()
+		}
+		return true
+	})
+	astutil.Apply(parseEqualSrc(t, applySrc), pre, post)
+	assert.Equal(t, 1, visited)
+}
+
+func TestApply(t *testing.T) {
+	var names []string
+	out := formatApply(t, func(f *ast.File) ast.Node {
+		return Apply(f, func(c *Cursor) bool {
+			switch n := c.Node().(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.Ident:
+				names = append(names, c.Name())
+				if n.Name == "a" {
+					c.Replace(ast.NewIdent("x"))
+				}
+			case *ast.ExprStmt:
+				if fun, ok := n.X.(*ast.CallExpr).Fun.(*ast.Ident); ok && fun.Name == "b" {
+					assert.Equal(t, "List", c.Name())
+					assert.Equal(t, 1, c.Index())
+					assert.IsType(t, &ast.BlockStmt{}, c.Parent())
+					c.InsertAfter(&ast.ExprStmt{X: &ast.CallExpr{Fun: &ast.Ident{NamePos: n.Pos(), Name: "c"}}})
+					c.Delete()
+				}
+			}
+			return true
+		}, nil)
+	})
+	assert.Equal(t, "package foo\n\nfunc Bar() {\n\tx()\n\tc()\n\tfunc() { a() }()\n}\n", out)
+	assert.Equal(t, []string{"Name", "Name", "Fun"}, names)
+
+	var posts []ast.Node
+	Apply(parseEqualSrc(t, applySrc), nil, func(c *Cursor) bool {
+		posts = append(posts, c.Node())
+		_, ok := c.Node().(*ast.Ident)
+		return !ok
+	})
+	// the walk stops after the first node it leaves, the package name
+	assert.Len(t, posts, 1)
+	assert.Equal(t, "foo", posts[0].(*ast.Ident).Name)
+}
+
+func TestApplyReplaceInPost(t *testing.T) {
+	f := parseEqualSrc(t, "package foo\n\nvar x = a + b\n")
+	Apply(f, nil, func(c *Cursor) bool {
+		if b, ok := c.Node().(*ast.BinaryExpr); ok {
+			assert.Equal(t, "Values", c.Name())
+			c.Replace(&ast.BinaryExpr{X: b.Y, Op: b.Op, Y: b.X})
+		}
+		return true
+	})
+	assert.Empty(t, Diff(parseEqualSrc(t, "package foo\n\nvar x = b + a\n"), f))
+}
+
+func TestNewCursorInspector(t *testing.T) {
+	var visited int
+	var replaced []string
+	out := formatApply(t, func(f *ast.File) ast.Node {
+		return NewCursorInspector(func(c *Cursor) bool {
+			assert.NotNil(t, c.Node())
+			visited++
+			switch n := c.Node().(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.CallExpr:
+				if fun, ok := n.Fun.(*ast.Ident); ok && fun.Name == "a" {
+					c.Replace(&ast.CallExpr{Fun: &ast.Ident{NamePos: fun.NamePos, Name: "x"}})
+				}
+			case *ast.Ident:
+				replaced = append(replaced, n.Name)
+				assert.Equal(t, c.Inspector().Current(), n)
+			}
+			return true
+		}, OnReplace(func(old, new ast.Node) {
+			replaced = append(replaced, "replaced")
+		})).Inspect(f)
+	})
+	assert.Equal(t, "package foo\n\nfunc Bar() {\n\tx()\n\tb()\n\tfunc() { a() }()\n}\n", out)
+	// the replacement's children aren't walked
+	assert.Equal(t, []string{"foo", "Bar", "replaced", "b"}, replaced)
+	assert.Equal(t, 15, visited)
+}
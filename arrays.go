@@ -0,0 +1,27 @@
+package astor
+
+import (
+	"go/ast"
+)
+
+// ArrayKind returns the kind of type n is, which depends on its Len: "slice" for a slice type such as []T, which has no
+// Len; "array" for an array type with its length given, such as [4]T or [N]T; or "inferred-array" for an array type
+// whose length is that of the composite literal it is the type of, such as [...]T{a, b}, whose Len is an
+// *ast.Ellipsis.
+func ArrayKind(n *ast.ArrayType) (kind string) {
+	switch n.Len.(type) {
+	case nil:
+		return "slice"
+	case *ast.Ellipsis:
+		return "inferred-array"
+	}
+	return "array"
+}
+
+// IsArrayLen reports whether the node i is visiting is the length of an array type: the Len of an *ast.ArrayType,
+// which is an *ast.Ellipsis for an array whose length is inferred. An expression such as the N in [N]T is a constant
+// expression which must stay one, so a Visitor rewriting expressions may need to leave it alone.
+func IsArrayLen(i Inspector) bool {
+	_, ok := i.Parent().(*ast.ArrayType)
+	return ok && i.Current() != nil && i.FieldName() == "Len"
+}
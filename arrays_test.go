@@ -0,0 +1,32 @@
+package astor
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArrayKind(t *testing.T) {
+	f := parseEqualSrc(t, "package foo\n\nconst N = 2\n\nvar (\n\ta []int\n\tb [4]int\n\tc [N * 2]int\n"+
+		"\td = [...]string{\"x\", \"y\"}\n\te = [][N]int{}\n)\n")
+
+	var kinds, lens []string
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		if n == nil {
+			return true
+		}
+		if t, ok := n.(*ast.ArrayType); ok {
+			kinds = append(kinds, ArrayKind(t))
+		}
+		if IsArrayLen(i) {
+			lens = append(lens, Sequence(n)[0])
+		}
+		return true
+	}).Inspect(f)
+	assert.Equal(t, []string{"slice", "array", "array", "inferred-array", "slice", "array"}, kinds)
+	assert.Equal(t, []string{"*ast.BasicLit Kind=INT Value=\"4\"", "*ast.BinaryExpr Op=*", "*ast.Ellipsis",
+		"*ast.Ident Name=\"N\""}, lens)
+
+	assert.Equal(t, "inferred-array", ArrayKind(&ast.ArrayType{Len: &ast.Ellipsis{}, Elt: ast.NewIdent("int")}))
+}
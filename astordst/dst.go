@@ -0,0 +1,180 @@
+// Package astordst walks decorated syntax trees from github.com/dave/dst with an astor.Inspector, so that a Visitor
+// can change a file without losing the author's formatting: the line breaks, spacing and comment placement which
+// go/format would otherwise reflow.
+//
+// The file is restored to a go/ast tree, which is walked as usual; the result is then written back into the dst tree.
+// Nodes which survive the walk keep their decorations, nodes added by the Visitor are created without any (other than
+// being placed on lines of their own, if they are statements or declarations), and nodes removed by it take their
+// decorations (including their comments) with them. Since comments are held by the nodes they are attached to, the
+// Visitor should not change the *ast.CommentGroup nodes of the restored file: such changes are not written back.
+package astordst
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+	"github.com/obeattie/astor"
+)
+
+// Inspect walks the dst file f with i, writing the changes made by its Visitor back into f, which it returns. An
+// error is returned if f can't be restored to a go/ast tree, or if the Visitor leaves a node which has no dst
+// equivalent. If the walk ends with an error (see astor.Inspector.Err), that error is returned and f is left as it
+// was.
+func Inspect(i astor.Inspector, f *dst.File) (*dst.File, error) {
+	r := decorator.NewRestorer()
+	af, err := r.RestoreFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	result := i.Inspect(af)
+	if err := i.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := result.(*ast.File); !ok {
+		return nil, fmt.Errorf("astordst: the Visitor replaced the file with %T", result)
+	}
+	s := &syncer{decorated: r.Dst.Nodes, done: map[ast.Node]dst.Node{}}
+	df, err := s.node(result)
+	if err != nil {
+		return nil, err
+	}
+	return df.(*dst.File), nil
+}
+
+// InspectSource is astor.InspectSource preserving the formatting of src: it parses src as a decorated file, walks it
+// with an Inspector constructed with the passed Visitor and Options, and returns the modified file printed as source.
+// If src can't be parsed the error is an *astor.ParseError, and if the modified file can't be printed it is an
+// *astor.FormatError.
+func InspectSource(src []byte, v astor.Visitor, opts ...astor.Option) ([]byte, error) {
+	f, err := decorator.Parse(src)
+	if err != nil {
+		return nil, &astor.ParseError{Err: err}
+	}
+	f, err = Inspect(astor.NewInspector(v, opts...), f)
+	if err != nil {
+		return nil, err
+	}
+
+	out := new(bytes.Buffer)
+	if err := decorator.Fprint(out, f); err != nil {
+		return nil, &astor.FormatError{Err: err}
+	}
+	return out.Bytes(), nil
+}
+
+var (
+	posType      = reflect.TypeOf(token.NoPos)
+	astNodeType  = reflect.TypeOf((*ast.Node)(nil)).Elem()
+	dstNodeTypes = map[string]reflect.Type{}
+)
+
+func init() {
+	for _, n := range []dst.Node{
+		&dst.Field{}, &dst.FieldList{}, &dst.BadExpr{}, &dst.Ident{}, &dst.Ellipsis{}, &dst.BasicLit{}, &dst.FuncLit{},
+		&dst.CompositeLit{}, &dst.ParenExpr{}, &dst.SelectorExpr{}, &dst.IndexExpr{}, &dst.IndexListExpr{},
+		&dst.SliceExpr{}, &dst.TypeAssertExpr{}, &dst.CallExpr{}, &dst.StarExpr{}, &dst.UnaryExpr{}, &dst.BinaryExpr{},
+		&dst.KeyValueExpr{}, &dst.ArrayType{}, &dst.StructType{}, &dst.FuncType{}, &dst.InterfaceType{}, &dst.MapType{},
+		&dst.ChanType{}, &dst.BadStmt{}, &dst.DeclStmt{}, &dst.EmptyStmt{}, &dst.LabeledStmt{}, &dst.ExprStmt{},
+		&dst.SendStmt{}, &dst.IncDecStmt{}, &dst.AssignStmt{}, &dst.GoStmt{}, &dst.DeferStmt{}, &dst.ReturnStmt{},
+		&dst.BranchStmt{}, &dst.BlockStmt{}, &dst.IfStmt{}, &dst.CaseClause{}, &dst.SwitchStmt{}, &dst.TypeSwitchStmt{},
+		&dst.CommClause{}, &dst.SelectStmt{}, &dst.ForStmt{}, &dst.RangeStmt{}, &dst.ImportSpec{}, &dst.ValueSpec{},
+		&dst.TypeSpec{}, &dst.BadDecl{}, &dst.GenDecl{}, &dst.FuncDecl{}, &dst.File{},
+	} {
+		t := reflect.TypeOf(n).Elem()
+		dstNodeTypes[t.Name()] = t
+	}
+}
+
+// A syncer writes a go/ast tree back into the dst tree it was restored from.
+type syncer struct {
+	// decorated maps the nodes of the restored tree to the dst nodes they were restored from
+	decorated map[ast.Node]dst.Node
+	// done holds the dst node written for each node already synced, so a node held in several places (such as the
+	// specs in the Imports of a file) is written once
+	done map[ast.Node]dst.Node
+}
+
+// node returns the dst node for n: the one it was restored from, with its fields updated, or a new one if n was added
+// by the Visitor.
+func (s *syncer) node(n ast.Node) (dst.Node, error) {
+	if d, ok := s.done[n]; ok {
+		return d, nil
+	}
+	d, ok := s.decorated[n]
+	added := !ok
+	if added {
+		t, ok := dstNodeTypes[reflect.TypeOf(n).Elem().Name()]
+		if !ok {
+			return nil, fmt.Errorf("astordst: %T has no dst equivalent", n)
+		}
+		d = reflect.New(t).Interface().(dst.Node)
+		// without decorations an added statement or declaration would be printed on the line of the one before it
+		switch d.(type) {
+		case dst.Stmt:
+			d.Decorations().Before, d.Decorations().After = dst.NewLine, dst.NewLine
+		case dst.Decl:
+			d.Decorations().Before, d.Decorations().After = dst.EmptyLine, dst.EmptyLine
+		}
+	}
+	s.done[n] = d
+
+	av, dv := reflect.ValueOf(n).Elem(), reflect.ValueOf(d).Elem()
+	for l := 0; l < av.NumField(); l++ {
+		a := av.Field(l)
+		df := dv.FieldByName(av.Type().Field(l).Name)
+		if !df.IsValid() {
+			// comments, and positions with no flag to hold whether they are set, have no dst field
+			continue
+		}
+		switch {
+		case a.Type() == posType:
+			// dst holds some positions as whether they are set (such as the Lparen of a GenDecl); those of an existing
+			// node are kept as they were decorated
+			if added && df.Kind() == reflect.Bool {
+				df.SetBool(a.Interface().(token.Pos).IsValid())
+			}
+		case a.Type().Implements(astNodeType):
+			child, err := s.child(a)
+			if err != nil {
+				return nil, err
+			}
+			if child == nil {
+				df.Set(reflect.Zero(df.Type()))
+			} else {
+				df.Set(reflect.ValueOf(child))
+			}
+		case a.Kind() == reflect.Slice && a.Type().Elem().Implements(astNodeType):
+			list := reflect.MakeSlice(df.Type(), 0, a.Len())
+			for m := 0; m < a.Len(); m++ {
+				child, err := s.child(a.Index(m))
+				if err != nil {
+					return nil, err
+				}
+				if child != nil {
+					list = reflect.Append(list, reflect.ValueOf(child))
+				}
+			}
+			if a.IsNil() {
+				list = reflect.Zero(df.Type())
+			}
+			df.Set(list)
+		case a.Type().ConvertibleTo(df.Type()):
+			df.Set(a.Convert(df.Type()))
+		}
+	}
+	return d, nil
+}
+
+// child returns the dst node for the node held in v, or nil if it holds none.
+func (s *syncer) child(v reflect.Value) (dst.Node, error) {
+	if v.IsNil() || v.Kind() == reflect.Interface && v.Elem().IsNil() {
+		return nil, nil
+	}
+	return s.node(v.Interface().(ast.Node))
+}
@@ -0,0 +1,132 @@
+package astordst
+
+import (
+	"errors"
+	"go/ast"
+	"go/token"
+	"testing"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+	"github.com/obeattie/astor"
+	"github.com/stretchr/testify/assert"
+)
+
+const src = `package foo
+
+// Bar does things
+func Bar() {
+	// a does a
+	a()
+
+	// b does b
+	b() // and is deleted
+
+	c(1, // one
+		2) // two
+}
+`
+
+func TestInspectSource(t *testing.T) {
+	visitor := func(i astor.Inspector, n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ExprStmt:
+			switch n.X.(*ast.CallExpr).Fun.(*ast.Ident).Name {
+			case "b":
+				i.Delete()
+			case "c":
+				i.InsertBefore(&ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent("added")}})
+			}
+		case *ast.Ident:
+			if n.Name == "a" {
+				i.Replace(ast.NewIdent("renamed"))
+			}
+		}
+		return true
+	}
+
+	out, err := InspectSource([]byte(src), visitor)
+	assert.NoError(t, err)
+	assert.Equal(t, `package foo
+
+// Bar does things
+func Bar() {
+	// a does a
+	renamed()
+
+	added()
+
+	c(1, // one
+		2) // two
+}
+`, string(out))
+}
+
+func TestInspect(t *testing.T) {
+	f, err := decorator.Parse(src)
+	assert.NoError(t, err)
+	body := f.Decls[0].(*dst.FuncDecl).Body
+	a := body.List[0]
+
+	result, err := Inspect(astor.NewInspector(func(i astor.Inspector, n ast.Node) bool {
+		if n, ok := n.(*ast.BlockStmt); ok {
+			i.Replace(&ast.BlockStmt{List: n.List[:1]})
+			return false
+		}
+		return true
+	}), f)
+	assert.NoError(t, err)
+	assert.Equal(t, f, result)
+	// the existing statement is kept, with its decorations, in a new block
+	newBody := result.Decls[0].(*dst.FuncDecl).Body
+	assert.NotSame(t, body, newBody)
+	assert.Equal(t, []dst.Stmt{a}, newBody.List)
+	assert.Equal(t, []string{"// a does a"}, a.Decorations().Start.All())
+}
+
+func TestInspectErrors(t *testing.T) {
+	_, err := InspectSource([]byte("package foo\n\nfunc {"), func(i astor.Inspector, n ast.Node) bool { return true })
+	var parseErr *astor.ParseError
+	assert.True(t, errors.As(err, &parseErr), "Expected a *astor.ParseError, got %v", err)
+
+	toPackage := func(i astor.Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.File); ok {
+			i.Replace(&ast.Package{Name: "foo"})
+			return false
+		}
+		return true
+	}
+	_, err = InspectSource([]byte("package foo\n"), toPackage)
+	assert.EqualError(t, err, "astordst: the Visitor replaced the file with *ast.Package")
+
+	// a walk ended by an error returns it
+	out, err := InspectSource([]byte(src), func(i astor.Inspector, n ast.Node) bool { return true }, astor.WithMaxNodes(3))
+	var limitErr *astor.NodeLimitError
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Nil(t, out)
+}
+
+func TestInspectAddedNodes(t *testing.T) {
+	visitor := func(i astor.Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.FuncDecl); ok {
+			i.InsertAfter(&ast.FuncDecl{
+				Name: ast.NewIdent("Baz"),
+				Type: &ast.FuncType{Results: &ast.FieldList{List: []*ast.Field{
+					{Type: ast.NewIdent("int")},
+					{Type: ast.NewIdent("error")},
+				}}},
+				Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{
+					&ast.BasicLit{Kind: token.INT, Value: "1"},
+					ast.NewIdent("nil"),
+				}}}},
+			})
+			return false
+		}
+		return true
+	}
+
+	out, err := InspectSource([]byte("package foo\n\n// Bar is a bar\nfunc Bar() {} // bar\n"), visitor)
+	assert.NoError(t, err)
+	assert.Equal(t, "package foo\n\n// Bar is a bar\nfunc Bar() {} // bar\n\nfunc Baz() (int, error) {\n\treturn 1, nil\n}\n",
+		string(out))
+}
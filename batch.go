@@ -0,0 +1,146 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// A Batch accumulates edits proposed for an AST, such as those found by InspectDryRun for several independent
+// Visitors, so they can be checked for conflicts before any of them is applied. The zero Batch is empty and ready to
+// use.
+type Batch struct {
+	edits []Edit
+}
+
+// A Conflict is a pair of edits in a Batch which can't both be applied: either they replace or delete the same node
+// in different ways, or B changes a node within the subtree A replaces or deletes. A was added to the Batch first.
+type Conflict struct {
+	A, B Edit
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s %T conflicts with %s %T", c.A.Kind, c.A.Old, c.B.Kind, c.B.Old)
+}
+
+// A ConflictError is returned by Batch.Apply when the edits in the Batch conflict.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	descs := make([]string, len(e.Conflicts))
+	for l, c := range e.Conflicts {
+		descs[l] = c.String()
+	}
+	return "astor: conflicting edits: " + strings.Join(descs, "; ")
+}
+
+// Add adds edits to the Batch.
+func (b *Batch) Add(edits ...Edit) {
+	b.edits = append(b.edits, edits...)
+}
+
+// Propose adds the edits the Visitor of i asks for when walking the AST rooted at node, without applying them (as
+// for InspectDryRun).
+func (b *Batch) Propose(i Inspector, node ast.Node) {
+	b.Add(InspectDryRun(i, node)...)
+}
+
+// Edits returns the edits in the Batch, in the order they were added.
+func (b *Batch) Edits() []Edit {
+	return append([]Edit(nil), b.edits...)
+}
+
+// Conflicts returns the pairs of edits in the Batch which conflict. Replacements of the same node with Equal nodes
+// don't conflict, and nor do insertions, except within a subtree which is replaced or deleted.
+func (b *Batch) Conflicts() []Conflict {
+	var conflicts []Conflict
+	for l, a := range b.edits {
+		if !replacesOrDeletes(a) {
+			continue
+		}
+
+		within := make(map[ast.Node]bool)
+		ast.Inspect(a.Old, func(n ast.Node) bool {
+			if n != nil && n != a.Old {
+				within[n] = true
+			}
+			return true
+		})
+		for k, e := range b.edits {
+			if k == l {
+				continue
+			}
+			sameNode := k > l && replacesOrDeletes(e) && e.Old == a.Old && !duplicateEdits(a, e)
+			if sameNode || within[e.Old] {
+				conflicts = append(conflicts, orderedConflict(l, a, k, e))
+			}
+		}
+	}
+	return conflicts
+}
+
+func replacesOrDeletes(e Edit) bool {
+	return e.Kind == EditReplace || e.Kind == EditDelete
+}
+
+// duplicateEdits reports whether a and b make the same change to the same node.
+func duplicateEdits(a, b Edit) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	return a.Kind == EditDelete || Equal(a.New, b.New)
+}
+
+// orderedConflict returns the conflict between the edits a and b, at indices l and k, with the one added first as A.
+func orderedConflict(l int, a Edit, k int, b Edit) Conflict {
+	if k < l {
+		return Conflict{A: b, B: a}
+	}
+	return Conflict{A: a, B: b}
+}
+
+// Apply applies the edits in the Batch to the AST rooted at node, which they must have been proposed for, returning
+// the modified tree. If any of the edits conflict, nothing is applied and the error is a *ConflictError. Duplicate
+// replacements and deletions are applied once; insertions are applied in the order they were added.
+func (b *Batch) Apply(node ast.Node) (ast.Node, error) {
+	if conflicts := b.Conflicts(); len(conflicts) > 0 {
+		return node, &ConflictError{Conflicts: conflicts}
+	}
+
+	byNode := make(map[ast.Node][]Edit)
+	for _, e := range b.edits {
+		byNode[e.Old] = append(byNode[e.Old], e)
+	}
+	visitor := func(i Inspector, n ast.Node) bool {
+		edits, ok := byNode[n]
+		if n == nil || !ok {
+			return true
+		}
+		// a replacement may hold the node it replaces, whose edits mustn't be applied again
+		delete(byNode, n)
+
+		changed := false
+		for _, e := range edits {
+			switch e.Kind {
+			case EditInsertBefore:
+				i.InsertBefore(e.New)
+			case EditInsertAfter:
+				i.InsertAfter(e.New)
+			case EditDelete:
+				if !changed {
+					i.Delete()
+				}
+				changed = true
+			case EditReplace:
+				if !changed {
+					i.Replace(e.New)
+				}
+				changed = true
+			}
+		}
+		return true
+	}
+	return NewInspector(visitor).Inspect(node), nil
+}
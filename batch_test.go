@@ -0,0 +1,73 @@
+package astor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// callVisitor returns a Visitor which calls f for each statement calling the named function.
+func callVisitor(name string, f func(i Inspector)) Visitor {
+	return func(i Inspector, n ast.Node) bool {
+		if calledName(n) == name {
+			f(i)
+		}
+		return true
+	}
+}
+
+func TestBatch(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "batch.go", "package foo\n\nfunc Bar() {\n\ta()\n\tb()\n}\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var batch Batch
+	batch.Propose(NewInspector(callVisitor("a", func(i Inspector) { i.Replace(callStmt("c")) })), f)
+	batch.Propose(NewInspector(callVisitor("a", func(i Inspector) { i.InsertAfter(callStmt("d")) })), f)
+	// The same replacement from another analyzer doesn't conflict
+	batch.Propose(NewInspector(callVisitor("a", func(i Inspector) { i.Replace(callStmt("c")) })), f)
+	batch.Propose(NewInspector(callVisitor("b", func(i Inspector) { i.Delete() })), f)
+	assert.Empty(t, batch.Conflicts())
+	assert.Len(t, batch.Edits(), 4)
+
+	result, err := batch.Apply(f)
+	assert.NoError(t, err)
+	out := new(bytes.Buffer)
+	assert.NoError(t, format.Node(out, fset, result))
+	assert.Equal(t, "package foo\n\nfunc Bar() {\n\tc()\n\td()\n\n}\n", out.String())
+}
+
+func TestBatchConflicts(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "batch.go", "package foo\n\nfunc Bar() {\n\ta(x)\n}\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var batch Batch
+	batch.Propose(NewInspector(callVisitor("a", func(i Inspector) { i.Replace(callStmt("c")) })), f)
+	batch.Propose(NewInspector(callVisitor("a", func(i Inspector) { i.Delete() })), f)
+	// Changing x conflicts with replacing the statement holding it
+	batch.Propose(NewInspector(func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "x" {
+			i.Replace(ast.NewIdent("y"))
+		}
+		return true
+	}), f)
+
+	conflicts := batch.Conflicts()
+	edits := batch.Edits()
+	assert.Equal(t, []Conflict{{A: edits[0], B: edits[1]}, {A: edits[0], B: edits[2]}, {A: edits[1], B: edits[2]}},
+		conflicts)
+
+	result, err := batch.Apply(f)
+	assert.Same(t, f, result)
+	assert.Equal(t, &ConflictError{Conflicts: conflicts}, err)
+	assert.EqualError(t, err, "astor: conflicting edits: "+
+		"replace *ast.ExprStmt conflicts with delete *ast.ExprStmt; "+
+		"replace *ast.ExprStmt conflicts with replace *ast.Ident; "+
+		"delete *ast.ExprStmt conflicts with replace *ast.Ident")
+	assert.Equal(t, "a", calledName(f.Decls[0].(*ast.FuncDecl).Body.List[0]))
+}
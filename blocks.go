@@ -0,0 +1,67 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// PrependStmts adds stmts to the start of the statements of the *ast.BlockStmt i is visiting, replacing it with a copy
+// holding them. The statements added aren't inspected, as with InsertBefore, though the rest of the block is.
+//
+// PrependStmts panics if i isn't visiting a block.
+func PrependStmts(i Inspector, stmts ...ast.Stmt) {
+	b := currentBlock(i, "PrependStmts")
+	editBlock(i, b, append(append([]ast.Stmt{}, stmts...), b.List...), stmts)
+}
+
+// AppendStmts adds stmts to the end of the statements of the *ast.BlockStmt i is visiting, replacing it with a copy
+// holding them. The statements added aren't inspected, as with InsertAfter, though the rest of the block is.
+//
+// AppendStmts panics if i isn't visiting a block.
+func AppendStmts(i Inspector, stmts ...ast.Stmt) {
+	b := currentBlock(i, "AppendStmts")
+	editBlock(i, b, append(append([]ast.Stmt{}, b.List...), stmts...), stmts)
+}
+
+// RemoveStmtAt removes the statement at index idx of the statements of the *ast.BlockStmt i is visiting, replacing
+// the block with a copy without it. The statement removed isn't inspected.
+//
+// RemoveStmtAt panics if i isn't visiting a block, or idx is out of its range.
+func RemoveStmtAt(i Inspector, idx int) {
+	b := currentBlock(i, "RemoveStmtAt")
+	if idx < 0 || idx >= len(b.List) {
+		panic(fmt.Sprintf("astor: RemoveStmtAt called with index %d of a block of %d statements", idx, len(b.List)))
+	}
+	editBlock(i, b, append(append([]ast.Stmt{}, b.List[:idx]...), b.List[idx+1:]...), nil)
+}
+
+func currentBlock(i Inspector, name string) *ast.BlockStmt {
+	b, ok := i.Current().(*ast.BlockStmt)
+	if !ok {
+		panic(fmt.Sprintf("astor: %s called while not visiting a block", name))
+	}
+	return b
+}
+
+// editBlock replaces b, the node i is visiting, with a copy holding list, in which added aren't to be inspected.
+func editBlock(i Inspector, b *ast.BlockStmt, list, added []ast.Stmt) {
+	edited := *b
+	edited.List = list
+	i.Replace(&edited)
+	skipAdded(i, added)
+}
+
+// skipAdded stops the statements added by an edit of the node i is visiting from being inspected, as the nodes added
+// by InsertBefore and InsertAfter aren't.
+func skipAdded(i Inspector, added []ast.Stmt) {
+	impl, ok := i.(*inspectorImpl)
+	if !ok || len(added) == 0 {
+		return
+	}
+	if impl.unvisited == nil {
+		impl.unvisited = make(map[ast.Node]bool)
+	}
+	for _, s := range added {
+		impl.unvisited[s] = true
+	}
+}
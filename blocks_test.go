@@ -0,0 +1,40 @@
+package astor
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockStmts(t *testing.T) {
+	f := parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\ta()\n\tb()\n\tc()\n}\n")
+	call := func(name string) ast.Stmt {
+		return &ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent(name)}}
+	}
+
+	var visited []string
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			RemoveStmtAt(i, 1)
+			PrependStmts(i, call("first"))
+			AppendStmts(i, call("last"), call("later"))
+			assert.Panics(t, func() { RemoveStmtAt(i, 5) })
+		case *ast.Ident:
+			visited = append(visited, n.Name)
+		}
+		return true
+	}).Inspect(f)
+
+	// the statements added aren't visited, nor is the one removed
+	assert.Equal(t, []string{"foo", "Bar", "a", "c"}, visited)
+	assert.Empty(t, Diff(parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\tfirst()\n\ta()\n\tc()\n\tlast()\n\tlater()\n}\n"), f))
+
+	assert.Panics(t, func() {
+		NewInspector(func(i Inspector, n ast.Node) bool {
+			AppendStmts(i, call("a"))
+			return true
+		}).Inspect(f)
+	})
+}
@@ -0,0 +1,81 @@
+package astor
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+)
+
+// These functions build nodes for a Visitor to insert or replace others with, such as
+// i.Replace(astor.Call(astor.Sel(astor.Id("log"), "Print"), arg)). The nodes have no positions, so they are printed
+// wherever they are put (see SetPositions).
+
+// Id returns an identifier with the given name.
+func Id(name string) *ast.Ident {
+	return ast.NewIdent(name)
+}
+
+// Sel returns the selector expression x.sel.
+func Sel(x ast.Expr, sel string) *ast.SelectorExpr {
+	return &ast.SelectorExpr{X: x, Sel: ast.NewIdent(sel)}
+}
+
+// Call returns a call of fun with args.
+func Call(fun ast.Expr, args ...ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{Fun: fun, Args: args}
+}
+
+// Str returns a string literal holding s, quoted as by strconv.Quote.
+func Str(s string) *ast.BasicLit {
+	return &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(s)}
+}
+
+// Int returns an integer literal holding n.
+func Int(n int) *ast.BasicLit {
+	return &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(n)}
+}
+
+// Binary returns the binary expression x op y, such as x + y.
+func Binary(x ast.Expr, op token.Token, y ast.Expr) *ast.BinaryExpr {
+	return &ast.BinaryExpr{X: x, Op: op, Y: y}
+}
+
+// Unary returns the unary expression op x, such as !x or &x. For a pointer dereference (*x), see Star.
+func Unary(op token.Token, x ast.Expr) *ast.UnaryExpr {
+	return &ast.UnaryExpr{Op: op, X: x}
+}
+
+// Star returns the expression *x: a pointer dereference, or a pointer type.
+func Star(x ast.Expr) *ast.StarExpr {
+	return &ast.StarExpr{X: x}
+}
+
+// Index returns the index expression x[index].
+func Index(x, index ast.Expr) *ast.IndexExpr {
+	return &ast.IndexExpr{X: x, Index: index}
+}
+
+// Assign returns the assignment lhs = rhs.
+func Assign(lhs, rhs ast.Expr) *ast.AssignStmt {
+	return &ast.AssignStmt{Lhs: []ast.Expr{lhs}, Tok: token.ASSIGN, Rhs: []ast.Expr{rhs}}
+}
+
+// Define returns the short variable declaration lhs := rhs.
+func Define(lhs, rhs ast.Expr) *ast.AssignStmt {
+	return &ast.AssignStmt{Lhs: []ast.Expr{lhs}, Tok: token.DEFINE, Rhs: []ast.Expr{rhs}}
+}
+
+// Return returns a return statement of results.
+func Return(results ...ast.Expr) *ast.ReturnStmt {
+	return &ast.ReturnStmt{Results: results}
+}
+
+// Block returns a block of stmts.
+func Block(stmts ...ast.Stmt) *ast.BlockStmt {
+	return &ast.BlockStmt{List: stmts}
+}
+
+// If returns the statement if cond { body }.
+func If(cond ast.Expr, body ...ast.Stmt) *ast.IfStmt {
+	return &ast.IfStmt{Cond: cond, Body: Block(body...)}
+}
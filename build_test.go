@@ -0,0 +1,36 @@
+package astor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	formatNode := func(n ast.Node) string {
+		var out bytes.Buffer
+		assert.NoError(t, format.Node(&out, token.NewFileSet(), n))
+		return out.String()
+	}
+
+	assert.Equal(t, `log.Print("hello", 1)`, formatNode(Call(Sel(Id("log"), "Print"), Str("hello"), Int(1))))
+	assert.Equal(t, `*p[i+1]`, formatNode(Star(Index(Id("p"), Binary(Id("i"), token.ADD, Int(1))))))
+	assert.Equal(t, `x = &y`, formatNode(Assign(Id("x"), Unary(token.AND, Id("y")))))
+	assert.Equal(t, "if !ok {\n\terr := f()\n\treturn err\n}",
+		formatNode(If(Unary(token.NOT, Id("ok")), Define(Id("err"), Call(Id("f"))), Return(Id("err")))))
+	assert.Equal(t, "{\n}", formatNode(Block()))
+
+	// the nodes are valid replacements
+	f := parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\tfmt.Println(x)\n}\n")
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		if c, ok := n.(*ast.CallExpr); ok {
+			i.Replace(Call(Sel(Id("log"), "Print"), c.Args...))
+		}
+		return true
+	}).Inspect(f)
+	assert.Empty(t, Diff(parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\tlog.Print(x)\n}\n"), f))
+}
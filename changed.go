@@ -0,0 +1,104 @@
+package astor
+
+import (
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// InspectChanged walks only the parts of new which differ from old, such as the files parsed from a buffer before and
+// after an edit, and returns new (or its replacement, if all of it is walked). For each of the Differences found by
+// Diff, the innermost node of new holding it is walked as by InspectFrom: the node whose field differs, or which holds
+// a list of a different length. A changed node inside another is only walked as part of it, and if the file itself
+// holds a difference (such as its package name), all of it is walked. Positions are ignored, as by Diff, so code
+// which has only moved isn't walked.
+//
+// InspectChanged panics if i wasn't constructed by this package.
+func InspectChanged(old, new *ast.File, i Inspector) ast.Node {
+	var paths []string
+	nodes := map[string]ast.Node{}
+	for _, d := range Diff(old, new) {
+		path, node := nodeAt(new, d.Path)
+		if _, ok := nodes[path]; !ok {
+			paths = append(paths, path)
+			nodes[path] = node
+		}
+	}
+
+	for _, path := range paths {
+		if path == "" {
+			return InspectFrom(i, new, new)
+		}
+	}
+	for _, path := range paths {
+		if !within(path, paths) {
+			InspectFrom(i, new, nodes[path])
+		}
+	}
+	return new
+}
+
+// nodeAt returns the innermost node found following path (as in a Difference) from root, and the path to it.
+func nodeAt(root ast.Node, path string) (string, ast.Node) {
+	node, nodePath := root, ""
+	v := reflect.ValueOf(root)
+	for done := 0; done < len(path); {
+		rest := path[done:]
+		l := len(rest)
+		switch rest[0] {
+		case '.':
+			if end := strings.IndexAny(rest[1:], ".["); end >= 0 {
+				l = end + 1
+			}
+		case '[':
+			if end := strings.IndexByte(rest, ']'); end >= 0 {
+				l = end + 1
+			}
+		}
+		elem := rest[:l]
+		done += l
+
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nodePath, node
+			}
+			v = v.Elem()
+		}
+		switch {
+		case elem[0] == '.' && v.Kind() == reflect.Struct:
+			v = v.FieldByName(elem[1:])
+		case elem[0] == '[' && v.Kind() == reflect.Slice:
+			idx, err := strconv.Atoi(elem[1 : len(elem)-1])
+			if err != nil || idx >= v.Len() {
+				return nodePath, node
+			}
+			v = v.Index(idx)
+		case elem[0] == '[' && v.Kind() == reflect.Map:
+			key, err := strconv.Unquote(elem[1 : len(elem)-1])
+			if err != nil {
+				return nodePath, node
+			}
+			v = v.MapIndex(reflect.ValueOf(key))
+		default:
+			return nodePath, node
+		}
+		if !v.IsValid() {
+			return nodePath, node
+		}
+		if n, ok := v.Interface().(ast.Node); ok && !isNil(n) {
+			node, nodePath = n, path[:done]
+		}
+	}
+	return nodePath, node
+}
+
+// within reports whether path leads inside the node led to by another of paths.
+func within(path string, paths []string) bool {
+	for _, p := range paths {
+		if len(p) < len(path) && strings.HasPrefix(path, p) && strings.ContainsRune(".[", rune(path[len(p)])) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package astor
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspectChanged(t *testing.T) {
+	old := parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\ta(1)\n\tb()\n}\n\nfunc Baz() {\n\tc()\n}\n")
+
+	visited := func(src string) []string {
+		var visited []string
+		InspectChanged(old, parseEqualSrc(t, src), NewInspector(func(i Inspector, n ast.Node) bool {
+			if n != nil {
+				visited = append(visited, Sequence(n)[0])
+			}
+			return true
+		}))
+		return visited
+	}
+
+	// nothing has changed, though code has moved
+	assert.Empty(t, visited("package foo\n\n\nfunc Bar() {\n\ta(1)\n\tb()\n}\n\nfunc Baz() {\n\n\tc()\n}\n"))
+	// a changed field is walked in the node holding it
+	assert.Equal(t, []string{"*ast.Ident Name=\"d\""},
+		visited("package foo\n\nfunc Bar() {\n\ta(1)\n\tb()\n}\n\nfunc Baz() {\n\td()\n}\n"))
+	assert.Equal(t, []string{"*ast.BasicLit Kind=INT Value=\"2\""},
+		visited("package foo\n\nfunc Bar() {\n\ta(2)\n\tb()\n}\n\nfunc Baz() {\n\tc()\n}\n"))
+	// a list of a different length is walked in the node holding it, along with the changes inside that node
+	assert.Equal(t, []string{"*ast.CallExpr", "*ast.Ident Name=\"e\"", "*ast.BasicLit Kind=INT Value=\"1\"",
+		"*ast.BasicLit Kind=INT Value=\"2\"", "*ast.Ident Name=\"d\""},
+		visited("package foo\n\nfunc Bar() {\n\te(1, 2)\n\tb()\n}\n\nfunc Baz() {\n\td()\n}\n"))
+	assert.Equal(t, []string{"*ast.Ident Name=\"qux\""},
+		visited("package qux\n\nfunc Bar() {\n\ta(1)\n\tb()\n}\n\nfunc Baz() {\n\tc()\n}\n"))
+	// a change to the file itself walks all of it
+	all := visited("package foo\n\nfunc Bar() {\n\ta(1)\n\tb()\n}\n\nfunc Baz() {\n\tc()\n}\n\nvar x int\n")
+	assert.Len(t, all, 26)
+	assert.Equal(t, "*ast.File", all[0])
+
+	// changes are stored back in the new file
+	new := parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\ta(1)\n\tb()\n}\n\nfunc Baz() {\n\td()\n}\n")
+	assert.Equal(t, ast.Node(new), InspectChanged(old, new, NewInspector(func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			i.Replace(ast.NewIdent(id.Name + "2"))
+		}
+		return true
+	})))
+	assert.Empty(t, Diff(parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\ta(1)\n\tb()\n}\n\nfunc Baz() {\n\td2()\n}\n"), new))
+}
@@ -0,0 +1,143 @@
+package astor
+
+import (
+	"go/ast"
+)
+
+// RemoveEmpty tidies up the AST rooted at node after nodes have been deleted from it, returning the modified tree. It
+// removes declarations with no specs (such as "import ()" or "var ()") both at file scope and as statements, nested
+// blocks with no statements, and else branches with no statements. Containers are removed innermost first, so a block
+// left empty by the removal of its only statement is removed too.
+//
+// It is conservative: anything whose removal could change the meaning of the program, such as an if statement with
+// an empty body (whose condition may have side effects) or a function's body, is kept.
+func RemoveEmpty(node ast.Node) ast.Node {
+	var stack []ast.Node
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n != nil {
+			stack = append(stack, n)
+			return true
+		}
+
+		n, stack = stack[len(stack)-1], stack[:len(stack)-1]
+		switch n := n.(type) {
+		case *ast.GenDecl:
+			if len(n.Specs) == 0 && i.Index() >= 0 {
+				i.Delete()
+			}
+		case *ast.DeclStmt:
+			if d, ok := n.Decl.(*ast.GenDecl); ok && len(d.Specs) == 0 && i.Index() >= 0 {
+				i.Delete()
+			}
+		case *ast.BlockStmt:
+			// only a block which is itself a statement; other blocks are required by their parents
+			if _, ok := i.Parent().(*ast.BlockStmt); ok && len(n.List) == 0 {
+				i.Delete()
+			}
+		case *ast.IfStmt:
+			if b, ok := n.Else.(*ast.BlockStmt); ok && len(b.List) == 0 {
+				n.Else = nil
+			}
+		}
+		return true
+	}
+	return NewInspector(visitor).Inspect(node)
+}
+
+// RemoveRedundantParens removes parentheses which aren't needed from the AST rooted at node, such as those left behind
+// by mechanical rewrites, returning the modified tree. Each *ast.ParenExpr is replaced with the expression it holds,
+// unless that would change how the code is parsed: so the parentheses of (a + b) * c, (*p).f and (*T)(x) are kept,
+// while those of ((x)), (a * b) + c and f((x)) are removed.
+//
+// It is conservative: the parentheses around expressions with an operand which is a composite literal of a named type
+// (such as a == T{}), which are needed in the headers of if, for and switch statements, and around the types of
+// fields (such as the constraints of type parameters) are always kept.
+func RemoveRedundantParens(node ast.Node) ast.Node {
+	visitor := func(i Inspector, n ast.Node) bool {
+		p, ok := n.(*ast.ParenExpr)
+		if !ok || parensNeeded(i.Parent(), i.FieldName(), p.X) {
+			return true
+		}
+		// the expression may itself be parenthesised, and is in the same place
+		x := p.X
+		for {
+			q, ok := x.(*ast.ParenExpr)
+			if !ok || parensNeeded(i.Parent(), i.FieldName(), q.X) {
+				break
+			}
+			x = q.X
+		}
+		i.Replace(x)
+		return true
+	}
+	return NewInspector(visitor).Inspect(node)
+}
+
+// parensNeeded reports whether x needs the parentheses around it, where they are held in the named field of parent.
+func parensNeeded(parent ast.Node, field string, x ast.Expr) bool {
+	switch parent.(type) {
+	case *ast.Field:
+		return true
+	case *ast.ChanType:
+		// chan (<-chan int) would otherwise be chan<- (chan int)
+		if _, ok := x.(*ast.ChanType); ok {
+			return true
+		}
+	}
+
+	if bareCompositeLit(x) {
+		return true
+	}
+	switch x.(type) {
+	case *ast.ParenExpr, *ast.Ident, *ast.BasicLit, *ast.FuncLit, *ast.SelectorExpr, *ast.IndexExpr, *ast.IndexListExpr,
+		*ast.SliceExpr, *ast.TypeAssertExpr, *ast.CallExpr:
+		// operands, which bind tightest
+		return false
+	}
+
+	switch parent := parent.(type) {
+	case *ast.SelectorExpr, *ast.IndexExpr, *ast.IndexListExpr, *ast.SliceExpr, *ast.TypeAssertExpr, *ast.StarExpr,
+		*ast.UnaryExpr:
+		return field == "X"
+	case *ast.CallExpr:
+		return field == "Fun"
+	case *ast.BinaryExpr:
+		// a unary expression binds tighter than any binary operator; binary operators of the same precedence are
+		// left-associative
+		b, ok := x.(*ast.BinaryExpr)
+		return ok && (b.Op.Precedence() < parent.Op.Precedence() ||
+			b.Op.Precedence() == parent.Op.Precedence() && field == "Y")
+	}
+	return false
+}
+
+// bareCompositeLit reports whether x is, or has an operand which is, a composite literal whose type is a name, the
+// brace of which would start the block of an if, for or switch statement if x were in its header without parentheses.
+func bareCompositeLit(x ast.Expr) bool {
+	switch x := x.(type) {
+	case *ast.CompositeLit:
+		switch x.Type.(type) {
+		case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr, *ast.IndexListExpr:
+			return true
+		}
+	case *ast.BinaryExpr:
+		return bareCompositeLit(x.X) || bareCompositeLit(x.Y)
+	case *ast.UnaryExpr:
+		return bareCompositeLit(x.X)
+	case *ast.StarExpr:
+		return bareCompositeLit(x.X)
+	case *ast.SelectorExpr:
+		return bareCompositeLit(x.X)
+	case *ast.IndexExpr:
+		return bareCompositeLit(x.X)
+	case *ast.IndexListExpr:
+		return bareCompositeLit(x.X)
+	case *ast.SliceExpr:
+		return bareCompositeLit(x.X)
+	case *ast.TypeAssertExpr:
+		return bareCompositeLit(x.X)
+	case *ast.CallExpr:
+		return bareCompositeLit(x.Fun)
+	}
+	return false
+}
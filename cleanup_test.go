@@ -0,0 +1,46 @@
+package astor
+
+import (
+	"bytes"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoveEmpty(t *testing.T) {
+	const infile, outfile = "test-samples/remove-empty.go.in", "test-samples/remove-empty.go.out"
+	expectedOut, err := ioutil.ReadFile(outfile)
+	assert.NoError(t, err, "Error reading expected output")
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, infile, nil, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	out := new(bytes.Buffer)
+	assert.NoError(t, format.Node(out, fset, RemoveEmpty(f)), "Error formatting output AST")
+	assert.Equal(t, string(expectedOut), out.String())
+}
+
+func TestRemoveRedundantParens(t *testing.T) {
+	const infile, outfile = "test-samples/remove-parens.go.in", "test-samples/remove-parens.go.out"
+	expectedOut, err := ioutil.ReadFile(outfile)
+	assert.NoError(t, err, "Error reading expected output")
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, infile, nil, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	out := new(bytes.Buffer)
+	assert.NoError(t, format.Node(out, fset, RemoveRedundantParens(f)), "Error formatting output AST")
+	assert.Equal(t, string(expectedOut), out.String())
+
+	// more nested parentheses than an Inspector revisits a replacement, and a sibling after them
+	src := "package foo\n\nvar v = f(" + strings.Repeat("(", 15) + "x" + strings.Repeat(")", 15) + ", ((y)))\n"
+	result := RemoveRedundantParens(parseEqualSrc(t, src))
+	assert.Empty(t, Diff(parseEqualSrc(t, "package foo\n\nvar v = f(x, y)\n"), result))
+}
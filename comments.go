@@ -0,0 +1,99 @@
+package astor
+
+import (
+	"go/ast"
+)
+
+// WithCommentMap makes the Inspector visit the comment groups cmap associates with each node, after the node's
+// children and before its terminating nil visit, with the node as their Parent. Groups which are also the node's Doc
+// or Comment are only visited once, as part of the node's children. For each node, its comment groups are held in a
+// slice, so they may be deleted and inserted as well as replaced.
+//
+// Changes are reflected back into cmap: replaced, deleted and inserted comment groups update the list cmap holds for
+// the node, and when a node is replaced its comments are associated with the replacement. Like any other comment,
+// they only appear in formatted output once the file's Comments are updated, for example with:
+//
+//	file.Comments = cmap.Filter(file).Comments()
+//
+// These comment groups aren't held in a field of their Parent, so FieldName gives CommentMapField with their index in
+// the node's list, and PrevSibling and NextSibling the groups beside them in that list.
+//
+// Clones of the Inspector share cmap, so must not walk the nodes it holds comments for concurrently.
+func WithCommentMap(cmap ast.CommentMap) Option {
+	return func(i *inspectorImpl) {
+		i.cmap = cmap
+	}
+}
+
+// CommentMapField is the field name FieldName gives, with an index, for a comment group visited from the comment map
+// passed to WithCommentMap. It can't be the name of a field, so a Visitor can tell these groups from a node's Doc or
+// Comment.
+const CommentMapField = "(CommentMap)"
+
+// inspectComments inspects the comment groups associated with node in the comment map, other than those which are
+// among its children.
+func (i *inspectorImpl) inspectComments(ii Inspector, node ast.Node) {
+	groups := i.cmap[node]
+	if len(groups) == 0 {
+		return
+	}
+
+	attached := attachedComments(node)
+	edited := make([]*ast.CommentGroup, 0, len(groups))
+	for l, g := range groups {
+		if g == attached[0] || g == attached[1] {
+			edited = append(edited, g)
+			continue
+		}
+
+		r, edit := inspectSlot(ii, slot{parent: node, field: CommentMapField, index: l, typ: slotType[*ast.CommentGroup](), comments: groups}, g)
+		for _, n := range edit.before {
+			edited = append(edited, n.(*ast.CommentGroup))
+		}
+		if !edit.deleted {
+			edited = append(edited, r)
+		}
+		for _, n := range edit.after {
+			edited = append(edited, n.(*ast.CommentGroup))
+		}
+	}
+
+	if len(edited) == 0 {
+		delete(i.cmap, node)
+	} else {
+		i.cmap[node] = edited
+	}
+}
+
+// moveComments associates the comment groups associated with original in the comment map with its replacement.
+func (i *inspectorImpl) moveComments(original, replacement ast.Node) {
+	groups, ok := i.cmap[original]
+	if !ok {
+		return
+	}
+	delete(i.cmap, original)
+	if replacement != nil {
+		i.cmap[replacement] = append(i.cmap[replacement], groups...)
+	}
+}
+
+// attachedComments returns the Doc and Comment groups of node, which are inspected as its children.
+func attachedComments(node ast.Node) [2]*ast.CommentGroup {
+	switch n := node.(type) {
+	case *ast.Field:
+		return [2]*ast.CommentGroup{n.Doc, n.Comment}
+	case *ast.ImportSpec:
+		return [2]*ast.CommentGroup{n.Doc, n.Comment}
+	case *ast.ValueSpec:
+		return [2]*ast.CommentGroup{n.Doc, n.Comment}
+	case *ast.TypeSpec:
+		return [2]*ast.CommentGroup{n.Doc, n.Comment}
+	case *ast.GenDecl:
+		return [2]*ast.CommentGroup{n.Doc, nil}
+	case *ast.FuncDecl:
+		return [2]*ast.CommentGroup{n.Doc, nil}
+	case *ast.File:
+		return [2]*ast.CommentGroup{n.Doc, nil}
+	}
+	return [2]*ast.CommentGroup{}
+}
@@ -0,0 +1,96 @@
+package astor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const commentsSrc = `package foo
+
+// Bar does things
+func Bar() {
+	// TODO: remove
+	a()
+	// NOTE: b is important
+	b()
+}
+`
+
+func TestWithCommentMap(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "comments.go", commentsSrc, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	cmap := ast.NewCommentMap(fset, f, f.Comments)
+
+	visits := make(map[string]int)
+	visitor := func(i Inspector, n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CommentGroup:
+			visits[n.Text()]++
+			if strings.HasPrefix(n.Text(), "NOTE") {
+				assert.Equal(t, "b", calledName(i.Parent()))
+				assert.Equal(t, CommentMapField+"[0]", i.FieldName())
+				i.Delete()
+			}
+		case *ast.Comment:
+			if strings.HasPrefix(n.Text, "// TODO") {
+				i.Replace(&ast.Comment{Slash: n.Slash, Text: strings.Replace(n.Text, "TODO", "FIXME", 1)})
+			}
+		}
+		return true
+	}
+	NewInspector(visitor, WithCommentMap(cmap)).Inspect(f)
+	assert.Equal(t, map[string]int{
+		"Bar does things\n":      1,
+		"TODO: remove\n":         1,
+		"NOTE: b is important\n": 1,
+	}, visits)
+
+	f.Comments = cmap.Filter(f).Comments()
+	out := new(bytes.Buffer)
+	assert.NoError(t, format.Node(out, fset, f), "Error formatting output AST")
+	assert.Equal(t, `package foo
+
+// Bar does things
+func Bar() {
+	// FIXME: remove
+	a()
+
+	b()
+}
+`, out.String())
+}
+
+func TestCommentMapSiblings(t *testing.T) {
+	fset := token.NewFileSet()
+	src := "package foo\n\nfunc Bar() {\n\t// one\n\ta() // two\n}\n\n// three\n"
+	f, err := parser.ParseFile(fset, "comments.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	cmap := ast.NewCommentMap(fset, f, f.Comments)
+
+	siblings := make(map[string][2]string)
+	text := func(n ast.Node) string {
+		if g, ok := n.(*ast.CommentGroup); ok {
+			return g.Text()
+		}
+		return ""
+	}
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		if g, ok := n.(*ast.CommentGroup); ok {
+			siblings[g.Text()] = [2]string{text(i.PrevSibling()), text(i.NextSibling())}
+		}
+		return true
+	}, WithCommentMap(cmap)).Inspect(f)
+	assert.Equal(t, map[string][2]string{
+		"one\n":   {"", "two\n"},
+		"two\n":   {"one\n", ""},
+		"three\n": {"", ""},
+	}, siblings)
+}
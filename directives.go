@@ -0,0 +1,170 @@
+package astor
+
+import (
+	"go/ast"
+	"strings"
+	"unicode"
+)
+
+// A Directive is a comment holding an instruction for a tool rather than for people, such as //go:generate, //nolint
+// or //go:build, parsed into its name and arguments so it can be changed and then written back with EditDirective.
+// By convention the text of a directive starts straight after the comment marker, with no space.
+type Directive struct {
+	// Name is the first word of the directive, such as "go:generate" or "nolint:errcheck"
+	Name string
+	// Args are the words following the name. A double-quoted string is a single argument, which keeps its quotes.
+	Args []string
+	// Block is whether the directive is a /* */ comment, rather than a // one
+	Block bool
+}
+
+// ParseDirective parses c as a directive, reporting whether it is one: whether its text starts straight after the
+// comment marker.
+func ParseDirective(c *ast.Comment) (*Directive, bool) {
+	d := &Directive{}
+	text := c.Text
+	switch {
+	case strings.HasPrefix(text, "//"):
+		text = text[2:]
+	case strings.HasPrefix(text, "/*") && strings.HasSuffix(text, "*/") && len(text) >= 4:
+		text = text[2 : len(text)-2]
+		d.Block = true
+	default:
+		return nil, false
+	}
+	if text == "" || unicode.IsSpace(rune(text[0])) {
+		return nil, false
+	}
+
+	args := splitDirectiveArgs(text)
+	d.Name = args[0]
+	if len(args) > 1 {
+		d.Args = args[1:]
+	}
+	return d, true
+}
+
+// splitDirectiveArgs splits text into words separated by spaces, keeping double-quoted strings (which may contain
+// spaces, and escaped quotes) whole.
+func splitDirectiveArgs(text string) []string {
+	var args []string
+	for {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		if text == "" {
+			return args
+		}
+
+		l := 0
+		for quoted := false; l < len(text) && (quoted || !unicode.IsSpace(rune(text[l]))); l++ {
+			switch {
+			case text[l] == '\\' && quoted:
+				l++
+			case text[l] == '"':
+				quoted = !quoted
+			}
+		}
+		if l > len(text) {
+			// an escape at the very end
+			l = len(text)
+		}
+		args = append(args, text[:l])
+		text = text[l:]
+	}
+}
+
+// String returns the directive as the text of a comment.
+func (d *Directive) String() string {
+	text := strings.Join(append([]string{d.Name}, d.Args...), " ")
+	if d.Block {
+		return "/*" + text + "*/"
+	}
+	return "//" + text
+}
+
+// EditDirective parses the *ast.Comment being visited by i as a directive, passes it to f to be changed, and
+// replaces the comment with one holding the changed directive, in the same place. It reports whether the comment is
+// a directive; if it isn't, f isn't called.
+//
+// EditDirective panics if i isn't visiting a comment.
+func EditDirective(i Inspector, f func(*Directive)) bool {
+	c, ok := i.Current().(*ast.Comment)
+	if !ok {
+		panic("astor: EditDirective called while not visiting a comment")
+	}
+	d, ok := ParseDirective(c)
+	if !ok {
+		return false
+	}
+
+	orig := d.String()
+	f(d)
+	if text := d.String(); text != orig {
+		i.Replace(&ast.Comment{Slash: c.Slash, Text: text})
+	}
+	return true
+}
+
+// OnBrokenDirective registers a function to be called each time the Visitor changes the AST in a way which would
+// separate a directive from the code it applies to: a //go:embed comment from the variable declared after it, or a
+// //line directive from the code on the line after it. It is called with the directive and the node it applies to,
+// before the change is made. Deleting that node, inserting a node before it, or replacing it with a node which starts
+// anywhere else (including nil) separates them; a replacement which starts where the node did is assumed to keep its
+// place after the directive. Directives are only found in the *ast.File nodes the Inspector visits.
+func OnBrokenDirective(f func(directive *ast.Comment, node ast.Node)) Option {
+	return func(i *inspectorImpl) {
+		i.onBroken = append(i.onBroken, f)
+	}
+}
+
+// findDirectives records the node each //go:embed and //line directive in n applies to, if n is a file and there are
+// functions to call if they are broken.
+func (i *inspectorImpl) findDirectives(n ast.Node) {
+	f, ok := n.(*ast.File)
+	if !ok || len(i.onBroken) == 0 {
+		return
+	}
+
+	var directives []*ast.Comment
+	for _, g := range f.Comments {
+		for _, c := range g.List {
+			if d, ok := ParseDirective(c); ok && (d.Name == "go:embed" && !d.Block || d.Name == "line") {
+				directives = append(directives, c)
+			}
+		}
+	}
+	if len(directives) == 0 {
+		return
+	}
+	if i.directives == nil {
+		i.directives = make(map[ast.Node][]*ast.Comment)
+	}
+	// a directive applies to the outermost node starting after it, which is the first found in a pre-order walk
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n.(type) {
+		case nil, *ast.Comment, *ast.CommentGroup:
+			return false
+		}
+		for len(directives) > 0 && directives[0].End() < n.Pos() {
+			i.directives[n] = append(i.directives[n], directives[0])
+			directives = directives[1:]
+		}
+		return len(directives) > 0
+	})
+}
+
+// checkReplaceDirectives calls the OnBrokenDirective functions if replacing the node being visited with n would break
+// the directives applying to it.
+func (i *inspectorImpl) checkReplaceDirectives(n ast.Node) {
+	if isNil(n) || n.Pos() != i.original.Pos() {
+		i.breakDirectives(i.original)
+	}
+}
+
+// breakDirectives calls the OnBrokenDirective functions for each of the directives applying to n.
+func (i *inspectorImpl) breakDirectives(n ast.Node) {
+	for _, d := range i.directives[n] {
+		for _, f := range i.onBroken {
+			f(d, n)
+		}
+	}
+}
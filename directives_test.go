@@ -0,0 +1,115 @@
+package astor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDirective(t *testing.T) {
+	d, ok := ParseDirective(&ast.Comment{Text: `//go:generate stringer -type "A B" -linecomment`})
+	assert.True(t, ok)
+	assert.Equal(t, &Directive{Name: "go:generate", Args: []string{"stringer", "-type", `"A B"`, "-linecomment"}}, d)
+	assert.Equal(t, `//go:generate stringer -type "A B" -linecomment`, d.String())
+
+	d, ok = ParseDirective(&ast.Comment{Text: "/*nolint:errcheck*/"})
+	assert.True(t, ok)
+	assert.Equal(t, &Directive{Name: "nolint:errcheck", Block: true}, d)
+	assert.Equal(t, "/*nolint:errcheck*/", d.String())
+
+	for _, text := range []string{"// go:generate", "//", "/* nolint */", "/**/"} {
+		_, ok = ParseDirective(&ast.Comment{Text: text})
+		assert.False(t, ok, text)
+	}
+}
+
+func TestEditDirective(t *testing.T) {
+	src := "package foo\n\n// A is a thing.\n//\n//go:generate stringer -type A\ntype A int\n"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "directives.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var directives int
+	visitor := func(i Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.Comment); ok && EditDirective(i, func(d *Directive) {
+			d.Args = append(d.Args, "-trimprefix", "A")
+		}) {
+			directives++
+		}
+		return true
+	}
+	NewInspector(visitor).Inspect(f)
+	assert.Equal(t, 1, directives)
+
+	out := new(bytes.Buffer)
+	assert.NoError(t, format.Node(out, fset, f))
+	assert.Equal(t, "package foo\n\n// A is a thing.\n//\n//go:generate stringer -type A -trimprefix A\ntype A int\n",
+		out.String())
+}
+
+func TestOnBrokenDirective(t *testing.T) {
+	src := "package foo\n\nimport _ \"embed\"\n\n//go:embed a.txt\nvar a string\n\nvar b string\n\nfunc c() {\n//line c.go:10\n\td()\n\te()\n}\n"
+	parse := func() *ast.File {
+		f, err := parser.ParseFile(token.NewFileSet(), "embed.go", src, parserFlags)
+		assert.NoError(t, err, "Error parsing input")
+		return f
+	}
+
+	var broken []string
+	onBroken := OnBrokenDirective(func(d *ast.Comment, n ast.Node) {
+		broken = append(broken, d.Text+" "+describeValue(n))
+	})
+	walk := func(visitor Visitor) []string {
+		broken = nil
+		NewInspector(visitor, onBroken).Inspect(parse())
+		return broken
+	}
+
+	// inserting between a directive and its node, or deleting the node, breaks it
+	assert.Equal(t, []string{"//go:embed a.txt *ast.GenDecl", "//line c.go:10 *ast.ExprStmt"},
+		walk(func(i Inspector, n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.GenDecl:
+				if n.Tok == token.VAR && i.Index() == 1 {
+					i.InsertBefore(&ast.GenDecl{Tok: token.VAR})
+				}
+			case *ast.ExprStmt:
+				i.Delete()
+			}
+			return true
+		}))
+	assert.Equal(t, []string{"//line c.go:10 *ast.ExprStmt"}, walk(func(i Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.FuncDecl); ok {
+			// this adds nothing between the directive and d(), so is fine
+			i.InsertAfter(&ast.GenDecl{Tok: token.VAR})
+		}
+		if _, ok := i.Parent().(*ast.BlockStmt); ok && i.Index() == 0 && n != nil {
+			i.Replace(callStmt("f"))
+		}
+		return true
+	}))
+	assert.Equal(t, []string{"//go:embed a.txt *ast.GenDecl"}, walk(func(i Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.ImportSpec); ok {
+			return false
+		}
+		if _, ok := n.(*ast.GenDecl); ok && i.Index() == 0 {
+			i.InsertAfter(&ast.GenDecl{Tok: token.VAR})
+		}
+		return true
+	}))
+
+	// a replacement starting in the same place keeps its place after the directive
+	assert.Empty(t, walk(func(i Inspector, n ast.Node) bool {
+		if d, ok := n.(*ast.GenDecl); ok && d.Tok == token.VAR {
+			edited := *d
+			edited.Specs = append([]ast.Spec(nil), d.Specs...)
+			i.Replace(&edited)
+		}
+		return true
+	}))
+}
@@ -0,0 +1,87 @@
+package astor
+
+import (
+	"go/ast"
+	"go/doc/comment"
+	"go/token"
+	"strings"
+)
+
+// EditDoc parses the *ast.CommentGroup being visited by i as a doc comment, with go/doc/comment, passes it to f to be
+// changed, and replaces the group with one holding the changed doc comment, in the same place. It reports whether the
+// group is the Doc of its Parent (such as a declaration, or a field); if it isn't, f isn't called.
+//
+// The doc comment is written back as gofmt would format it, as // comments (even if it was a /* */ comment, which
+// go/printer can't place once its length changes), and is only replaced if f changed it. Directives in the group,
+// such as //go:generate, aren't part of the doc comment, so are kept as they are, after it. If f empties the doc
+// comment and there are no directives, the group is removed. Like any other comment, the replacement only appears in
+// formatted output once the file's Comments are updated (see WithCommentMap).
+//
+// EditDoc panics if i isn't visiting a comment group.
+func EditDoc(i Inspector, f func(*comment.Doc)) bool {
+	g, ok := i.Current().(*ast.CommentGroup)
+	if !ok {
+		panic("astor: EditDoc called while not visiting a comment group")
+	}
+	if i.FieldName() != "Doc" || len(g.List) == 0 {
+		return false
+	}
+
+	var p comment.Parser
+	var pr comment.Printer
+	d := p.Parse(g.Text())
+	orig := string(pr.Comment(d))
+	f(d)
+	// the text of the doc comment, without comment markers
+	text := string(pr.Comment(d))
+	if text == orig {
+		return true
+	}
+
+	var list []*ast.Comment
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			break
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "", strings.HasPrefix(line, "\t"):
+			line = "//" + line
+		default:
+			line = "// " + line
+		}
+		list = append(list, &ast.Comment{Text: line})
+	}
+	lines := len(list)
+	for _, c := range g.List {
+		if isDirectiveComment(c) {
+			if len(list) == lines && lines > 0 {
+				// a blank line between the doc comment and the directives
+				list = append(list, &ast.Comment{Text: "//"})
+			}
+			list = append(list, &ast.Comment{Text: c.Text})
+		}
+	}
+	if len(list) == 0 {
+		i.Replace(nil)
+		return true
+	}
+	// go/printer places a doc comment by where the group starts and ends, so the replacement starts and ends where the
+	// group did, and the comments between start with it (as when go/printer formats doc comments itself)
+	for _, c := range list {
+		c.Slash = g.Pos()
+	}
+	if last := list[len(list)-1]; g.End()-token.Pos(len(last.Text)) > g.Pos() {
+		last.Slash = g.End() - token.Pos(len(last.Text))
+	}
+	i.Replace(&ast.CommentGroup{List: list})
+	return true
+}
+
+// isDirectiveComment reports whether c is a directive which go/ast leaves out of the text of its comment group.
+func isDirectiveComment(c *ast.Comment) bool {
+	if _, ok := ParseDirective(c); !ok {
+		return false
+	}
+	return (&ast.CommentGroup{List: []*ast.Comment{c}}).Text() == ""
+}
@@ -0,0 +1,68 @@
+package astor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/doc/comment"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDoc(t *testing.T) {
+	src := "package foo\n\n// A is a thing:\n//   - one\n//   - two\n//\n//go:generate stringer -type A\ntype A int\n\n" +
+		"/*\nB is another.\n*/\nfunc B() {}\n\n// C isn't changed.\nfunc C() {}\n\nfunc D() {} // not a doc comment\n\n// E is removed.\nfunc E() {}\n"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "doc.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var docs int
+	// the replacements are put in the file's comments, so they are formatted
+	onReplace := OnReplace(func(old, new ast.Node) {
+		comments := f.Comments[:0]
+		for _, g := range f.Comments {
+			if g == old {
+				g, _ = new.(*ast.CommentGroup)
+			}
+			if g != nil {
+				comments = append(comments, g)
+			}
+		}
+		f.Comments = comments
+	})
+	visitor := func(i Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.CommentGroup); ok && EditDoc(i, func(d *comment.Doc) {
+			docs++
+			switch p := d.Content[0].(*comment.Paragraph); p.Text[0] {
+			case comment.Plain("A is a thing:"):
+				d.Content[1].(*comment.List).Items[1].Content[0].(*comment.Paragraph).Text[0] = comment.Plain("three")
+				d.Content = append(d.Content, &comment.Paragraph{Text: []comment.Text{comment.Plain("See B.")}})
+			case comment.Plain("B is another."):
+				d.Content = append(d.Content, &comment.Code{Text: "x := B()\n"})
+			case comment.Plain("E is removed."):
+				d.Content = nil
+			}
+		}) {
+			return false
+		}
+		return true
+	}
+	NewInspector(visitor, onReplace).Inspect(f)
+	assert.Equal(t, 4, docs)
+
+	out := new(bytes.Buffer)
+	assert.NoError(t, format.Node(out, fset, f))
+	assert.Equal(t, "package foo\n\n// A is a thing:\n//   - one\n//   - three\n//\n// See B.\n//\n//go:generate stringer -type A\ntype A int\n\n"+
+		"// B is another.\n//\n//\tx := B()\nfunc B() {}\n\n// C isn't changed.\nfunc C() {}\n\nfunc D() {} // not a doc comment\n\nfunc E() {}\n",
+		out.String())
+
+	assert.Panics(t, func() {
+		NewInspector(func(i Inspector, n ast.Node) bool {
+			EditDoc(i, func(*comment.Doc) {})
+			return false
+		}).Inspect(f)
+	})
+}
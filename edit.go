@@ -0,0 +1,89 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// EditKind is the kind of change described by an Edit.
+type EditKind int
+
+const (
+	// EditReplace is a call to Inspector.Replace (or ReplaceAndRevisit)
+	EditReplace EditKind = iota
+	// EditDelete is a call to Inspector.Delete
+	EditDelete
+	// EditInsertBefore is a call to Inspector.InsertBefore
+	EditInsertBefore
+	// EditInsertAfter is a call to Inspector.InsertAfter
+	EditInsertAfter
+)
+
+func (k EditKind) String() string {
+	switch k {
+	case EditReplace:
+		return "replace"
+	case EditDelete:
+		return "delete"
+	case EditInsertBefore:
+		return "insert before"
+	case EditInsertAfter:
+		return "insert after"
+	default:
+		return fmt.Sprintf("EditKind(%d)", int(k))
+	}
+}
+
+// An Edit is a change a Visitor asked to make to an AST.
+type Edit struct {
+	// Parent is the node holding Old, or nil if Old is the root of the walk
+	Parent ast.Node
+	// Old is the node being inspected when the change was asked for
+	Old ast.Node
+	// New is the replacement or inserted node, or nil for a deletion
+	New ast.Node
+	// Kind is the kind of change
+	Kind EditKind
+}
+
+// InspectDryRun walks the AST for the node passed like Inspect, but rather than applying the changes the Visitor asks
+// for, returns them in the order they were asked for. The AST is not modified, so the Visitor only sees the original
+// nodes (Current isn't changed by Replace), and ReplaceAndRevisit doesn't revisit anything. A node replaced or
+// deleted more than once in a visit has a single edit, in the place of the first, holding the last change asked for.
+// i must have been constructed by this package.
+func InspectDryRun(i Inspector, node ast.Node) []Edit {
+	impl, ok := i.(*inspectorImpl)
+	if !ok {
+		panic(fmt.Sprintf("astor: InspectDryRun can't record the edits of a %T", i))
+	}
+
+	impl.dryRun, impl.edits = true, nil
+	impl.Inspect(node)
+	edits := impl.edits
+	impl.dryRun, impl.edits = false, nil
+	return edits
+}
+
+// record records an edit to the current node, in place of applying it. As when they are applied, a replacement or
+// deletion supersedes any the Visitor has already asked for in the same visit.
+func (i *inspectorImpl) record(kind EditKind, n ast.Node) {
+	e := Edit{
+		Parent: i.slot.parent,
+		Old:    i.node,
+		New:    n,
+		Kind:   kind,
+	}
+	if i.finishing {
+		e.Old = i.finished
+	}
+	if replacesOrDeletes(e) {
+		// the edits asked for in this visit are the last recorded, as the node's children haven't been visited yet
+		for l := len(i.edits) - 1; l >= 0 && i.edits[l].Old == e.Old && i.edits[l].Parent == e.Parent; l-- {
+			if replacesOrDeletes(i.edits[l]) {
+				i.edits[l] = e
+				return
+			}
+		}
+	}
+	i.edits = append(i.edits, e)
+}
@@ -0,0 +1,43 @@
+package astor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspectDryRun(t *testing.T) {
+	const infile = "test-samples/delete-and-insert.go.in"
+	inputSrc, err := ioutil.ReadFile(infile)
+	assert.NoError(t, err, "Error reading input")
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, infile, inputSrc, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	edits := InspectDryRun(NewInspector(deleteAndInsertVisitor), f)
+
+	body := f.Decls[0].(*ast.FuncDecl).Body
+	kinds := make([]EditKind, len(edits))
+	for l, e := range edits {
+		kinds[l] = e.Kind
+		assert.Equal(t, body, e.Parent)
+	}
+	assert.Equal(t, []EditKind{EditInsertBefore, EditInsertAfter, EditInsertAfter, EditDelete, EditReplace}, kinds)
+	assert.Equal(t, "a", calledName(edits[0].Old))
+	assert.Equal(t, "w", calledName(edits[0].New))
+	assert.Equal(t, "b", calledName(edits[3].Old))
+	assert.Nil(t, edits[3].New)
+	assert.Equal(t, "c", calledName(edits[4].Old))
+	assert.Equal(t, "d", calledName(edits[4].New))
+
+	// The tree is left as it was
+	out := new(bytes.Buffer)
+	assert.NoError(t, format.Node(out, fset, f), "Error formatting output AST")
+	assert.Equal(t, string(inputSrc), out.String())
+}
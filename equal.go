@@ -0,0 +1,167 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"sort"
+)
+
+// A Difference is a structural divergence between two ASTs, found by Diff.
+type Difference struct {
+	// Path leads from the roots of the ASTs to the values which differ, as a sequence of field selectors and indices
+	// (eg. ".Decls[0].Body.List[1]"). It is empty if the roots themselves differ.
+	Path string
+	// A and B are the values which differ, from the first and second AST respectively. Where nodes differ in type, they
+	// are the nodes; where a slice or map differs in length, they are its length.
+	A, B interface{}
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %s != %s", d.Path, describeValue(d.A), describeValue(d.B))
+}
+
+// Equal reports whether the ASTs rooted at a and b are structurally equal: whether their nodes have the same types and
+// their fields hold the same values. Positions, the *ast.Object and *ast.Scope of resolved identifiers, and the
+// Comments, Imports and Unresolved lists of *ast.File (which are derived from the rest of the file) are ignored.
+// Comment groups held in Doc and Comment fields are compared.
+func Equal(a, b ast.Node) bool {
+	d := differ{stopAtFirst: true}
+	d.compare("", reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem())
+	return len(d.diffs) == 0
+}
+
+// Diff returns the structural differences between the ASTs rooted at a and b, ignoring the same things as Equal, in
+// the order they are found in a pre-order walk. Each Difference is reported where the ASTs first diverge, so the
+// differences between the subtrees beneath it are not reported too. The result is empty if a and b are Equal.
+func Diff(a, b ast.Node) []Difference {
+	d := differ{}
+	d.compare("", reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem())
+	return d.diffs
+}
+
+var (
+	objectType = reflect.TypeOf((*ast.Object)(nil))
+	scopeType  = reflect.TypeOf((*ast.Scope)(nil))
+	fileType   = reflect.TypeOf(ast.File{})
+
+	commentGroupType = reflect.TypeOf((*ast.CommentGroup)(nil))
+)
+
+// ignoredFileFields are the fields of ast.File derived from the rest of the file.
+var ignoredFileFields = map[string]bool{"Comments": true, "Imports": true, "Unresolved": true}
+
+type differ struct {
+	stopAtFirst bool
+	diffs       []Difference
+	// ignoreComments also ignores comment groups, which Equal compares
+	ignoreComments bool
+	// wildcard, if set, is called for each pair of values before they are compared, and compares them itself if it
+	// returns true
+	wildcard func(path string, a, b reflect.Value) bool
+}
+
+func (d *differ) differ(path string, a, b interface{}) {
+	d.diffs = append(d.diffs, Difference{Path: path, A: a, B: b})
+}
+
+// done reports whether no more differences need to be found.
+func (d *differ) done() bool {
+	return d.stopAtFirst && len(d.diffs) > 0
+}
+
+func (d *differ) compare(path string, a, b reflect.Value) {
+	if d.wildcard != nil && d.wildcard(path, a, b) {
+		return
+	}
+	switch t := a.Type(); {
+	case t == posType, t == objectType, t == scopeType:
+		return
+	case t == commentGroupType && d.ignoreComments:
+		return
+	case t.Kind() == reflect.Map && (t.Elem() == objectType || t.Elem() == scopeType):
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.differ(path, a.Interface(), b.Interface())
+			}
+			return
+		}
+		if a.Elem().Type() != b.Elem().Type() {
+			d.differ(path, a.Interface(), b.Interface())
+			return
+		}
+		d.compare(path, a.Elem(), b.Elem())
+
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.differ(path, a.Interface(), b.Interface())
+			}
+			return
+		}
+		d.compare(path, a.Elem(), b.Elem())
+
+	case reflect.Struct:
+		for l := 0; l < a.NumField() && !d.done(); l++ {
+			name := a.Type().Field(l).Name
+			if a.Type() == fileType && ignoredFileFields[name] {
+				continue
+			}
+			d.compare(path+"."+name, a.Field(l), b.Field(l))
+		}
+
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			d.differ(path, a.Len(), b.Len())
+			return
+		}
+		for l := 0; l < a.Len() && !d.done(); l++ {
+			d.compare(fmt.Sprintf("%s[%d]", path, l), a.Index(l), b.Index(l))
+		}
+
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			d.differ(path, a.Len(), b.Len())
+			return
+		}
+		keys := a.MapKeys()
+		sort.Slice(keys, func(x, y int) bool {
+			return fmt.Sprint(keys[x].Interface()) < fmt.Sprint(keys[y].Interface())
+		})
+		for _, k := range keys {
+			if d.done() {
+				return
+			}
+			kpath := fmt.Sprintf("%s[%#v]", path, k.Interface())
+			bv := b.MapIndex(k)
+			if !bv.IsValid() {
+				d.differ(kpath, a.MapIndex(k).Interface(), nil)
+				continue
+			}
+			d.compare(kpath, a.MapIndex(k), bv)
+		}
+
+	default:
+		if a.Interface() != b.Interface() {
+			d.differ(path, a.Interface(), b.Interface())
+		}
+	}
+}
+
+// describeValue describes a value held by a Difference: nodes by their type, and anything else by its Go syntax.
+func describeValue(v interface{}) string {
+	switch v := v.(type) {
+	case ast.Node:
+		return fmt.Sprintf("%T", v)
+	case token.Token:
+		return v.String()
+	default:
+		return fmt.Sprintf("%#v", v)
+	}
+}
@@ -0,0 +1,49 @@
+package astor
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseEqualSrc(t *testing.T, src string) *ast.File {
+	f, err := parser.ParseFile(token.NewFileSet(), "equal.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	return f
+}
+
+func TestEqual(t *testing.T) {
+	a := parseEqualSrc(t, "package foo\n\nfunc Bar() int { return 1 + 2 }\n")
+	// only positions differ
+	b := parseEqualSrc(t, "package foo\n\n\n\nfunc Bar() int {\n\treturn 1 +\n\t\t2\n}\n")
+	assert.True(t, Equal(a, b))
+	assert.Empty(t, Diff(a, b))
+
+	c := parseEqualSrc(t, "package foo\n\nfunc Bar() int { return 1 - 2 }\n")
+	assert.False(t, Equal(a, c))
+	assert.Equal(t, []Difference{{
+		Path: ".Decls[0].Body.List[0].Results[0].Op",
+		A:    token.ADD,
+		B:    token.SUB,
+	}}, Diff(a, c))
+
+	assert.True(t, Equal(nil, nil))
+	assert.False(t, Equal(a, nil))
+}
+
+func TestDiff(t *testing.T) {
+	a := parseEqualSrc(t, "package foo\n\nfunc Bar(x int) { a(x); b() }\n")
+	b := parseEqualSrc(t, "package foo\n\nfunc Bar(y int) { a(1); b(); c() }\n")
+
+	diffs := Diff(a, b)
+	assert.Len(t, diffs, 2)
+	assert.Equal(t, ".Decls[0].Type.Params.List[0].Names[0].Name", diffs[0].Path)
+	assert.Equal(t, `.Decls[0].Type.Params.List[0].Names[0].Name: "x" != "y"`, diffs[0].String())
+	assert.Equal(t, ".Decls[0].Body.List: 2 != 3", diffs[1].String())
+
+	c := parseEqualSrc(t, "package foo\n\nfunc Bar(x int) { a(x.y); b() }\n")
+	assert.Equal(t, ".Decls[0].Body.List[0].X.Args[0]: *ast.Ident != *ast.SelectorExpr", Diff(a, c)[0].String())
+}
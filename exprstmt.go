@@ -0,0 +1,28 @@
+package astor
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// AsStmt returns e as a statement, held in an *ast.ExprStmt, so it can be inserted into a list of statements. Only
+// some expressions (such as calls and receives) are valid statements; see Validate.
+func AsStmt(e ast.Expr) ast.Stmt {
+	return &ast.ExprStmt{X: e}
+}
+
+// AsExpr returns the expression computed by the statement s, and whether it has one: the expression held by an
+// *ast.ExprStmt, or the value assigned to a single variable by an = or := *ast.AssignStmt (such as f() in x := f()).
+// Other statements, including assignments of several values and those with an operator, such as x += f() (whose
+// value is x + f()), have no such expression.
+func AsExpr(s ast.Stmt) (ast.Expr, bool) {
+	switch s := s.(type) {
+	case *ast.ExprStmt:
+		return s.X, true
+	case *ast.AssignStmt:
+		if (s.Tok == token.ASSIGN || s.Tok == token.DEFINE) && len(s.Lhs) == 1 && len(s.Rhs) == 1 {
+			return s.Rhs[0], true
+		}
+	}
+	return nil, false
+}
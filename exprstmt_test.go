@@ -0,0 +1,29 @@
+package astor
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsExpr(t *testing.T) {
+	f := parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\ta()\n\tx := b()\n\ty, z = c, d\n\tx += e()\n"+
+		"\ty, z := g()\n\treturn\n}\n")
+	var exprs []ast.Expr
+	for _, s := range f.Decls[0].(*ast.FuncDecl).Body.List {
+		if e, ok := AsExpr(s); ok {
+			exprs = append(exprs, e)
+		}
+	}
+	assert.Len(t, exprs, 2)
+	assert.Equal(t, "a", exprs[0].(*ast.CallExpr).Fun.(*ast.Ident).Name)
+	assert.Equal(t, "b", exprs[1].(*ast.CallExpr).Fun.(*ast.Ident).Name)
+
+	e := exprs[0]
+	s := AsStmt(e)
+	assert.Equal(t, &ast.ExprStmt{X: e}, s)
+	back, ok := AsExpr(s)
+	assert.True(t, ok)
+	assert.Same(t, e, back)
+}
@@ -0,0 +1,78 @@
+package astor
+
+import (
+	"go/ast"
+)
+
+// WithImportsAndUnresolved makes the Inspector also walk the Imports and Unresolved lists of each *ast.File, after
+// its declarations. These list nodes which are also held in the declarations (the import specs, and the identifiers
+// go/parser couldn't resolve), so each is only visited the first time it is reached: if it has already been visited
+// through the declarations, its replacement (or deletion) there is made in the list too, keeping the lists in step
+// with the declarations. An identifier replaced by something other than an identifier is removed from Unresolved.
+func WithImportsAndUnresolved() Option {
+	return func(i *inspectorImpl) {
+		i.visitLists = true
+	}
+}
+
+// visitingFileLists reports whether ii was constructed with WithImportsAndUnresolved.
+func visitingFileLists(ii Inspector) bool {
+	impl, ok := ii.(*inspectorImpl)
+	return ok && impl.visitLists
+}
+
+// A fileListNode is a node held in the Imports or Unresolved list of a file, which is only visited the first time it
+// is reached.
+type fileListNode struct {
+	visited bool
+	node    ast.Node
+	edit    listEdit
+}
+
+// findFileLists records the nodes held in the Imports and Unresolved lists of n, if n is a file and they are to be
+// walked.
+func (i *inspectorImpl) findFileLists(n ast.Node) {
+	f, ok := n.(*ast.File)
+	if !ok || !i.visitLists {
+		return
+	}
+	if i.fileLists == nil {
+		i.fileLists = make(map[ast.Node]*fileListNode, len(f.Imports)+len(f.Unresolved))
+	}
+	for _, spec := range f.Imports {
+		i.fileLists[spec] = &fileListNode{}
+	}
+	for _, id := range f.Unresolved {
+		i.fileLists[id] = &fileListNode{}
+	}
+}
+
+// visitedFileList records the replacement and edits made to original, if it is held in a file's lists and this is
+// the first time it has been reached.
+func (i *inspectorImpl) visitedFileList(original, node ast.Node, edit listEdit) {
+	if r, ok := i.fileLists[original]; ok && !r.visited {
+		r.visited, r.node, r.edit = true, node, edit
+	}
+}
+
+// in returns the replacement and edits made to the node when it was visited, as they apply to it where it is held in
+// s. A replacement which s can't hold removes the node from its list, and inserted nodes which s can't hold are left
+// out.
+func (r *fileListNode) in(s slot) (ast.Node, listEdit) {
+	edit := listEdit{deleted: r.edit.deleted || isNil(r.node) || !s.holds(r.node)}
+	for _, n := range r.edit.before {
+		if s.holds(n) {
+			edit.before = append(edit.before, n)
+		}
+	}
+	for _, n := range r.edit.after {
+		if s.holds(n) {
+			edit.after = append(edit.after, n)
+		}
+	}
+	if s.index < 0 {
+		// deleting the node from a list doesn't remove it from a field holding it too
+		return r.node, listEdit{}
+	}
+	return r.node, edit
+}
@@ -0,0 +1,68 @@
+package astor
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithImportsAndUnresolved(t *testing.T) {
+	src := "package foo\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc Bar() { fmt.Println(a) }\n\nfunc Baz() { b() }\n"
+	for _, iterative := range []bool{false, true} {
+		f, err := parser.ParseFile(token.NewFileSet(), "lists.go", src, parserFlags)
+		assert.NoError(t, err, "Error parsing input")
+		fmtSpec := f.Imports[0]
+
+		visits := map[ast.Node]int{}
+		var unresolved []string
+		visitor := func(i Inspector, n ast.Node) bool {
+			if n == nil {
+				return true
+			}
+			visits[n]++
+			switch n := n.(type) {
+			case *ast.ImportSpec:
+				if n.Path.Value == `"os"` {
+					i.Delete()
+				}
+			case *ast.Ident:
+				if i.Parent() == ast.Node(f) && i.Index() >= 0 {
+					// visited through Unresolved
+					unresolved = append(unresolved, n.Name)
+				}
+				if n.Name == "a" {
+					i.Replace(ast.NewIdent("c"))
+				}
+			case *ast.FuncDecl:
+				if n.Name.Name == "Baz" {
+					// b is only reached through Unresolved
+					i.SkipChildren()
+				}
+			}
+			return true
+		}
+		inspector := NewInspector(visitor, WithImportsAndUnresolved())
+		if iterative {
+			InspectIterative(inspector, f)
+		} else {
+			inspector.Inspect(f)
+		}
+
+		assert.Equal(t, []*ast.ImportSpec{fmtSpec}, f.Imports)
+		assert.Len(t, f.Decls[0].(*ast.GenDecl).Specs, 1)
+		assert.Equal(t, 1, visits[fmtSpec])
+		// the replacement of a is kept in Unresolved too
+		var names []string
+		for _, id := range f.Unresolved {
+			names = append(names, id.Name)
+			if id.Name == "fmt" {
+				assert.Equal(t, 1, visits[id])
+			}
+		}
+		assert.Equal(t, []string{"fmt", "c", "b"}, names)
+		assert.Equal(t, []string{"b"}, unresolved)
+	}
+}
@@ -0,0 +1,36 @@
+package astor
+
+import (
+	"go/ast"
+	"regexp"
+	"strings"
+)
+
+// generatedComment matches the comment marking a file as generated, by the convention described at
+// https://go.dev/s/generatedcode.
+var generatedComment = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// IsGenerated reports whether f was generated by a tool, by the Go convention: whether a line comment before its
+// package clause reads "// Code generated ... DO NOT EDIT.". The file must have been parsed with its comments.
+func IsGenerated(f *ast.File) bool {
+	for _, g := range f.Comments {
+		if g.Pos() >= f.Package {
+			break
+		}
+		for _, c := range g.List {
+			if strings.HasPrefix(c.Text, "//") && generatedComment.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SkipGenerated makes the Inspector leave generated files (see IsGenerated) alone, as codemods should: they aren't
+// visited, and nor are their children. InspectSource and ProcessDir return and write back the source of a generated
+// file as it was, rather than formatting it.
+func SkipGenerated() Option {
+	return func(i *inspectorImpl) {
+		i.skipGenerated = true
+	}
+}
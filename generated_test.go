@@ -0,0 +1,50 @@
+package astor
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsGenerated(t *testing.T) {
+	for src, generated := range map[string]bool{
+		"// Code generated by stringer; DO NOT EDIT.\n\npackage foo\n":                      true,
+		"// Copyright 2024\n\n// Code generated by hand. DO NOT EDIT.\n\npackage foo\n":     true,
+		"//go:build linux\n// Code generated by protoc-gen-go. DO NOT EDIT.\npackage foo\n": true,
+		"package foo\n\n// Code generated by stringer; DO NOT EDIT.\n":                      false,
+		"// Code generated by stringer; DO NOT EDIT\n\npackage foo\n":                       false,
+		"/* Code generated by stringer; DO NOT EDIT. */\n\npackage foo\n":                   false,
+		"// This code was generated. DO NOT EDIT.\n\npackage foo\n":                         false,
+	} {
+		f, err := parser.ParseFile(token.NewFileSet(), "gen.go", src, parserFlags)
+		assert.NoError(t, err, "Error parsing input")
+		assert.Equal(t, generated, IsGenerated(f), src)
+	}
+}
+
+func TestSkipGenerated(t *testing.T) {
+	src := "// Code generated by stringer; DO NOT EDIT.\n\npackage foo\n\nfunc   Bar() {}\n"
+	rename := func(i Inspector, n ast.Node) bool {
+		if n, ok := n.(*ast.Ident); ok && n.Name == "Bar" {
+			i.Replace(ast.NewIdent("Baz"))
+		}
+		return true
+	}
+
+	out, err := InspectSource([]byte(src), rename, SkipGenerated())
+	assert.NoError(t, err)
+	assert.Equal(t, src, string(out))
+	out, err = InspectSource([]byte(src), rename)
+	assert.NoError(t, err)
+	assert.Equal(t, "// Code generated by stringer; DO NOT EDIT.\n\npackage foo\n\nfunc Baz() {}\n", string(out))
+
+	// generated files among others are skipped
+	generated := parseEqualSrc(t, src)
+	other := parseEqualSrc(t, "package foo\n\nfunc Bar() {}\n")
+	files := InspectFiles(NewInspector(rename, SkipGenerated()), []*ast.File{generated, other})
+	assert.Equal(t, "Bar", files[0].Decls[0].(*ast.FuncDecl).Name.Name)
+	assert.Equal(t, "Baz", files[1].Decls[0].(*ast.FuncDecl).Name.Name)
+}
@@ -0,0 +1,128 @@
+package astor
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// IsGenericInstantiation reports whether n instantiates a generic type or function with a single type argument (as in
+// List[int]) rather than indexing an array, slice, map, pointer or string (as in list[0]). The parser produces an
+// *ast.IndexExpr for both, so they can only be told apart from context.
+//
+// If i was constructed with type information that covers n, that settles it. Otherwise the syntax is used: a type
+// literal index or a type-only position (when n is the node i is visiting), or an X or index declared in the same file
+// as a type or generic function. If none of these apply, n is assumed to be an index and false is returned.
+func IsGenericInstantiation(i Inspector, n *ast.IndexExpr) bool {
+	if n == nil {
+		return false
+	}
+
+	if impl, ok := i.(*inspectorImpl); ok && impl.info != nil {
+		if id := indexedIdent(n.X); id != nil {
+			if _, ok := impl.info.Instances[id]; ok {
+				return true
+			}
+		}
+		if tv, ok := impl.info.Types[n.X]; ok {
+			return tv.IsType()
+		}
+	}
+
+	switch n.Index.(type) {
+	case *ast.ArrayType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType, *ast.MapType, *ast.StructType:
+		return true
+	}
+	if id := indexedIdent(n.X); id != nil && id.Obj != nil {
+		switch id.Obj.Kind {
+		case ast.Typ:
+			return true
+		case ast.Fun:
+			fd, ok := id.Obj.Decl.(*ast.FuncDecl)
+			return ok && fd.Type.TypeParams != nil
+		case ast.Var, ast.Con:
+			return false
+		}
+	}
+	if id, ok := n.Index.(*ast.Ident); ok && id.Obj != nil && id.Obj.Kind == ast.Typ {
+		return true
+	}
+
+	if impl, ok := i.(*inspectorImpl); ok && impl.node == n {
+		return isTypePosition(impl.slot.parent, impl.slot.field)
+	}
+	return false
+}
+
+// indexedIdent returns the identifier naming the thing indexed by x, or nil if x isn't a (possibly qualified) name.
+func indexedIdent(x ast.Expr) *ast.Ident {
+	switch x := x.(type) {
+	case *ast.Ident:
+		return x
+	case *ast.SelectorExpr:
+		return x.Sel
+	}
+	return nil
+}
+
+// isTypePosition reports whether the named field of parent can only hold a type.
+func isTypePosition(parent ast.Node, field string) bool {
+	switch parent.(type) {
+	case *ast.ArrayType:
+		return field == "Elt"
+	case *ast.ChanType, *ast.Ellipsis, *ast.MapType:
+		return true
+	case *ast.CompositeLit, *ast.Field, *ast.TypeAssertExpr, *ast.TypeSpec, *ast.ValueSpec:
+		return field == "Type"
+	}
+	return false
+}
+
+// IsConstraintElem reports whether the node i is visiting is an element of a type constraint, or a part of one: the
+// constraint of a type parameter (as in [T ~int | ~string]), or an element embedded in an interface (as in
+// interface{ ~int | ~string } or interface{ io.Reader }), or any of the unions (*ast.BinaryExpr with |), ~ terms
+// (*ast.UnaryExpr with ~) and types making up such an element. The types within those types, such as the int of
+// ~[]int, aren't elements themselves.
+//
+// This uses the nodes enclosing the current one, so is always false for an Inspector not constructed by this package.
+func IsConstraintElem(i Inspector) bool {
+	impl, ok := i.(*inspectorImpl)
+	if !ok || impl.node == nil {
+		return false
+	}
+
+	// climb through the unions and ~ terms holding the node to the field holding the element
+	child := impl.node
+	for l := len(impl.ancestors) - 1; l >= 0; l-- {
+		switch n := impl.ancestors[l].(type) {
+		case *ast.BinaryExpr:
+			if n.Op != token.OR {
+				return false
+			}
+		case *ast.UnaryExpr:
+			if n.Op != token.TILDE {
+				return false
+			}
+		case *ast.Field:
+			if n.Type != child || l < 2 {
+				return false
+			}
+			list, ok := impl.ancestors[l-1].(*ast.FieldList)
+			if !ok {
+				return false
+			}
+			switch p := impl.ancestors[l-2].(type) {
+			case *ast.InterfaceType:
+				return p.Methods == list && len(n.Names) == 0
+			case *ast.FuncType:
+				return p.TypeParams == list
+			case *ast.TypeSpec:
+				return p.TypeParams == list
+			}
+			return false
+		default:
+			return false
+		}
+		child = impl.ancestors[l]
+	}
+	return false
+}
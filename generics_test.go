@@ -0,0 +1,110 @@
+package astor
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const genericsSrc = `package foo
+
+import "sync/atomic"
+
+type List[T any] []T
+
+func First[T any](l List[T]) T { return l[0] }
+
+var p atomic.Pointer[int]
+
+func Use(l List[int], m map[string]int, k string) int {
+	_ = First[int](l)
+	_ = List[int]{}
+	return l[0] + m[k]
+}
+`
+
+func genericInstantiations(i func(Visitor) Inspector, f *ast.File) map[string]bool {
+	found := make(map[string]bool)
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n, ok := n.(*ast.IndexExpr); ok {
+			found[types.ExprString(n)] = IsGenericInstantiation(i, n)
+		}
+		return true
+	}
+	i(visitor).Inspect(f)
+	return found
+}
+
+func TestIsGenericInstantiation(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "generics.go", genericsSrc, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	expected := map[string]bool{
+		"List[T]":             true,
+		"l[0]":                false,
+		"atomic.Pointer[int]": true,
+		"List[int]":           true,
+		"First[int]":          true,
+		"m[k]":                false,
+	}
+
+	// From the syntax alone
+	found := genericInstantiations(func(v Visitor) Inspector { return NewInspector(v) }, f)
+	assert.Equal(t, expected, found)
+
+	// From type information
+	info := &types.Info{
+		Types:     make(map[ast.Expr]types.TypeAndValue),
+		Instances: make(map[*ast.Ident]types.Instance),
+	}
+	conf := types.Config{Importer: importer.Default()}
+	_, err = conf.Check("foo", fset, []*ast.File{f}, info)
+	assert.NoError(t, err, "Error type-checking input")
+	found = genericInstantiations(func(v Visitor) Inspector { return NewInspectorWithTypes(v, info) }, f)
+	assert.Equal(t, expected, found)
+
+	// A generic type outside the file in an expression can't be told from an index without type information
+	f, err = parser.ParseFile(fset, "generics.go", "package foo\n\nvar p = new(atomic.Pointer[int])\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	found = genericInstantiations(func(v Visitor) Inspector { return NewInspector(v) }, f)
+	assert.Equal(t, map[string]bool{"atomic.Pointer[int]": false}, found)
+}
+
+func TestIsConstraintElem(t *testing.T) {
+	src := `package foo
+
+type Number interface {
+	~int | ~float64 | Small
+	io.Reader
+	Len() int
+}
+
+func Sum[T ~[]E, E Number](s T, n int) {
+	_ = a | b
+}
+`
+	f, err := parser.ParseFile(token.NewFileSet(), "constraints.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var elems []string
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n != nil && IsConstraintElem(i) {
+			elems = append(elems, types.ExprString(n.(ast.Expr)))
+		}
+		return true
+	}
+	expected := []string{"~int | ~float64 | Small", "~int | ~float64", "~int", "int", "~float64", "float64", "Small",
+		"io.Reader", "~[]E", "[]E", "Number"}
+	NewInspector(visitor).Inspect(f)
+	assert.Equal(t, expected, elems)
+
+	elems = nil
+	InspectIterative(NewInspector(visitor), f)
+	assert.Equal(t, expected, elems)
+}
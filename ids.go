@@ -0,0 +1,51 @@
+package astor
+
+import (
+	"go/ast"
+	"go/token"
+	"reflect"
+)
+
+// WithNodeIDs makes the Inspector assign each node a unique, non-zero identifier the first time it is visited, which
+// can be looked up with ID. Identifiers increase in the order nodes are visited, and are kept across calls to Inspect,
+// so they can be used to correlate nodes before and after a transformation even once replacements have moved them
+// around the tree. The Inspector holds a reference to every node it has identified. Each Clone of the Inspector
+// identifies nodes independently.
+func WithNodeIDs() Option {
+	return func(i *inspectorImpl) {
+		i.ids = make(map[ast.Node]uint64)
+	}
+}
+
+func (i *inspectorImpl) ID(n ast.Node) uint64 {
+	return i.ids[n]
+}
+
+// assignID assigns n the next identifier, if identifiers are being assigned and it doesn't already have one.
+func (i *inspectorImpl) assignID(n ast.Node) {
+	if i.ids == nil || n == nil {
+		return
+	}
+	if _, ok := i.ids[n]; !ok {
+		i.ids[n] = uint64(len(i.ids) + 1)
+	}
+}
+
+// A NodeKey identifies a node by its type and the positions it spans, rather than by its pointer, so it can be used as
+// a map key which survives copying the AST or parsing the same source again. Positions are relative
+// to the token.FileSet the source was parsed into, so keys only match across parses which add the same files to their
+// FileSets in the same order.
+type NodeKey struct {
+	// Type is the type of the node, such as "*ast.Ident"
+	Type     string
+	Pos, End token.Pos
+}
+
+// Key returns the NodeKey of n. The keys of different nodes only collide if they are of the same type and span the
+// same source, which nodes parsed from source never do, but nodes constructed without positions may.
+func Key(n ast.Node) NodeKey {
+	if n == nil {
+		return NodeKey{}
+	}
+	return NodeKey{Type: reflect.TypeOf(n).String(), Pos: n.Pos(), End: n.End()}
+}
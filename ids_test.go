@@ -0,0 +1,67 @@
+package astor
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeIDs(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "ids.go", "package foo\n\nvar a = b\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var visited []ast.Node
+	i := NewInspector(func(i Inspector, n ast.Node) bool {
+		if n != nil {
+			visited = append(visited, n)
+		}
+		if id, ok := n.(*ast.Ident); ok && id.Name == "b" {
+			i.Replace(ast.NewIdent("c"))
+		}
+		return true
+	}, WithNodeIDs())
+	i.Inspect(f)
+
+	for l, n := range visited {
+		assert.Equal(t, uint64(l+1), i.ID(n))
+	}
+	// The replaced node keeps its identifier, and its replacement (which wasn't visited) has none
+	assert.Equal(t, uint64(len(visited)), i.ID(visited[len(visited)-1]))
+	assert.Equal(t, uint64(0), i.ID(f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0]))
+
+	// Identifiers are stable across walks
+	visited = nil
+	i.Inspect(f)
+	assert.Equal(t, uint64(1), i.ID(visited[0]))
+	assert.Equal(t, uint64(len(visited)+1), i.ID(visited[len(visited)-1]))
+
+	// Without the option nothing is identified
+	assert.Equal(t, uint64(0), NewInspector(func(Inspector, ast.Node) bool { return true }).ID(f))
+}
+
+func TestKey(t *testing.T) {
+	src := "package foo\n\nfunc Bar(a, b int) int {\n\treturn a + b + 1\n}\n"
+	keys := func() []NodeKey {
+		f, err := parser.ParseFile(token.NewFileSet(), "key.go", src, parserFlags)
+		assert.NoError(t, err, "Error parsing input")
+		var keys []NodeKey
+		for _, n := range Collect(f, func(ast.Node) bool { return true }) {
+			keys = append(keys, Key(n))
+		}
+		return keys
+	}
+
+	// Parsing the source again gives the same keys, and no two nodes share one
+	first := keys()
+	assert.Equal(t, first, keys())
+	seen := map[NodeKey]bool{}
+	for _, k := range first {
+		assert.False(t, seen[k], k)
+		seen[k] = true
+	}
+	assert.Equal(t, NodeKey{Type: "*ast.Ident", Pos: 9, End: 12}, first[1])
+	assert.Equal(t, NodeKey{}, Key(nil))
+}
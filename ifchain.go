@@ -0,0 +1,72 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// ElseIfChain returns the branches of the if-else-if chain starting at n, in order: n itself, followed by each
+// *ast.IfStmt held in the Else of the one before. A final else block isn't a branch, so isn't included.
+func ElseIfChain(n *ast.IfStmt) []*ast.IfStmt {
+	var chain []*ast.IfStmt
+	for ; n != nil; n, _ = n.Else.(*ast.IfStmt) {
+		chain = append(chain, n)
+	}
+	return chain
+}
+
+// InsertElseIf adds a branch to the if-else-if chain being visited by i, straight after the *ast.IfStmt i is
+// visiting: the statement is replaced with a copy whose Else is an else-if with the cond and body passed, which holds
+// the Else the statement had. The new branch is inspected with the rest of the chain.
+//
+// InsertElseIf panics if i isn't visiting an if statement.
+func InsertElseIf(i Inspector, cond ast.Expr, body *ast.BlockStmt) {
+	n := currentIf(i, "InsertElseIf")
+	edited := *n
+	edited.Else = &ast.IfStmt{Cond: cond, Body: body, Else: n.Else}
+	i.Replace(&edited)
+}
+
+// RemoveIfBranch removes the *ast.IfStmt i is visiting from the if-else-if chain it's a branch of, keeping the
+// branches after it: the statement is replaced with its Else, or deleted if it has none. Removing the first branch
+// makes the next one the start of the chain, or the else block a plain block.
+//
+// The Init statement of a removed branch is kept if there is anything after it, as the variables it declares are in
+// scope there: it's moved to the next else-if if that has no Init of its own, and otherwise what follows is wrapped in
+// a block starting with it.
+//
+// RemoveIfBranch panics if i isn't visiting an if statement.
+func RemoveIfBranch(i Inspector) {
+	n := currentIf(i, "RemoveIfBranch")
+	var rest ast.Stmt
+	switch next := n.Else.(type) {
+	case *ast.IfStmt:
+		rest = next
+		if n.Init != nil && next.Init == nil {
+			edited := *next
+			edited.Init = n.Init
+			rest = &edited
+		} else if n.Init != nil {
+			rest = &ast.BlockStmt{List: []ast.Stmt{n.Init, next}}
+		}
+	case *ast.BlockStmt:
+		rest = next
+		if n.Init != nil {
+			rest = &ast.BlockStmt{Lbrace: next.Lbrace, List: append([]ast.Stmt{n.Init}, next.List...), Rbrace: next.Rbrace}
+		}
+	}
+
+	if rest == nil {
+		i.Delete()
+		return
+	}
+	i.Replace(rest)
+}
+
+func currentIf(i Inspector, name string) *ast.IfStmt {
+	n, ok := i.Current().(*ast.IfStmt)
+	if !ok {
+		panic(fmt.Sprintf("astor: %s called while not visiting an if statement", name))
+	}
+	return n
+}
@@ -0,0 +1,135 @@
+package astor
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const ifChainSrc = `package foo
+
+func Bar(a int) {
+	if a == 1 {
+		one()
+	} else if a == 2 {
+		two()
+	} else if b := a * 2; b == 6 {
+		three()
+	} else {
+		other()
+	}
+}
+`
+
+func TestElseIfChain(t *testing.T) {
+	f := parseEqualSrc(t, ifChainSrc)
+	n := f.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.IfStmt)
+	chain := ElseIfChain(n)
+	assert.Len(t, chain, 3)
+	assert.Equal(t, n, chain[0])
+	assert.Equal(t, n.Else, chain[1])
+	assert.Equal(t, chain[1].Else, chain[2])
+	assert.IsType(t, &ast.BlockStmt{}, chain[2].Else)
+	assert.Len(t, ElseIfChain(chain[2]), 1)
+	assert.Empty(t, ElseIfChain(nil))
+}
+
+// editIfChain edits the if statement whose condition compares against value with f.
+func editIfChain(t *testing.T, fset *token.FileSet, src, value string, f func(Inspector)) *ast.File {
+	file, err := parser.ParseFile(fset, "ifchain.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		if n, ok := n.(*ast.IfStmt); ok {
+			if cond, ok := n.Cond.(*ast.BinaryExpr); ok && cond.Y.(*ast.BasicLit).Value == value {
+				f(i)
+			}
+		}
+		return true
+	}).Inspect(file)
+	return file
+}
+
+func TestInsertElseIf(t *testing.T) {
+	fset := token.NewFileSet()
+	file := editIfChain(t, fset, ifChainSrc, "2", func(i Inspector) {
+		InsertElseIf(i, &ast.BinaryExpr{X: ast.NewIdent("a"), Op: token.EQL, Y: &ast.BasicLit{Kind: token.INT, Value: "4"}},
+			&ast.BlockStmt{List: []ast.Stmt{&ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent("four")}}}})
+	})
+	var out strings.Builder
+	assert.NoError(t, format.Node(&out, fset, file))
+	assert.Equal(t, `package foo
+
+func Bar(a int) {
+	if a == 1 {
+		one()
+	} else if a == 2 {
+		two()
+	} else if a == 4 {
+		four()
+	} else if b := a * 2; b == 6 {
+		three()
+	} else {
+		other()
+	}
+}
+`, out.String())
+
+	assert.Panics(t, func() {
+		NewInspector(func(i Inspector, n ast.Node) bool {
+			InsertElseIf(i, nil, nil)
+			return true
+		}).Inspect(&ast.BlockStmt{})
+	})
+}
+
+func TestRemoveIfBranch(t *testing.T) {
+	for _, c := range []struct {
+		value, src, out string
+	}{
+		{
+			value: "1",
+			src:   ifChainSrc,
+			out:   "if a == 2 {\n\t\ttwo()\n\t} else if b := a * 2; b == 6 {\n\t\tthree()\n\t} else {\n\t\tother()\n\t}",
+		},
+		{
+			value: "2",
+			src:   ifChainSrc,
+			out:   "if a == 1 {\n\t\tone()\n\t} else if b := a * 2; b == 6 {\n\t\tthree()\n\t} else {\n\t\tother()\n\t}",
+		},
+		{
+			value: "6",
+			src:   ifChainSrc,
+			out:   "if a == 1 {\n\t\tone()\n\t} else if a == 2 {\n\t\ttwo()\n\t} else {\n\t\tb := a * 2\n\t\tother()\n\t}",
+		},
+		{
+			value: "1",
+			src:   "package foo\n\nfunc Bar(a int) {\n\tif b := a; b == 1 {\n\t} else if b == 2 {\n\t}\n}\n",
+			out:   "if b := a; b == 2 {\n\t}",
+		},
+		{
+			value: "1",
+			src:   "package foo\n\nfunc Bar(a int) {\n\tif b := a; b == 1 {\n\t} else if c := b; c == 2 {\n\t}\n}\n",
+			out:   "{\n\t\tb := a\n\t\tif c := b; c == 2 {\n\t\t}\n\t}",
+		},
+		{
+			value: "1",
+			src:   "package foo\n\nfunc Bar(a int) {\n\tif a == 1 {\n\t}\n}\n",
+			out:   "",
+		},
+		{
+			value: "2",
+			src:   "package foo\n\nfunc Bar(a int) {\n\tif a == 1 {\n\t} else if a == 2 {\n\t}\n}\n",
+			out:   "if a == 1 {\n\t}",
+		},
+	} {
+		// the removed lines are left blank when formatted, so the structure is compared instead
+		file := editIfChain(t, token.NewFileSet(), c.src, c.value, RemoveIfBranch)
+		expected := parseEqualSrc(t, "package foo\n\nfunc Bar(a int) {\n\t"+c.out+"\n}\n")
+		assert.Empty(t, Diff(expected, file), c.src)
+	}
+}
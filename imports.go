@@ -0,0 +1,158 @@
+package astor
+
+import (
+	"go/ast"
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// EnsureImport adds an import of path to file, named name (or by its package name if name is empty), unless file
+// already has exactly that import, reporting whether it was added. The import is added to the first import
+// declaration, before the first import which sorts after it, or in a new declaration after the package clause if
+// file has none.
+func EnsureImport(file *ast.File, path, name string) bool {
+	for _, spec := range file.Imports {
+		if importPath(spec) == path && importName(spec) == name {
+			return false
+		}
+	}
+
+	spec := &ast.ImportSpec{Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(path)}}
+	if name != "" {
+		spec.Name = ast.NewIdent(name)
+	}
+
+	var decl *ast.GenDecl
+	for _, d := range file.Decls {
+		if d, ok := d.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			decl = d
+			break
+		}
+	}
+	if decl == nil {
+		decl = &ast.GenDecl{TokPos: file.Name.End(), Tok: token.IMPORT}
+		file.Decls = append([]ast.Decl{decl}, file.Decls...)
+	}
+
+	l := 0
+	for l < len(decl.Specs) && importPath(decl.Specs[l].(*ast.ImportSpec)) <= path {
+		l++
+	}
+	// the printer keeps the import with its neighbours if it has one of their positions
+	switch {
+	case l > 0:
+		spec.Path.ValuePos = decl.Specs[l-1].Pos()
+	case len(decl.Specs) > 0:
+		spec.Path.ValuePos = decl.Specs[0].Pos()
+	default:
+		spec.Path.ValuePos = decl.TokPos
+	}
+	if spec.Name != nil {
+		spec.Name.NamePos = spec.Path.ValuePos
+	}
+	decl.Specs = append(decl.Specs[:l], append([]ast.Spec{spec}, decl.Specs[l:]...)...)
+	if len(decl.Specs) > 1 && !decl.Lparen.IsValid() {
+		// more than one import has to be in parentheses
+		decl.Lparen = decl.TokPos
+	}
+	file.Imports = append(file.Imports, spec)
+	return true
+}
+
+// RemoveUnusedImports removes the imports of file whose names it doesn't refer to, along with their comments and any
+// import declarations left empty, reporting whether any were removed. Without type information the name of an
+// unnamed import is taken to be the last element of its path, without any "go-" prefix or major version suffix (eg.
+// "yaml" for "gopkg.in/yaml.v3"), so an import of a package with another name may be removed. Blank and dot imports,
+// and imports of "C", are never removed.
+func RemoveUnusedImports(file *ast.File) bool {
+	used := map[string]bool{}
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			// an identifier resolved to a declaration in the file doesn't refer to an import
+			if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil {
+				used[id.Name] = true
+			}
+		}
+		return true
+	}).Inspect(file)
+
+	unused := map[*ast.ImportSpec]bool{}
+	for _, spec := range file.Imports {
+		switch name := importName(spec); {
+		case name == "_", name == ".", importPath(spec) == "C":
+		case name == "" && !used[guessPackageName(importPath(spec))], name != "" && !used[name]:
+			unused[spec] = true
+		}
+	}
+	if len(unused) == 0 {
+		return false
+	}
+
+	removed := map[*ast.CommentGroup]bool{}
+	decls := file.Decls[:0]
+	for _, d := range file.Decls {
+		if d, ok := d.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			specs := d.Specs[:0]
+			for _, spec := range d.Specs {
+				if spec := spec.(*ast.ImportSpec); unused[spec] {
+					removed[spec.Doc], removed[spec.Comment] = true, true
+					continue
+				}
+				specs = append(specs, spec)
+			}
+			d.Specs = specs
+			if len(d.Specs) == 0 {
+				removed[d.Doc] = true
+				continue
+			}
+		}
+		decls = append(decls, d)
+	}
+	file.Decls = decls
+
+	imports := file.Imports[:0]
+	for _, spec := range file.Imports {
+		if !unused[spec] {
+			imports = append(imports, spec)
+		}
+	}
+	file.Imports = imports
+	comments := file.Comments[:0]
+	for _, g := range file.Comments {
+		if !removed[g] {
+			comments = append(comments, g)
+		}
+	}
+	file.Comments = comments
+	return true
+}
+
+func importPath(spec *ast.ImportSpec) string {
+	p, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return spec.Path.Value
+	}
+	return p
+}
+
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name == nil {
+		return ""
+	}
+	return spec.Name.Name
+}
+
+// guessPackageName guesses the name of the package imported from p, which is usually the last element of its path.
+func guessPackageName(p string) string {
+	base := path.Base(p)
+	if len(base) > 1 && base[0] == 'v' && strings.Trim(base[1:], "0123456789") == "" && p != base {
+		// a major version suffix, as in example.com/foo/v2
+		base = path.Base(path.Dir(p))
+	}
+	if l := strings.Index(base, ".v"); l > 0 && strings.Trim(base[l+2:], "0123456789") == "" {
+		base = base[:l]
+	}
+	return strings.TrimPrefix(base, "go-")
+}
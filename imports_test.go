@@ -0,0 +1,74 @@
+package astor
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func formatImports(t *testing.T, src string, f func(*ast.File) bool) (string, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "imports.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	changed := f(file)
+	var out strings.Builder
+	assert.NoError(t, format.Node(&out, fset, file))
+	return out.String(), changed
+}
+
+func TestEnsureImport(t *testing.T) {
+	out, added := formatImports(t, "package foo\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n", func(f *ast.File) bool {
+		return EnsureImport(f, "os", "")
+	})
+	assert.True(t, added)
+	assert.Equal(t, "package foo\n\nimport (\n\t\"fmt\"\n\t\"os\"\n\t\"strings\"\n)\n", out)
+
+	out, added = formatImports(t, "package foo\n\nimport \"fmt\"\n", func(f *ast.File) bool {
+		return EnsureImport(f, "bytes", "b")
+	})
+	assert.True(t, added)
+	assert.Equal(t, "package foo\n\nimport (\n\tb \"bytes\"\n\t\"fmt\"\n)\n", out)
+
+	out, added = formatImports(t, "package foo\n\nvar a = 1\n", func(f *ast.File) bool {
+		return EnsureImport(f, "fmt", "")
+	})
+	assert.True(t, added)
+	assert.Equal(t, "package foo\n\nimport \"fmt\"\n\nvar a = 1\n", out)
+
+	_, added = formatImports(t, "package foo\n\nimport \"fmt\"\n", func(f *ast.File) bool {
+		return EnsureImport(f, "fmt", "")
+	})
+	assert.False(t, added)
+}
+
+func TestRemoveUnusedImports(t *testing.T) {
+	src := `package foo
+
+import (
+	_ "embed"
+	"fmt"
+	// os is unused
+	"os"
+	"gopkg.in/yaml.v3"
+	s "strings"
+)
+
+import "bytes"
+
+func Bar(os int) {
+	fmt.Println(yaml.Marshal, os)
+}
+`
+	out, removed := formatImports(t, src, RemoveUnusedImports)
+	assert.True(t, removed)
+	assert.Equal(t, "package foo\n\nimport (\n\t_ \"embed\"\n\t\"fmt\"\n\n\t\"gopkg.in/yaml.v3\"\n)\n\n"+
+		"func Bar(os int) {\n\tfmt.Println(yaml.Marshal, os)\n}\n", out)
+
+	_, removed = formatImports(t, "package foo\n\nimport \"fmt\"\n\nvar a = fmt.Sprint()\n", RemoveUnusedImports)
+	assert.False(t, removed)
+}
@@ -3,52 +3,794 @@ package astor
 import (
 	"fmt"
 	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
 	"sync"
+	"sync/atomic"
 )
 
 // Visitor is called by an Inspector for each node in an AST. If the result is true, each of the children of
 // node will be visited, followed by a call with node as nil. This is deliberately similar to ast.Visitor.
+//
+// If the result is false, neither the children nor the terminating nil call are visited. To skip the children but
+// still be called with nil when the Inspector is finished with node (eg. to pop state pushed on entry), call
+// Inspector.SkipChildren() and return true.
 type Visitor func(i Inspector, node ast.Node) (recurse bool)
 
 // An Inspector visits each node in an AST, calling a Visitor. The current node may be replaced in the AST with a call
 // to Replace().
+//
+// A node held in more than one place in the AST (as can happen in one built or copied by hand) is visited, and may be
+// replaced, each time it is reached, which may make the changes to it inconsistent; see SkipSharedNodes and
+// OnSharedNode.
+//
+// An Inspector keeps the state of the walk it is making, so may only make one walk at a time: it must not be used
+// from several goroutines at once. To walk several ASTs in parallel, give each goroutine its own Clone(). In
+// particular, the methods which change the AST (Replace, Delete and so on) must only be called by the Visitor, from
+// the goroutine it was called on, before it returns. They panic if they are called once the visit is over, or while
+// another of them is running; goroutines started by a Visitor must hand their results back to it instead.
 type Inspector interface {
-	// Current returns the node currently being inspected
+	// Current returns the node currently being inspected: once the Visitor has replaced it, the replacement
 	Current() ast.Node
-	// Replace replaces the node currently being inspected with the passed node
+	// Original returns the node which was held where the current node is before it was visited, so it differs from
+	// Current once the current node has been replaced. During the terminating nil visit, it returns the node which was
+	// held where the node being finished is, before it was replaced.
+	Original() ast.Node
+	// Replace replaces the node currently being inspected with the passed node. It panics if the node can't be held
+	// where the current node is in the AST (eg. replacing an *ast.Ident in an ast.Stmt slot). A node held in a field
+	// which may be nil (eg. the Init of an *ast.IfStmt, or the Tag of an *ast.Field) may be replaced with nil to
+	// remove it, in which case its children are not inspected, and nor is it visited again with nil.
+	//
+	// If the Visitor replaces or deletes the node more than once in a visit, the last call wins: each replacement
+	// replaces the one before (and is what Current returns), and a replacement after a Delete takes its place.
+	//
+	// During the terminating nil visit, Replace replaces the node being finished, whose children have already been
+	// inspected. Current still returns nil.
 	Replace(ast.Node)
+	// ReplaceAndRevisit replaces the node currently being inspected with the passed node, and then calls the Visitor
+	// again for the replacement. Whether the replacement's children are inspected is decided by that later call.
+	ReplaceAndRevisit(ast.Node)
+	// WrapExpr replaces the expression currently being inspected with the result of passing it to f, which typically
+	// wraps it (eg. x becomes instrument(x)). The walk goes on to visit the wrapper's children, so the expression will
+	// be visited again; WrapExpr does nothing for an expression it has already wrapped, or for a wrapper it returned.
+	// It panics if the current node isn't an ast.Expr.
+	WrapExpr(f func(ast.Expr) ast.Expr)
+	// WrapStmt is WrapExpr for statements. It panics if the current node isn't an ast.Stmt.
+	WrapStmt(f func(ast.Stmt) ast.Stmt)
+	// SkipChildren stops the Inspector inspecting the children of the current node, though (unlike the Visitor
+	// returning false) it is still followed by a call with node as nil, if the Visitor returns true
+	SkipChildren()
+	// SkipField stops the Inspector inspecting the children held in the named field of the current node (eg. "Else"
+	// of an *ast.IfStmt), while still inspecting those in its other fields. It panics if the node has no such field
+	SkipField(name string)
+	// Delete removes the node currently being inspected from the slice holding it in its parent, or from a field which
+	// may be nil, as Replace(nil) does. Its children are not inspected, and nor is it visited again with nil. It panics
+	// if the node isn't held in a slice or such a field.
+	Delete()
+	// InsertBefore inserts the passed node before the node currently being inspected, in the slice holding it in its
+	// parent. The inserted node is not inspected. It panics if the node isn't held in a slice.
+	InsertBefore(ast.Node)
+	// InsertAfter inserts the passed node after the node currently being inspected, in the slice holding it in its
+	// parent. The inserted node is not inspected. It panics if the node isn't held in a slice.
+	InsertAfter(ast.Node)
+	// Stop ends the walk once the Visitor returns: no further nodes are visited, and nor are the terminating nil visits
+	// of the nodes enclosing the current one. Changes already made to the AST are kept.
+	Stop()
+	// Err returns the error which ended the last walk early, such as a *DepthError or *NodeLimitError, or nil if it
+	// wasn't ended by an error. With Recover, it is the *VisitorPanicError for the first panic in the Visitor
+	Err() error
+	// Dirty reports whether the current node has been replaced or deleted, or had nodes inserted alongside it, or
+	// whether any of that has happened to a node in its subtree. During the terminating nil visit, it covers the whole
+	// of the node's subtree
+	Dirty() bool
+	// Parent returns the node holding the node currently being inspected, or nil at the root of the walk
+	Parent() ast.Node
+	// EnclosingFunc returns the innermost function enclosing the node currently being inspected: the *ast.FuncDecl or
+	// *ast.FuncLit among its ancestors, such as the one whose results a return statement returns. It returns nil at
+	// package scope, or if the current node is itself the only function. The function is the node as it is being
+	// inspected, so holds any changes the Visitor has made to it
+	EnclosingFunc() ast.Node
+	// Index returns the position of the node currently being inspected in the slice holding it in its parent, or -1
+	// if it isn't held in a slice
+	Index() int
+	// PrevSibling returns the node before the node currently being inspected in the slice holding it, as the slice
+	// was before the walk changed it, or nil if it is the first or isn't held in a slice
+	PrevSibling() ast.Node
+	// NextSibling is PrevSibling for the node after the current one, or nil if it is the last
+	NextSibling() ast.Node
+	// FieldName returns the name of the field of the parent node holding the node currently being inspected, with
+	// its index if the field is a slice (eg. "Cond" or "List[2]"), or "" at the root of the walk. For a comment group
+	// from the comment map passed to WithCommentMap, it is CommentMapField with the group's index
+	FieldName() string
+	// ID returns the identifier assigned to the passed node when it was first visited, or 0 if it hasn't been visited
+	// or the Inspector wasn't constructed with WithNodeIDs
+	ID(n ast.Node) uint64
+	// SetData stores value under key for the node n, for the Visitor (or a later walk by the same Inspector) to look
+	// up with Data. The data for a node is dropped when the Inspector replaces or deletes it, so isn't kept alive by
+	// the Inspector; the data for the nodes beneath it is kept, since they may be held by its replacement
+	SetData(n ast.Node, key, value interface{})
+	// Data returns the value stored under key for the node n with SetData, and whether there is one
+	Data(n ast.Node, key interface{}) (interface{}, bool)
+	// Scope returns the innermost lexical scope enclosing the current node, or nil if the Inspector wasn't constructed
+	// with WithScopes
+	Scope() *Scope
+	// TypeOf returns the type of the passed expression, or nil if it is unknown or the Inspector was constructed
+	// without type information
+	TypeOf(expr ast.Expr) types.Type
 	// Inspect walks the AST for the node passed, calling the Visitor, and returning the modified tree
 	Inspect(node ast.Node) ast.Node
+	// Clone returns a new Inspector with the same Visitor and configuration, but none of the state of any walk, which
+	// may be used concurrently with this one
+	Clone() Inspector
 	// Visit calls the Visitor for the node, returning its replacement, and optionally an Inspector to be called for its
 	// children
 	Visit(node ast.Node) (replacement ast.Node, i Inspector)
 }
 
-// NewInspector constructs a new Inspector with the passed Visitor.
-func NewInspector(v Visitor) Inspector {
-	return &inspectorImpl{
+// defaultMaxRevisits is the number of times ReplaceAndRevisit may be called in a row for the same node before the
+// Inspector assumes the Visitor is stuck in a loop, unless WithMaxRevisits sets another limit.
+const defaultMaxRevisits = 10
+
+// An Option configures an Inspector when it is constructed.
+type Option func(*inspectorImpl)
+
+// NewInspector constructs a new Inspector with the passed Visitor and Options.
+func NewInspector(v Visitor, opts ...Option) Inspector {
+	i := &inspectorImpl{
+		walkState:   walkState{slot: rootSlot},
 		visitorImpl: v,
+		opts:        opts,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// OnReplace registers a function to be called each time the Inspector replaces a node, with the node being replaced
+// and its replacement, before the replacement is made. Functions are called in the order they were registered.
+func OnReplace(f func(old, new ast.Node)) Option {
+	return func(i *inspectorImpl) {
+		i.onReplace = append(i.onReplace, f)
+	}
+}
+
+// OnDeclaration registers a function to be called each time the Inspector visits an *ast.Ident which go/parser
+// resolved to a declaration in the same file, with the identifier and the node declaring it (such as an *ast.Field,
+// *ast.ValueSpec or *ast.FuncDecl), before the Visitor is called. It is called for the identifier in the declaration
+// too. The declaration isn't walked, so there are no cycles. Functions are called in the order they were registered.
+func OnDeclaration(f func(id *ast.Ident, decl ast.Node)) Option {
+	return func(i *inspectorImpl) {
+		i.onDeclaration = append(i.onDeclaration, f)
+	}
+}
+
+// declaration calls the OnDeclaration functions for n, if it is an identifier resolved to a declaration.
+func (i *inspectorImpl) declaration(n ast.Node) {
+	if len(i.onDeclaration) == 0 {
+		return
+	}
+	id, ok := n.(*ast.Ident)
+	if !ok || id.Obj == nil {
+		return
+	}
+	if decl, ok := id.Obj.Decl.(ast.Node); ok {
+		for _, f := range i.onDeclaration {
+			f(id, decl)
+		}
+	}
+}
+
+// WithReverseOrder makes the Inspector inspect the children of each node in reverse: from the last field of the node
+// to the first, and from the end of each slice to its start. Each node is still visited before its children and
+// finished with a nil visit after them. Index() still gives each node's position in its slice as it was before the
+// walk changed it.
+func WithReverseOrder() Option {
+	return func(i *inspectorImpl) {
+		i.reverse = true
+	}
+}
+
+// SkipBodies makes the Inspector skip the bodies of functions (and function literals), so that only declarations,
+// types and function signatures are visited. This makes walks much quicker for tools which are only interested in
+// the API of a package, such as those extracting its documentation.
+func SkipBodies() Option {
+	return func(i *inspectorImpl) {
+		i.skipBodies = true
+	}
+}
+
+// ExportedOnly restricts the walk to the exported API of each file: its exported top-level declarations, and their
+// signatures (the Inspector skips function bodies, as with SkipBodies). Imports are skipped, as are unexported
+// functions, types, variables and constants, methods of unexported types, and declarations of nothing exported.
+// Skipped declarations aren't visited, and are left as they are.
+func ExportedOnly() Option {
+	return func(i *inspectorImpl) {
+		i.exportedOnly, i.skipBodies = true, true
+	}
+}
+
+// skippingBodies reports whether ii was constructed with SkipBodies.
+func skippingBodies(ii Inspector) bool {
+	impl, ok := ii.(*inspectorImpl)
+	return ok && impl.skipBodies
+}
+
+// exportedDecl reports whether n, held in s, is part of the exported API of its file, as ExportedOnly walks it. Only
+// top-level declarations and their specs are ever excluded.
+func exportedDecl(s slot, n ast.Node) bool {
+	switch s.parent.(type) {
+	case *ast.File:
+		if s.field == "Imports" {
+			return false
+		}
+		if s.field != "Decls" {
+			return true
+		}
+	case *ast.GenDecl:
+	default:
+		return true
+	}
+
+	switch n := n.(type) {
+	case *ast.FuncDecl:
+		if n.Recv != nil && len(n.Recv.List) > 0 && !exportedType(n.Recv.List[0].Type) {
+			return false
+		}
+		return n.Name.IsExported()
+	case *ast.GenDecl:
+		if n.Tok == token.IMPORT {
+			return false
+		}
+		for _, spec := range n.Specs {
+			if exportedDecl(slot{parent: n}, spec) {
+				return true
+			}
+		}
+		return false
+	case *ast.TypeSpec:
+		return n.Name.IsExported()
+	case *ast.ValueSpec:
+		for _, name := range n.Names {
+			if name.IsExported() {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// exportedType reports whether the receiver type x names an exported type.
+func exportedType(x ast.Expr) bool {
+	for {
+		switch t := x.(type) {
+		case *ast.StarExpr:
+			x = t.X
+		case *ast.ParenExpr:
+			x = t.X
+		case *ast.IndexExpr:
+			x = t.X
+		case *ast.IndexListExpr:
+			x = t.X
+		case *ast.Ident:
+			return t.IsExported()
+		default:
+			return false
+		}
 	}
 }
 
+// WithMaxDepth limits the depth of the walk to n nodes below its root, for ASTs too deeply nested to walk safely (eg.
+// from generated or untrusted source). The walk stops at the first node beyond the limit, without visiting it, and
+// Err returns a *DepthError. A limit of 0 or less is no limit, which is the default.
+func WithMaxDepth(n int) Option {
+	return func(i *inspectorImpl) {
+		i.maxDepth = n
+	}
+}
+
+// A DepthError ends a walk which goes deeper than the limit set by WithMaxDepth. Node is the first node beyond the
+// limit, which wasn't visited.
+type DepthError struct {
+	MaxDepth int
+	Node     ast.Node
+}
+
+func (e *DepthError) Error() string {
+	return fmt.Sprintf("astor: %T is more than %d nodes deep", e.Node, e.MaxDepth)
+}
+
+// WithMaxNodes limits the walk to visiting n nodes, for ASTs too large to walk in reasonable time (eg. from untrusted
+// source): a wide, shallow AST can be enormous without being deep. The walk stops at the first node beyond the limit,
+// without visiting it, and Err returns a *NodeLimitError. The AST is returned with the changes made up to then. A
+// limit of 0 or less is no limit, which is the default.
+func WithMaxNodes(n int) Option {
+	return func(i *inspectorImpl) {
+		i.maxNodes = n
+	}
+}
+
+// A NodeLimitError ends a walk which visits more nodes than the limit set by WithMaxNodes. Node is the first node
+// beyond the limit, which wasn't visited.
+type NodeLimitError struct {
+	MaxNodes int
+	Node     ast.Node
+}
+
+func (e *NodeLimitError) Error() string {
+	return fmt.Sprintf("astor: walk stopped at %T after visiting %d nodes", e.Node, e.MaxNodes)
+}
+
+// WithMaxRevisits limits the number of times ReplaceAndRevisit may be called in a row for the same node, which is 10
+// by default (as it is for a limit of 0 or less). A Visitor which replaces a node with one which matches its own
+// condition for replacement would otherwise loop forever. The walk stops at the call beyond the limit, keeping the
+// replacement it was passed, and Err returns a *RevisitError.
+func WithMaxRevisits(n int) Option {
+	return func(i *inspectorImpl) {
+		i.maxRevisits = n
+	}
+}
+
+// A RevisitError ends a walk in which ReplaceAndRevisit was called more times in a row for the same node than the
+// limit set by WithMaxRevisits, which suggests the Visitor's replacements form a cycle. Node is the node originally
+// visited, and Replacement the last replacement it was given, which is kept.
+type RevisitError struct {
+	MaxRevisits int
+	Node        ast.Node
+	Replacement ast.Node
+}
+
+func (e *RevisitError) Error() string {
+	return fmt.Sprintf("astor: possible rewrite cycle at %T (position %d): ReplaceAndRevisit called %d times in a row",
+		e.Node, e.Node.Pos(), e.MaxRevisits)
+}
+
+// overLimit reports whether visiting original would exceed the limits set by WithMaxDepth or WithMaxNodes, in which
+// case it stops the walk with an error. Otherwise it counts original as visited.
+func (i *inspectorImpl) overLimit(original ast.Node) bool {
+	switch {
+	case i.maxDepth > 0 && i.depth > i.maxDepth:
+		i.stopped, i.err = true, &DepthError{MaxDepth: i.maxDepth, Node: original}
+		return true
+	case i.maxNodes > 0 && i.visited >= i.maxNodes:
+		i.stopped, i.err = true, &NodeLimitError{MaxNodes: i.maxNodes, Node: original}
+		return true
+	}
+	i.visited++
+	return false
+}
+
+// NewInspectorWithTypes constructs a new Inspector with the passed Visitor and Options, which can look up the types of
+// expressions in info (as populated by a go/types Config.Check of the AST being inspected).
+func NewInspectorWithTypes(v Visitor, info *types.Info, opts ...Option) Inspector {
+	i := NewInspector(v, opts...).(*inspectorImpl)
+	i.info = info
+	return i
+}
+
 type inspectorImpl struct {
-	mtx         sync.Mutex
-	node        ast.Node
-	visitorImpl Visitor
+	mtx sync.Mutex
+	walkState
+	visitorImpl   Visitor
+	opts          []Option
+	info          *types.Info
+	dryRun        bool
+	edits         []Edit
+	data          map[ast.Node]map[interface{}]interface{}
+	ids           map[ast.Node]uint64
+	cmap          ast.CommentMap
+	onReplace     []func(old, new ast.Node)
+	onDeclaration []func(id *ast.Ident, decl ast.Node)
+	onBroken      []func(directive *ast.Comment, node ast.Node)
+	scopes        bool
+	reverse       bool
+	skipBodies    bool
+	exportedOnly  bool
+	visitLists    bool
+	identsOnly    bool
+	maxDepth      int
+	maxNodes      int
+	maxRevisits   int
+	collecting    *[]childRef
+	skipShared    bool
+	onShared      []func(node ast.Node)
+	recover       bool
+	onPanic       []func(node ast.Node, recovered interface{}) bool
+	testFiles     bool
+	onEnterList   []ListHook
+	onExitList    []ListHook
+	readOnly      bool
+	testFuncsOnly bool
+	skipGenerated bool
+	// visiting and changing guard against calls from goroutines other than the one running the Visitor
+	visiting atomic.Bool
+	changing atomic.Bool
+}
+
+// walkState is the state of an inspectorImpl's walk, which a walk nested in it has its own of.
+type walkState struct {
+	node     ast.Node
+	original ast.Node
+	revisit  bool
+	skip     bool
+	slot     slot
+	edit     listEdit
+	wrapped  map[ast.Node]bool
+	// unvisited are the nodes added by the Visitor (such as with AppendStmts) which aren't to be inspected
+	unvisited map[ast.Node]bool
+	// seen are the nodes visited, if shared nodes are being looked for
+	seen       map[ast.Node]bool
+	directives map[ast.Node][]*ast.Comment
+	scope      *Scope
+	dirty      bool
+	fileLists  map[ast.Node]*fileListNode
+	stopped    bool
+	err        error
+	depth      int
+	visited    int
+	// skipFields are the fields of the current node SkipField was called with, and skipping those of the node whose
+	// children are being inspected
+	skipFields []string
+	skipping   []string
+	// ancestors are the nodes whose children are being inspected, from the root of the walk to the current node's
+	// parent
+	ancestors []ast.Node
+	// finishing is whether the terminating nil visit of a node is being made, and finished is that node, or its
+	// replacement
+	finishing bool
+	finished  ast.Node
+	// packageFiles are the names of the files to visit of the packages walked by InspectPackage and
+	// InspectPackageForContext, in order
+	packageFiles map[*ast.Package][]string
 }
 
 func (i *inspectorImpl) Current() ast.Node {
 	return i.node
 }
 
+func (i *inspectorImpl) Original() ast.Node {
+	return i.original
+}
+
 func (i *inspectorImpl) Replace(n ast.Node) {
-	i.node = n
+	i.enterChange("Replace")
+	defer i.exit()
+	i.slot.check(n)
+	i.checkReplaceDirectives(n)
+	if i.dryRun {
+		i.record(EditReplace, n)
+		return
+	}
+	i.replace(n)
+}
+
+func (i *inspectorImpl) ReplaceAndRevisit(n ast.Node) {
+	i.enterChange("ReplaceAndRevisit")
+	defer i.exit()
+	i.slot.check(n)
+	i.checkReplaceDirectives(n)
+	if i.dryRun {
+		// the replacement isn't applied, so there's nothing to revisit
+		i.record(EditReplace, n)
+		return
+	}
+	i.replace(n)
+	i.revisit = true
+}
+
+func (i *inspectorImpl) replace(n ast.Node) {
+	old := i.node
+	if i.finishing {
+		old = i.finished
+	}
+	for _, f := range i.onReplace {
+		f(old, n)
+	}
+	if old != n {
+		delete(i.data, old)
+	}
+	if i.finishing {
+		i.finished = n
+	} else {
+		i.node = n
+	}
+	i.edit.deleted = false
+	i.dirty = true
+}
+
+func (i *inspectorImpl) WrapExpr(f func(ast.Expr) ast.Expr) {
+	if n := i.wrappable("WrapExpr"); n != nil {
+		x, ok := n.(ast.Expr)
+		if !ok {
+			panic(fmt.Sprintf("astor: WrapExpr called for %T, which isn't an ast.Expr", n))
+		}
+		i.wrap(n, f(x))
+	}
+}
+
+func (i *inspectorImpl) WrapStmt(f func(ast.Stmt) ast.Stmt) {
+	if n := i.wrappable("WrapStmt"); n != nil {
+		stmt, ok := n.(ast.Stmt)
+		if !ok {
+			panic(fmt.Sprintf("astor: WrapStmt called for %T, which isn't an ast.Stmt", n))
+		}
+		i.wrap(n, f(stmt))
+	}
+}
+
+// wrappable returns the current node, or nil if it has already been wrapped, or is a wrapper.
+func (i *inspectorImpl) wrappable(method string) ast.Node {
+	i.enterChange(method)
+	defer i.exit()
+	if i.wrapped[i.node] {
+		return nil
+	}
+	return i.node
+}
+
+func (i *inspectorImpl) wrap(n, wrapper ast.Node) {
+	i.Replace(wrapper)
+	if i.wrapped == nil {
+		i.wrapped = make(map[ast.Node]bool)
+	}
+	i.wrapped[n], i.wrapped[wrapper] = true, true
+}
+
+func (i *inspectorImpl) SkipChildren() {
+	i.enter("SkipChildren")
+	defer i.exit()
+	i.skip = true
+}
+
+func (i *inspectorImpl) SkipField(name string) {
+	i.enter("SkipField")
+	defer i.exit()
+	if v := reflect.ValueOf(i.node); v.Kind() != reflect.Ptr || !v.Elem().FieldByName(name).IsValid() {
+		panic(fmt.Sprintf("astor: SkipField called with %q, which isn't a field of %T", name, i.node))
+	}
+	i.skipFields = append(i.skipFields, name)
+}
+
+// skippingField reports whether ii is to skip the children held in the named field of the node whose children it is
+// inspecting.
+func skippingField(ii Inspector, field string) bool {
+	impl, ok := ii.(*inspectorImpl)
+	if !ok {
+		return false
+	}
+	for _, f := range impl.skipping {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *inspectorImpl) Stop() {
+	i.enter("Stop")
+	defer i.exit()
+	i.stopped = true
+}
+
+func (i *inspectorImpl) Err() error {
+	return i.err
+}
+
+func (i *inspectorImpl) Delete() {
+	i.enterChange("Delete")
+	defer i.exit()
+	if i.slot.index < 0 && i.slot.optional {
+		i.breakDirectives(i.original)
+		if i.dryRun {
+			i.record(EditDelete, nil)
+			return
+		}
+		i.replace(nil)
+		return
+	}
+	i.checkInList("Delete")
+	i.breakDirectives(i.original)
+	if i.dryRun {
+		i.record(EditDelete, nil)
+		return
+	}
+	i.edit.deleted = true
+	i.dirty = true
+	delete(i.data, i.node)
+}
+
+func (i *inspectorImpl) InsertBefore(n ast.Node) {
+	i.enterChange("InsertBefore")
+	defer i.exit()
+	i.checkInList("InsertBefore")
+	i.slot.check(n)
+	i.breakDirectives(i.original)
+	if i.dryRun {
+		i.record(EditInsertBefore, n)
+		return
+	}
+	i.edit.before = append(i.edit.before, n)
+	i.dirty = true
+}
+
+func (i *inspectorImpl) InsertAfter(n ast.Node) {
+	i.enterChange("InsertAfter")
+	defer i.exit()
+	i.checkInList("InsertAfter")
+	i.slot.check(n)
+	if i.directives != nil {
+		i.breakDirectives(i.NextSibling())
+	}
+	if i.dryRun {
+		i.record(EditInsertAfter, n)
+		return
+	}
+	// nodes inserted after the current one are kept in the order they were inserted
+	i.edit.after = append(i.edit.after, n)
+	i.dirty = true
+}
+
+// enter panics unless a visit is in progress and no other call to a method changing the AST is. Each call must be
+// paired with a call to exit once the method is done.
+func (i *inspectorImpl) enter(method string) {
+	if !i.visiting.Load() {
+		panic(fmt.Sprintf("astor: %s called while no node is being visited", method))
+	}
+	if !i.changing.CompareAndSwap(false, true) {
+		panic(fmt.Sprintf("astor: %s called concurrently with another change to the AST", method))
+	}
+}
+
+// enterChange is enter for the methods which change the AST, which also panics if the Inspector is read-only.
+func (i *inspectorImpl) enterChange(method string) {
+	if i.readOnly {
+		panic(fmt.Sprintf("astor: %s called on a read-only Inspector", method))
+	}
+	i.enter(method)
+}
+
+func (i *inspectorImpl) exit() {
+	i.changing.Store(false)
+}
+
+func (i *inspectorImpl) checkInList(method string) {
+	if i.slot.index < 0 {
+		panic(fmt.Sprintf("astor: %s called for %s, which isn't held in a slice", method, i.slot))
+	}
+}
+
+func (i *inspectorImpl) Dirty() bool {
+	return i.dirty
+}
+
+func (i *inspectorImpl) Parent() ast.Node {
+	return i.slot.parent
+}
+
+func (i *inspectorImpl) EnclosingFunc() ast.Node {
+	for l := len(i.ancestors) - 1; l >= 0; l-- {
+		switch n := i.ancestors[l].(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			return n
+		}
+	}
+	return nil
+}
+
+func (i *inspectorImpl) Index() int {
+	return i.slot.index
+}
+
+func (i *inspectorImpl) PrevSibling() ast.Node {
+	return i.sibling(-1)
+}
+
+func (i *inspectorImpl) NextSibling() ast.Node {
+	return i.sibling(1)
+}
+
+// sibling returns the node offset places from the current one in the slice holding it, or nil if there isn't one.
+func (i *inspectorImpl) sibling(offset int) ast.Node {
+	if i.slot.parent == nil || i.slot.index < 0 {
+		return nil
+	}
+	l := i.slot.index + offset
+	if i.slot.comments != nil {
+		if l < 0 || l >= len(i.slot.comments) {
+			return nil
+		}
+		return i.slot.comments[l]
+	}
+	list := reflect.ValueOf(i.slot.parent).Elem().FieldByName(i.slot.field)
+	if l < 0 || l >= list.Len() {
+		return nil
+	}
+	n, _ := list.Index(l).Interface().(ast.Node)
+	return n
+}
+
+func (i *inspectorImpl) FieldName() string {
+	if i.slot.parent == nil {
+		return ""
+	}
+	if i.slot.index >= 0 {
+		return fmt.Sprintf("%s[%d]", i.slot.field, i.slot.index)
+	}
+	return i.slot.field
+}
+
+func (i *inspectorImpl) SetData(n ast.Node, key, value interface{}) {
+	if i.data == nil {
+		i.data = make(map[ast.Node]map[interface{}]interface{})
+	}
+	if i.data[n] == nil {
+		i.data[n] = make(map[interface{}]interface{})
+	}
+	i.data[n][key] = value
+}
+
+func (i *inspectorImpl) Data(n ast.Node, key interface{}) (interface{}, bool) {
+	value, ok := i.data[n][key]
+	return value, ok
+}
+
+func (i *inspectorImpl) TypeOf(expr ast.Expr) types.Type {
+	if i.info == nil {
+		return nil
+	}
+	return i.info.TypeOf(expr)
+}
+
+func (i *inspectorImpl) Clone() Inspector {
+	// reapplying the options gives the clone its own copy of any state they set up
+	c := NewInspector(i.visitorImpl, i.opts...).(*inspectorImpl)
+	c.info = i.info
+	return c
+}
+
+// reset clears the state of i's walks, leaving it as it was when it was constructed, but without a Visitor. The space
+// for the ancestors is kept, so a reused Inspector doesn't allocate it again, but not the nodes it held.
+func (i *inspectorImpl) reset() {
+	ancestors := i.ancestors[:cap(i.ancestors)]
+	for l := range ancestors {
+		ancestors[l] = nil
+	}
+	*i = inspectorImpl{walkState: walkState{slot: rootSlot, ancestors: ancestors[:0]}, opts: i.opts, info: i.info}
+	for _, opt := range i.opts {
+		opt(i)
+	}
 }
 
 func (i *inspectorImpl) Visit(n ast.Node) (ast.Node, Inspector) {
 	i.mtx.Lock()
+	i.assignID(n)
 	i.node = n
-	result := i.visitorImpl(i, n)
+	if n != nil {
+		i.original = n
+		i.declaration(n)
+		i.findDirectives(n)
+		i.findFileLists(n)
+	}
+	i.visiting.Store(true)
+	result := i.callVisitor(n)
+	maxRevisits := i.maxRevisits
+	if maxRevisits <= 0 {
+		maxRevisits = defaultMaxRevisits
+	}
+	// the terminating nil visit has no replacement to revisit
+	for revisits := 0; i.revisit && n != nil && i.node != nil; revisits++ {
+		if revisits == maxRevisits {
+			i.stopped, i.err = true, &RevisitError{MaxRevisits: maxRevisits, Node: n, Replacement: i.node}
+			break
+		}
+		i.revisit, i.skip, i.skipFields = false, false, nil
+		i.assignID(i.node)
+		result = i.callVisitor(i.node)
+	}
+	i.visiting.Store(false)
+	i.revisit = false
 	replacement := i.node
 	i.node = nil
 	i.mtx.Unlock()
@@ -61,9 +803,218 @@ func (i *inspectorImpl) Visit(n ast.Node) (ast.Node, Inspector) {
 }
 
 func (i *inspectorImpl) Inspect(node ast.Node) ast.Node {
-	var ii Inspector
-	if node, ii = i.Visit(node); ii == nil {
-		return node
+	defer startWalk(i)()
+	node, _ = i.inspect(node)
+	return node
+}
+
+// InspectExpr walks the AST rooted at the expression e like Inspect, returning the modified expression. The Visitor
+// may only replace e with another expression: Replace panics otherwise.
+func InspectExpr(i Inspector, e ast.Expr) ast.Expr {
+	return inspectRoot(i, e)
+}
+
+// InspectStmt walks the AST rooted at the statement s like Inspect, returning the modified statement. The Visitor may
+// only replace s with another statement: Replace panics otherwise.
+func InspectStmt(i Inspector, s ast.Stmt) ast.Stmt {
+	return inspectRoot(i, s)
+}
+
+// InspectDecl walks the AST rooted at the declaration d like Inspect, returning the modified declaration. The Visitor
+// may only replace d with another declaration: Replace panics otherwise.
+func InspectDecl(i Inspector, d ast.Decl) ast.Decl {
+	return inspectRoot(i, d)
+}
+
+// InspectFileNode walks the AST rooted at the file f like Inspect, returning the modified file. The Visitor may
+// replace f with another file, which is returned, but with nothing else: Replace panics otherwise.
+func InspectFileNode(i Inspector, f *ast.File) *ast.File {
+	return inspectRoot(i, f)
+}
+
+// inspectRoot walks the AST rooted at node like Inspect, with node held in a root slot of type T.
+func inspectRoot[T ast.Node](i Inspector, node T) T {
+	s := slot{index: -1, typ: slotType[T]()}
+	impl, ok := i.(*inspectorImpl)
+	if !ok {
+		return slotNode[T](s, i.Inspect(node), listEdit{})
+	}
+
+	defer startWalk(i)()
+	outer := impl.slot
+	impl.slot = s
+	defer func() {
+		impl.slot = outer
+	}()
+	n, _ := impl.inspect(node)
+	return slotNode[T](s, n, listEdit{})
+}
+
+// Visiting reports whether i is visiting a node, so a walk started with it now (such as by its Visitor calling
+// Inspect) would be nested in the walk visiting the node. A nested walk has its own state: the Visitor sees the nodes
+// of the nested walk, and their Parent and so on, until it's done, when the Inspector carries on with the node it was
+// visiting. Err reports the errors of the nested walk until it's done, and then those of the outer one again.
+//
+// It is always false for an Inspector not constructed by this package.
+func Visiting(i Inspector) bool {
+	impl, ok := i.(*inspectorImpl)
+	return ok && impl.visiting.Load()
+}
+
+// startWalk readies i for a new walk, which a previous walk may have stopped, and returns a function to be called once
+// the walk is done. A walk started by the Visitor, while i is visiting a node of another walk, is nested in that walk:
+// it has its own state, and the outer walk's state is restored when it's done.
+func startWalk(i Inspector) func() {
+	impl, ok := i.(*inspectorImpl)
+	if !ok {
+		return func() {}
+	}
+
+	done := func() {}
+	if impl.visiting.Load() {
+		outer := impl.walkState
+		impl.walkState = walkState{slot: rootSlot}
+		// the Visitor is being called by Visit, which holds the lock the nested walk's visits need
+		impl.visiting.Store(false)
+		impl.mtx.Unlock()
+		done = func() {
+			impl.mtx.Lock()
+			impl.visiting.Store(true)
+			impl.walkState = outer
+		}
+	}
+	impl.stopped, impl.err, impl.depth, impl.visited, impl.ancestors = false, nil, 0, 0, impl.ancestors[:0]
+	impl.wrapped, impl.unvisited, impl.seen, impl.directives, impl.fileLists = nil, nil, nil, nil, nil
+	impl.packageFiles = nil
+	return done
+}
+
+// inspect is Inspect, but also returns the edits the Visitor made to the slice holding node.
+func (i *inspectorImpl) inspect(original ast.Node) (ast.Node, listEdit) {
+	if i.fileLists == nil {
+		return i.inspectNode(original)
+	}
+	if r, ok := i.fileLists[original]; ok && r.visited {
+		return r.in(i.slot)
+	}
+	node, edit := i.inspectNode(original)
+	i.visitedFileList(original, node, edit)
+	return node, edit
+}
+
+// excluded reports whether original, held in i.slot, is left out of the walk by ExportedOnly, TestFuncsOnly or
+// SkipGenerated.
+func (i *inspectorImpl) excluded(original ast.Node) bool {
+	switch {
+	case i.exportedOnly && !exportedDecl(i.slot, original):
+		return true
+	case i.testFuncsOnly && !testFuncDecl(i.slot, original):
+		return true
+	case i.skipGenerated:
+		f, ok := original.(*ast.File)
+		return ok && IsGenerated(f)
+	}
+	return false
+}
+
+func (i *inspectorImpl) inspectNode(original ast.Node) (ast.Node, listEdit) {
+	if i.stopped {
+		return original, listEdit{}
+	}
+	if original == nil {
+		// a missing child (in an incomplete or invalid AST), which would be mistaken for the terminating visit
+		return nil, listEdit{}
+	}
+	if i.unvisited[original] || i.shared(original) {
+		return original, listEdit{}
+	}
+	if i.excluded(original) {
+		return original, listEdit{}
+	}
+	if i.overLimit(original) {
+		return original, listEdit{}
+	}
+	if _, ok := original.(*ast.Ident); i.identsOnly && !ok {
+		// InspectIdents walks straight through everything else
+		inspectChildren(i, original)
+		return original, listEdit{}
+	}
+
+	// a change to node or its subtree is also a change to the subtree of each of its ancestors
+	outerDirty := i.dirty
+	i.edit, i.skip, i.skipFields, i.dirty = listEdit{}, false, nil, false
+	node, ii := i.Visit(original)
+	edit, skip, skipFields := i.edit, i.skip, i.skipFields
+	if i.cmap != nil && node != original {
+		i.moveComments(original, node)
+	}
+	if ii == nil || edit.deleted || i.stopped || node == nil {
+		if i.scopes && !edit.deleted && node != nil {
+			i.declare(node)
+		}
+		i.dirty = outerDirty || i.dirty
+		return node, edit
+	}
+
+	// inspecting the children overwrites the slot and edits, which should be those of node again for the terminating
+	// visit
+	s := i.slot
+	if !skip {
+		opened := i.scopes && i.openScope(node)
+		// in reverse, comments mirror their usual place after the children
+		if i.cmap != nil && i.reverse {
+			i.inspectComments(ii, node)
+		}
+		outerSkipping := i.skipping
+		i.depth, i.skipping = i.depth+1, skipFields
+		i.ancestors = append(i.ancestors, node)
+		inspectChildren(ii, node)
+		i.ancestors = i.ancestors[:len(i.ancestors)-1]
+		i.depth, i.skipping = i.depth-1, outerSkipping
+		if i.cmap != nil && !i.reverse {
+			i.inspectComments(ii, node)
+		}
+		if opened {
+			i.scope = i.scope.Outer
+		}
+	}
+	i.slot, i.edit, i.original = s, edit, original
+	if i.stopped {
+		i.dirty = outerDirty || i.dirty
+		return node, i.edit
+	}
+	if r := i.finish(ii, node); r != node {
+		if i.cmap != nil {
+			i.moveComments(node, r)
+		}
+		node = r
+	}
+	if i.scopes && node != nil {
+		i.declare(node)
+	}
+	i.dirty = outerDirty || i.dirty
+	return node, i.edit
+}
+
+// finish makes the terminating nil visit of node with ii, returning node, or its replacement if the Visitor replaced
+// it during the visit.
+func (i *inspectorImpl) finish(ii Inspector, node ast.Node) ast.Node {
+	finishing, finished := i.finishing, i.finished
+	i.finishing, i.finished = true, node
+	ii.Visit(nil)
+	node = i.finished
+	i.finishing, i.finished = finishing, finished
+	return node
+}
+
+// inspectChildren inspects each of the children of node with ii, storing their replacements back in node.
+func inspectChildren(ii Inspector, node ast.Node) {
+	if impl, ok := ii.(*inspectorImpl); ok && impl.reverse && impl.collecting == nil {
+		refs := impl.children(node)
+		for l := len(refs) - 1; l >= 0; l-- {
+			refs[l].inspect(ii)
+		}
+		return
 	}
 
 	// inspect children
@@ -75,27 +1026,23 @@ func (i *inspectorImpl) Inspect(node ast.Node) ast.Node {
 		// nothing to do
 
 	case *ast.CommentGroup:
-		for l, c := range n.List {
-			n.List[l] = ii.Inspect(c).(*ast.Comment)
-		}
+		inspectList(ii, n, "List", &n.List)
 
 	case *ast.Field:
 		if n.Doc != nil {
-			n.Doc = ii.Inspect(n.Doc).(*ast.CommentGroup)
+			inspectOptional(ii, n, "Doc", &n.Doc)
 		}
-		n.Names = inspectIdentList(ii, n.Names)
-		n.Type = ii.Inspect(n.Type).(ast.Expr)
+		inspectList(ii, n, "Names", &n.Names)
+		inspectChild(ii, n, "Type", &n.Type)
 		if n.Tag != nil {
-			n.Tag = ii.Inspect(n.Tag).(*ast.BasicLit)
+			inspectOptional(ii, n, "Tag", &n.Tag)
 		}
 		if n.Comment != nil {
-			n.Comment = ii.Inspect(n.Comment).(*ast.CommentGroup)
+			inspectOptional(ii, n, "Comment", &n.Comment)
 		}
 
 	case *ast.FieldList:
-		for l, f := range n.List {
-			n.List[l] = ii.Inspect(f).(*ast.Field)
-		}
+		inspectList(ii, n, "List", &n.List)
 
 	// Expressions
 	case *ast.BadExpr, *ast.Ident, *ast.BasicLit:
@@ -103,234 +1050,246 @@ func (i *inspectorImpl) Inspect(node ast.Node) ast.Node {
 
 	case *ast.Ellipsis:
 		if n.Elt != nil {
-			n.Elt = ii.Inspect(n.Elt).(ast.Expr)
+			inspectOptional(ii, n, "Elt", &n.Elt)
 		}
 
 	case *ast.FuncLit:
-		n.Type = ii.Inspect(n.Type).(*ast.FuncType)
-		n.Body = ii.Inspect(n.Body).(*ast.BlockStmt)
+		inspectChild(ii, n, "Type", &n.Type)
+		if !skippingBodies(ii) {
+			inspectChild(ii, n, "Body", &n.Body)
+		}
 
 	case *ast.CompositeLit:
 		if n.Type != nil {
-			n.Type = ii.Inspect(n.Type).(ast.Expr)
+			inspectOptional(ii, n, "Type", &n.Type)
 		}
-		n.Elts = inspectExprList(ii, n.Elts)
+		inspectList(ii, n, "Elts", &n.Elts)
 
 	case *ast.ParenExpr:
-		n.X = ii.Inspect(n.X).(ast.Expr)
+		inspectChild(ii, n, "X", &n.X)
 
 	case *ast.SelectorExpr:
-		n.X = ii.Inspect(n.X).(ast.Expr)
-		n.Sel = ii.Inspect(n.Sel).(*ast.Ident)
+		inspectChild(ii, n, "X", &n.X)
+		inspectChild(ii, n, "Sel", &n.Sel)
 
 	case *ast.IndexExpr:
-		n.X = ii.Inspect(n.X).(ast.Expr)
-		n.Index = ii.Inspect(n.Index).(ast.Expr)
+		inspectChild(ii, n, "X", &n.X)
+		inspectChild(ii, n, "Index", &n.Index)
+
+	case *ast.IndexListExpr:
+		inspectChild(ii, n, "X", &n.X)
+		inspectList(ii, n, "Indices", &n.Indices)
 
 	case *ast.SliceExpr:
-		n.X = ii.Inspect(n.X).(ast.Expr)
+		inspectChild(ii, n, "X", &n.X)
 		if n.Low != nil {
-			n.Low = ii.Inspect(n.Low).(ast.Expr)
+			inspectOptional(ii, n, "Low", &n.Low)
 		}
 		if n.High != nil {
-			n.High = ii.Inspect(n.High).(ast.Expr)
+			inspectOptional(ii, n, "High", &n.High)
 		}
 		if n.Max != nil {
-			n.Max = ii.Inspect(n.Max).(ast.Expr)
+			inspectOptional(ii, n, "Max", &n.Max)
 		}
 
 	case *ast.TypeAssertExpr:
-		n.X = ii.Inspect(n.X).(ast.Expr)
+		inspectChild(ii, n, "X", &n.X)
 		if n.Type != nil {
-			n.Type = ii.Inspect(n.Type).(ast.Expr)
+			inspectOptional(ii, n, "Type", &n.Type)
 		}
 
 	case *ast.CallExpr:
-		n.Fun = ii.Inspect(n.Fun).(ast.Expr)
-		n.Args = inspectExprList(ii, n.Args)
+		inspectChild(ii, n, "Fun", &n.Fun)
+		inspectList(ii, n, "Args", &n.Args)
 
 	case *ast.StarExpr:
-		n.X = ii.Inspect(n.X).(ast.Expr)
+		inspectChild(ii, n, "X", &n.X)
 
 	case *ast.UnaryExpr:
-		n.X = ii.Inspect(n.X).(ast.Expr)
+		inspectChild(ii, n, "X", &n.X)
 
 	case *ast.BinaryExpr:
-		n.X = ii.Inspect(n.X).(ast.Expr)
-		n.Y = ii.Inspect(n.Y).(ast.Expr)
+		inspectChild(ii, n, "X", &n.X)
+		inspectChild(ii, n, "Y", &n.Y)
 
 	case *ast.KeyValueExpr:
-		n.Key = ii.Inspect(n.Key).(ast.Expr)
-		n.Value = ii.Inspect(n.Value).(ast.Expr)
+		inspectChild(ii, n, "Key", &n.Key)
+		inspectChild(ii, n, "Value", &n.Value)
 
 	// Types
 	case *ast.ArrayType:
 		if n.Len != nil {
-			n.Len = ii.Inspect(n.Len).(ast.Expr)
+			inspectOptional(ii, n, "Len", &n.Len)
 		}
-		n.Elt = ii.Inspect(n.Elt).(ast.Expr)
+		inspectChild(ii, n, "Elt", &n.Elt)
 
 	case *ast.StructType:
-		n.Fields = ii.Inspect(n.Fields).(*ast.FieldList)
+		inspectChild(ii, n, "Fields", &n.Fields)
 
 	case *ast.FuncType:
+		if n.TypeParams != nil {
+			inspectOptional(ii, n, "TypeParams", &n.TypeParams)
+		}
 		if n.Params != nil {
-			n.Params = ii.Inspect(n.Params).(*ast.FieldList)
+			inspectOptional(ii, n, "Params", &n.Params)
 		}
 		if n.Results != nil {
-			n.Results = ii.Inspect(n.Results).(*ast.FieldList)
+			inspectOptional(ii, n, "Results", &n.Results)
 		}
 
 	case *ast.InterfaceType:
-		n.Methods = ii.Inspect(n.Methods).(*ast.FieldList)
+		inspectChild(ii, n, "Methods", &n.Methods)
 
 	case *ast.MapType:
-		n.Key = ii.Inspect(n.Key).(ast.Expr)
-		n.Value = ii.Inspect(n.Value).(ast.Expr)
+		inspectChild(ii, n, "Key", &n.Key)
+		inspectChild(ii, n, "Value", &n.Value)
 
 	case *ast.ChanType:
-		n.Value = ii.Inspect(n.Value).(ast.Expr)
+		inspectChild(ii, n, "Value", &n.Value)
 
 	// Statements
 	case *ast.BadStmt:
 		// nothing to do
 
 	case *ast.DeclStmt:
-		n.Decl = ii.Inspect(n.Decl).(ast.Decl)
+		inspectChild(ii, n, "Decl", &n.Decl)
 
 	case *ast.EmptyStmt:
 		// nothing to do
 
 	case *ast.LabeledStmt:
-		n.Label = ii.Inspect(n.Label).(*ast.Ident)
-		n.Stmt = ii.Inspect(n.Stmt).(ast.Stmt)
+		inspectChild(ii, n, "Label", &n.Label)
+		inspectChild(ii, n, "Stmt", &n.Stmt)
 
 	case *ast.ExprStmt:
-		n.X = ii.Inspect(n.X).(ast.Expr)
+		inspectChild(ii, n, "X", &n.X)
 
 	case *ast.SendStmt:
-		n.Chan = ii.Inspect(n.Chan).(ast.Expr)
-		n.Value = ii.Inspect(n.Value).(ast.Expr)
+		inspectChild(ii, n, "Chan", &n.Chan)
+		inspectChild(ii, n, "Value", &n.Value)
 
 	case *ast.IncDecStmt:
-		n.X = ii.Inspect(n.X).(ast.Expr)
+		inspectChild(ii, n, "X", &n.X)
 
 	case *ast.AssignStmt:
-		n.Lhs = inspectExprList(ii, n.Lhs)
-		n.Rhs = inspectExprList(ii, n.Rhs)
+		inspectList(ii, n, "Lhs", &n.Lhs)
+		inspectList(ii, n, "Rhs", &n.Rhs)
 
 	case *ast.GoStmt:
-		n.Call = ii.Inspect(n.Call).(*ast.CallExpr)
+		inspectChild(ii, n, "Call", &n.Call)
 
 	case *ast.DeferStmt:
-		n.Call = ii.Inspect(n.Call).(*ast.CallExpr)
+		inspectChild(ii, n, "Call", &n.Call)
 
 	case *ast.ReturnStmt:
-		n.Results = inspectExprList(ii, n.Results)
+		inspectList(ii, n, "Results", &n.Results)
 
 	case *ast.BranchStmt:
 		if n.Label != nil {
-			n.Label = ii.Inspect(n.Label).(*ast.Ident)
+			inspectOptional(ii, n, "Label", &n.Label)
 		}
 
 	case *ast.BlockStmt:
-		n.List = inspectStmtList(ii, n.List)
+		inspectList(ii, n, "List", &n.List)
 
 	case *ast.IfStmt:
 		if n.Init != nil {
-			n.Init = ii.Inspect(n.Init).(ast.Stmt)
+			inspectOptional(ii, n, "Init", &n.Init)
 		}
-		n.Cond = ii.Inspect(n.Cond).(ast.Expr)
-		n.Body = ii.Inspect(n.Body).(*ast.BlockStmt)
+		inspectChild(ii, n, "Cond", &n.Cond)
+		inspectChild(ii, n, "Body", &n.Body)
 		if n.Else != nil {
-			n.Else = ii.Inspect(n.Else).(ast.Stmt)
+			inspectOptional(ii, n, "Else", &n.Else)
 		}
 
 	case *ast.CaseClause:
-		n.List = inspectExprList(ii, n.List)
-		n.Body = inspectStmtList(ii, n.Body)
+		inspectList(ii, n, "List", &n.List)
+		inspectList(ii, n, "Body", &n.Body)
 
 	case *ast.SwitchStmt:
 		if n.Init != nil {
-			n.Init = ii.Inspect(n.Init).(ast.Stmt)
+			inspectOptional(ii, n, "Init", &n.Init)
 		}
 		if n.Tag != nil {
-			n.Tag = ii.Inspect(n.Tag).(ast.Expr)
+			inspectOptional(ii, n, "Tag", &n.Tag)
 		}
-		n.Body = ii.Inspect(n.Body).(*ast.BlockStmt)
+		inspectChild(ii, n, "Body", &n.Body)
 
 	case *ast.TypeSwitchStmt:
 		if n.Init != nil {
-			n.Init = ii.Inspect(n.Init).(ast.Stmt)
+			inspectOptional(ii, n, "Init", &n.Init)
 		}
-		n.Assign = ii.Inspect(n.Assign).(ast.Stmt)
-		n.Body = ii.Inspect(n.Body).(*ast.BlockStmt)
+		inspectChild(ii, n, "Assign", &n.Assign)
+		inspectChild(ii, n, "Body", &n.Body)
 
 	case *ast.CommClause:
 		if n.Comm != nil {
-			n.Comm = ii.Inspect(n.Comm).(ast.Stmt)
+			inspectOptional(ii, n, "Comm", &n.Comm)
 		}
-		n.Body = inspectStmtList(ii, n.Body)
+		inspectList(ii, n, "Body", &n.Body)
 
 	case *ast.SelectStmt:
-		n.Body = ii.Inspect(n.Body).(*ast.BlockStmt)
+		inspectChild(ii, n, "Body", &n.Body)
 
 	case *ast.ForStmt:
 		if n.Init != nil {
-			n.Init = ii.Inspect(n.Init).(ast.Stmt)
+			inspectOptional(ii, n, "Init", &n.Init)
 		}
 		if n.Cond != nil {
-			n.Cond = ii.Inspect(n.Cond).(ast.Expr)
+			inspectOptional(ii, n, "Cond", &n.Cond)
 		}
 		if n.Post != nil {
-			n.Post = ii.Inspect(n.Post).(ast.Stmt)
+			inspectOptional(ii, n, "Post", &n.Post)
 		}
-		n.Body = ii.Inspect(n.Body).(*ast.BlockStmt)
+		inspectChild(ii, n, "Body", &n.Body)
 
 	case *ast.RangeStmt:
 		if n.Key != nil {
-			n.Key = ii.Inspect(n.Key).(ast.Expr)
+			inspectOptional(ii, n, "Key", &n.Key)
 		}
 		if n.Value != nil {
-			n.Value = ii.Inspect(n.Value).(ast.Expr)
+			inspectOptional(ii, n, "Value", &n.Value)
 		}
-		n.X = ii.Inspect(n.X).(ast.Expr)
-		n.Body = ii.Inspect(n.Body).(*ast.BlockStmt)
+		inspectChild(ii, n, "X", &n.X)
+		inspectChild(ii, n, "Body", &n.Body)
 
 	// Declarations
 	case *ast.ImportSpec:
 		if n.Doc != nil {
-			n.Doc = ii.Inspect(n.Doc).(*ast.CommentGroup)
+			inspectOptional(ii, n, "Doc", &n.Doc)
 		}
 		if n.Name != nil {
-			n.Name = ii.Inspect(n.Name).(*ast.Ident)
+			inspectOptional(ii, n, "Name", &n.Name)
 		}
-		n.Path = ii.Inspect(n.Path).(*ast.BasicLit)
+		inspectChild(ii, n, "Path", &n.Path)
 		if n.Comment != nil {
-			n.Comment = ii.Inspect(n.Comment).(*ast.CommentGroup)
+			inspectOptional(ii, n, "Comment", &n.Comment)
 		}
 
 	case *ast.ValueSpec:
 		if n.Doc != nil {
-			n.Doc = ii.Inspect(n.Doc).(*ast.CommentGroup)
+			inspectOptional(ii, n, "Doc", &n.Doc)
 		}
-		n.Names = inspectIdentList(ii, n.Names)
+		inspectList(ii, n, "Names", &n.Names)
 		if n.Type != nil {
-			n.Type = ii.Inspect(n.Type).(ast.Expr)
+			inspectOptional(ii, n, "Type", &n.Type)
 		}
-		n.Values = inspectExprList(ii, n.Values)
+		inspectList(ii, n, "Values", &n.Values)
 		if n.Comment != nil {
-			n.Comment = ii.Inspect(n.Comment).(*ast.CommentGroup)
+			inspectOptional(ii, n, "Comment", &n.Comment)
 		}
 
 	case *ast.TypeSpec:
 		if n.Doc != nil {
-			n.Doc = ii.Inspect(n.Doc).(*ast.CommentGroup)
+			inspectOptional(ii, n, "Doc", &n.Doc)
+		}
+		inspectChild(ii, n, "Name", &n.Name)
+		if n.TypeParams != nil {
+			inspectOptional(ii, n, "TypeParams", &n.TypeParams)
 		}
-		n.Name = ii.Inspect(n.Name).(*ast.Ident)
-		n.Type = ii.Inspect(n.Type).(ast.Expr)
+		inspectChild(ii, n, "Type", &n.Type)
 		if n.Comment != nil {
-			n.Comment = ii.Inspect(n.Comment).(*ast.CommentGroup)
+			inspectOptional(ii, n, "Comment", &n.Comment)
 		}
 
 	case *ast.BadDecl:
@@ -338,76 +1297,480 @@ func (i *inspectorImpl) Inspect(node ast.Node) ast.Node {
 
 	case *ast.GenDecl:
 		if n.Doc != nil {
-			n.Doc = ii.Inspect(n.Doc).(*ast.CommentGroup)
-		}
-		for l, s := range n.Specs {
-			n.Specs[l] = ii.Inspect(s).(ast.Spec)
+			inspectOptional(ii, n, "Doc", &n.Doc)
 		}
+		inspectList(ii, n, "Specs", &n.Specs)
 
 	case *ast.FuncDecl:
 		if n.Doc != nil {
-			n.Doc = ii.Inspect(n.Doc).(*ast.CommentGroup)
+			inspectOptional(ii, n, "Doc", &n.Doc)
 		}
 		if n.Recv != nil {
-			n.Recv = ii.Inspect(n.Recv).(*ast.FieldList)
+			inspectOptional(ii, n, "Recv", &n.Recv)
 		}
-		n.Name = ii.Inspect(n.Name).(*ast.Ident)
-		n.Type = ii.Inspect(n.Type).(*ast.FuncType)
-		if n.Body != nil {
-			n.Body = ii.Inspect(n.Body).(*ast.BlockStmt)
+		inspectChild(ii, n, "Name", &n.Name)
+		inspectChild(ii, n, "Type", &n.Type)
+		if n.Body != nil && !skippingBodies(ii) {
+			inspectOptional(ii, n, "Body", &n.Body)
 		}
 
 	// Files and packages
 	case *ast.File:
 		if n.Doc != nil {
-			n.Doc = ii.Inspect(n.Doc).(*ast.CommentGroup)
+			inspectOptional(ii, n, "Doc", &n.Doc)
+		}
+		inspectChild(ii, n, "Name", &n.Name)
+		inspectList(ii, n, "Decls", &n.Decls)
+		if visitingFileLists(ii) {
+			inspectList(ii, n, "Imports", &n.Imports)
+			inspectList(ii, n, "Unresolved", &n.Unresolved)
 		}
-		n.Name = ii.Inspect(n.Name).(*ast.Ident)
-		n.Decls = inspectDeclList(ii, n.Decls)
 		// don't inspect n.Comments - they have been
 		// visited already through the individual
-		// nodes
+		// nodes (or will be, through the comment map
+		// passed to WithCommentMap)
 
 	case *ast.Package:
-		for l, f := range n.Files {
-			n.Files[l] = ii.Inspect(f).(*ast.File)
+		// n.Files is a map, so walk it in a stable order
+		names, ok := packageFiles(ii, n)
+		if !ok {
+			names = packageFileNames(n, nil)
+		}
+		for _, name := range names {
+			if _, ok := n.Files[name]; ok {
+				inspectFile(ii, n, name)
+			}
 		}
 
 	default:
 		fmt.Printf("astor.Inspect: unexpected node type %T", n)
 		panic("astor.Inspect")
 	}
+}
 
-	ii.Visit(nil)
-	return node
+// slot describes where the node being inspected is held in the AST, so its replacement can be checked before it is
+// stored there.
+type slot struct {
+	// parent is the node holding the node being inspected, or nil at the root of a walk
+	parent ast.Node
+	// field is the name of the parent's field holding the node, or CommentMapField for a comment group from the comment
+	// map
+	field string
+	// index is the position of the node in the field, if the field is a slice, or -1
+	index int
+	// typ is the type of the field (or of its elements) and so of any replacement, or nil if any node is acceptable
+	typ reflect.Type
+	// optional is whether the field may be nil, so the node may be replaced with nil
+	optional bool
+	// comments is the list of comment groups the comment map holds for the parent, if the node is one of them rather
+	// than being held in a field of the parent
+	comments []*ast.CommentGroup
+}
+
+// rootSlot is the slot of the node passed to Inspect, which may be replaced by any node.
+var rootSlot = slot{index: -1}
+
+func (s slot) String() string {
+	if s.parent == nil {
+		if s.index >= 0 {
+			return fmt.Sprintf("root node %d", s.index)
+		}
+		return "the root node"
+	}
+	if s.comments != nil {
+		return fmt.Sprintf("comment group %d of the comment map for %T", s.index, s.parent)
+	}
+	if s.index >= 0 {
+		return fmt.Sprintf("%T.%s[%d]", s.parent, s.field, s.index)
+	}
+	return fmt.Sprintf("%T.%s", s.parent, s.field)
+}
+
+// check panics if n can't be stored in the slot. The parent's type assertion would panic anyway, but far from the
+// Visitor's call to Replace and without saying where n was going.
+func (s slot) check(n ast.Node) {
+	if s.typ == nil {
+		return
+	}
+	if n == nil {
+		if s.optional {
+			return
+		}
+		panic(fmt.Sprintf("astor: cannot replace %s (%s) with nil", s, s.typ))
+	}
+	if !s.holds(n) {
+		panic(fmt.Sprintf("astor: cannot replace %s (%s) with %T", s, s.typ, n))
+	}
+}
+
+// holds reports whether the (non-nil) node n can be held in s.
+func (s slot) holds(n ast.Node) bool {
+	return s.typ == nil || reflect.TypeOf(n).AssignableTo(s.typ)
 }
 
-// Helper functions for common node lists. They may be empty. Copied/adapted shamelessly from go/ast.
+// slotType returns the reflect.Type of T, which may be an interface type.
+func slotType[T ast.Node]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// inspectChild inspects the child held in the named field of parent, which ptr points to, with ii, storing its
+// replacement there.
+func inspectChild[T ast.Node](ii Inspector, parent ast.Node, field string, ptr *T) {
+	inspectField(ii, parent, field, ptr, false)
+}
 
-func inspectIdentList(i Inspector, list []*ast.Ident) []*ast.Ident {
-	for l, x := range list {
-		list[l] = i.Inspect(x).(*ast.Ident)
+// inspectOptional is inspectChild for a field which may be nil, so the child may be replaced with nil (or deleted).
+func inspectOptional[T ast.Node](ii Inspector, parent ast.Node, field string, ptr *T) {
+	inspectField(ii, parent, field, ptr, true)
+}
+
+func inspectField[T ast.Node](ii Inspector, parent ast.Node, field string, ptr *T, optional bool) {
+	if skippingField(ii, field) {
+		return
+	}
+	if refs := collecting(ii); refs != nil {
+		*refs = append(*refs, fieldRef[T]{parent: parent, field: field, ptr: ptr, optional: optional})
+		return
+	}
+	s := slot{parent: parent, field: field, index: -1, typ: slotType[T](), optional: optional}
+	// only changes are stored, so a walk which changes nothing doesn't write to the AST
+	if r, _ := inspectSlot(ii, s, *ptr); ast.Node(r) != ast.Node(*ptr) {
+		*ptr = r
 	}
-	return list
 }
 
-func inspectExprList(i Inspector, list []ast.Expr) []ast.Expr {
-	for l, x := range list {
-		list[l] = i.Inspect(x).(ast.Expr)
+// inspectList inspects each of the children held in the named slice field of parent, which ptr points to, with ii,
+// storing the list of their replacements there, with any deletions and insertions applied. The list may be empty.
+func inspectList[T ast.Node](ii Inspector, parent ast.Node, field string, ptr *[]T) {
+	if skippingField(ii, field) {
+		return
+	}
+	if refs := collecting(ii); refs != nil {
+		*refs = append(*refs, listRef[T]{parent: parent, field: field, ptr: ptr})
+		return
+	}
+
+	impl, ok := ii.(*inspectorImpl)
+	if ok {
+		enterList(impl, parent, field, ptr)
+	}
+	b := listBuilder[T]{list: *ptr, reverse: ok && impl.reverse}
+	typ := slotType[T]()
+	for k := range b.list {
+		l := b.index(k)
+		r, edit := inspectSlot(ii, slot{parent: parent, field: field, index: l, typ: typ}, b.list[l])
+		b.add(l, r, edit)
+	}
+	if b.edited != nil {
+		*ptr = b.result()
 	}
-	return list
+	if ok {
+		exitList(impl, parent, field, ptr)
+	}
+}
+
+// A listBuilder applies the replacements and edits made to each of the elements of list in turn, in reverse if it is
+// inspected from its end.
+type listBuilder[T ast.Node] struct {
+	list    []T
+	reverse bool
+	// edited is the new list (in reverse, if the list is), once it differs from the old one, which is left as it was
+	// so the nodes yet to be inspected can see their siblings
+	edited []T
 }
 
-func inspectStmtList(i Inspector, list []ast.Stmt) []ast.Stmt {
-	for l, x := range list {
-		list[l] = i.Inspect(x).(ast.Stmt)
+// index returns the index of the kth element of the list to be inspected.
+func (b *listBuilder[T]) index(k int) int {
+	if b.reverse {
+		return len(b.list) - 1 - k
 	}
-	return list
+	return k
 }
 
-func inspectDeclList(i Inspector, list []ast.Decl) []ast.Decl {
-	for l, x := range list {
-		list[l] = i.Inspect(x).(ast.Decl)
+// add applies the replacement and edits made to the element at index l.
+func (b *listBuilder[T]) add(l int, r T, edit listEdit) {
+	if b.edited == nil && edit.empty() && ast.Node(r) == ast.Node(b.list[l]) {
+		return
 	}
-	return list
+
+	if b.reverse {
+		if b.edited == nil {
+			b.edited = make([]T, 0, len(b.list)+len(edit.before)+len(edit.after))
+			for k := len(b.list) - 1; k > l; k-- {
+				b.edited = append(b.edited, b.list[k])
+			}
+		}
+		for k := len(edit.after) - 1; k >= 0; k-- {
+			b.edited = append(b.edited, edit.after[k].(T))
+		}
+		if !edit.deleted {
+			b.edited = append(b.edited, r)
+		}
+		for k := len(edit.before) - 1; k >= 0; k-- {
+			b.edited = append(b.edited, edit.before[k].(T))
+		}
+		return
+	}
+
+	if b.edited == nil {
+		b.edited = make([]T, l, len(b.list)+len(edit.before)+len(edit.after))
+		copy(b.edited, b.list[:l])
+	}
+	for _, n := range edit.before {
+		b.edited = append(b.edited, n.(T))
+	}
+	if !edit.deleted {
+		b.edited = append(b.edited, r)
+	}
+	for _, n := range edit.after {
+		b.edited = append(b.edited, n.(T))
+	}
+}
+
+// result returns the new list.
+func (b *listBuilder[T]) result() []T {
+	if b.edited == nil {
+		return b.list
+	}
+	if b.reverse {
+		for l, k := 0, len(b.edited)-1; l < k; l, k = l+1, k-1 {
+			b.edited[l], b.edited[k] = b.edited[k], b.edited[l]
+		}
+	}
+	return b.edited
+}
+
+// inspectFile inspects the named file of pkg with ii, storing its replacement in pkg.
+// packageFiles returns the names of the files of pkg to visit, in order, if the walk was started by InspectPackage or
+// InspectPackageForContext.
+func packageFiles(ii Inspector, pkg *ast.Package) ([]string, bool) {
+	impl, ok := ii.(*inspectorImpl)
+	if !ok {
+		return nil, false
+	}
+	names, ok := impl.packageFiles[pkg]
+	return names, ok
+}
+
+func inspectFile(ii Inspector, pkg *ast.Package, name string) {
+	if skippingField(ii, "Files") {
+		return
+	}
+	if refs := collecting(ii); refs != nil {
+		*refs = append(*refs, fileRef{pkg: pkg, name: name})
+		return
+	}
+	f, _ := inspectSlot(ii, slot{parent: pkg, field: "Files", index: -1, typ: slotType[*ast.File]()}, pkg.Files[name])
+	if f != pkg.Files[name] {
+		pkg.Files[name] = f
+	}
+}
+
+func inspectSlot[T ast.Node](ii Inspector, s slot, child T) (T, listEdit) {
+	var replacement ast.Node
+	var edit listEdit
+	if impl, ok := ii.(*inspectorImpl); ok {
+		impl.slot = s
+		replacement, edit = impl.inspect(child)
+	} else {
+		replacement = ii.Inspect(child)
+	}
+
+	return slotNode[T](s, replacement, edit), edit
+}
+
+// slotNode returns n, the replacement for the node held in s, as the type of node s holds.
+func slotNode[T ast.Node](s slot, n ast.Node, edit listEdit) T {
+	r, ok := n.(T)
+	if !ok && n != nil && !edit.deleted {
+		// Replace checks this for inspectorImpl, but the node might have been replaced by another Inspector. A nil node
+		// is a missing child left as it was.
+		s.check(n)
+	}
+	return r
+}
+
+// A childRef refers to where a child of a node (or a list of them) is held, so it can be inspected later.
+type childRef interface {
+	inspect(ii Inspector)
+	// cursor returns a childCursor over the children, inspected in reverse if reverse is true
+	cursor(reverse bool) childCursor
+}
+
+// A hookedCursor is a childCursor over a list, around which the OnEnterList and OnExitList functions of an Inspector
+// can be called.
+type hookedCursor interface {
+	hook(i *inspectorImpl)
+}
+
+// A childCursor steps through the children held where a childRef refers to, so they can be inspected one at a time
+// without recursion.
+type childCursor interface {
+	// next returns the slot and node of the next child to be inspected, or false if there are no more
+	next() (slot, ast.Node, bool)
+	// store stores the replacement for the child last returned by next, and the edits made to its slice
+	store(n ast.Node, edit listEdit)
+	// finish stores the result of any edits, once every child has been inspected
+	finish()
+}
+
+type fieldRef[T ast.Node] struct {
+	parent   ast.Node
+	field    string
+	ptr      *T
+	optional bool
+}
+
+func (r fieldRef[T]) inspect(ii Inspector) {
+	inspectField(ii, r.parent, r.field, r.ptr, r.optional)
+}
+
+func (r fieldRef[T]) cursor(reverse bool) childCursor {
+	s := slot{parent: r.parent, field: r.field, index: -1, typ: slotType[T](), optional: r.optional}
+	return &fieldCursor[T]{ref: r, slot: s}
+}
+
+type fieldCursor[T ast.Node] struct {
+	ref  fieldRef[T]
+	slot slot
+	done bool
+}
+
+func (c *fieldCursor[T]) next() (slot, ast.Node, bool) {
+	if c.done {
+		return slot{}, nil, false
+	}
+	c.done = true
+	return c.slot, *c.ref.ptr, true
+}
+
+func (c *fieldCursor[T]) store(n ast.Node, edit listEdit) {
+	if r := slotNode[T](c.slot, n, edit); ast.Node(r) != ast.Node(*c.ref.ptr) {
+		*c.ref.ptr = r
+	}
+}
+
+func (c *fieldCursor[T]) finish() {}
+
+type listRef[T ast.Node] struct {
+	parent ast.Node
+	field  string
+	ptr    *[]T
+}
+
+func (r listRef[T]) inspect(ii Inspector) {
+	inspectList(ii, r.parent, r.field, r.ptr)
+}
+
+func (r listRef[T]) cursor(reverse bool) childCursor {
+	return &listCursor[T]{ref: r, b: listBuilder[T]{list: *r.ptr, reverse: reverse}, typ: slotType[T]()}
+}
+
+type listCursor[T ast.Node] struct {
+	ref listRef[T]
+	b   listBuilder[T]
+	typ reflect.Type
+	// hooks is the Inspector whose OnEnterList and OnExitList functions are called around the list, if any
+	hooks *inspectorImpl
+	// k is the number of children returned by next, the last of which is at index l
+	k, l int
+}
+
+func (c *listCursor[T]) next() (slot, ast.Node, bool) {
+	if c.k == len(c.b.list) {
+		return slot{}, nil, false
+	}
+	c.l = c.b.index(c.k)
+	c.k++
+	return c.slot(), c.b.list[c.l], true
+}
+
+func (c *listCursor[T]) slot() slot {
+	return slot{parent: c.ref.parent, field: c.ref.field, index: c.l, typ: c.typ}
+}
+
+func (c *listCursor[T]) store(n ast.Node, edit listEdit) {
+	c.b.add(c.l, slotNode[T](c.slot(), n, edit), edit)
+}
+
+func (c *listCursor[T]) finish() {
+	if c.b.edited != nil {
+		*c.ref.ptr = c.b.result()
+	}
+	if c.hooks != nil {
+		exitList(c.hooks, c.ref.parent, c.ref.field, c.ref.ptr)
+	}
+}
+
+// hook calls the OnEnterList functions of i with the list, and has its OnExitList functions called once it is
+// finished.
+func (c *listCursor[T]) hook(i *inspectorImpl) {
+	enterList(i, c.ref.parent, c.ref.field, c.ref.ptr)
+	c.b.list, c.hooks = *c.ref.ptr, i
+}
+
+type fileRef struct {
+	pkg  *ast.Package
+	name string
+}
+
+func (r fileRef) inspect(ii Inspector) {
+	inspectFile(ii, r.pkg, r.name)
+}
+
+func (r fileRef) cursor(reverse bool) childCursor {
+	return &fileCursor{ref: r, slot: slot{parent: r.pkg, field: "Files", index: -1, typ: slotType[*ast.File]()}}
+}
+
+type fileCursor struct {
+	ref  fileRef
+	slot slot
+	done bool
+}
+
+func (c *fileCursor) next() (slot, ast.Node, bool) {
+	if c.done {
+		return slot{}, nil, false
+	}
+	c.done = true
+	return c.slot, c.ref.pkg.Files[c.ref.name], true
+}
+
+func (c *fileCursor) store(n ast.Node, edit listEdit) {
+	if f := slotNode[*ast.File](c.slot, n, edit); f != c.ref.pkg.Files[c.ref.name] {
+		c.ref.pkg.Files[c.ref.name] = f
+	}
+}
+
+func (c *fileCursor) finish() {}
+
+// collecting returns the references to which children should be added, rather than inspected, if ii is collecting
+// the children of a node, or nil. (The references are only made if they are needed, since each is an allocation.)
+func collecting(ii Inspector) *[]childRef {
+	if impl, ok := ii.(*inspectorImpl); ok {
+		return impl.collecting
+	}
+	return nil
+}
+
+// children returns references to the children of node (and lists of them), in the order they would be inspected,
+// without inspecting them.
+func (i *inspectorImpl) children(node ast.Node) []childRef {
+	var refs []childRef
+	outer := i.collecting
+	i.collecting = &refs
+	inspectChildren(i, node)
+	i.collecting = outer
+	return refs
+}
+
+// listEdit holds the changes a Visitor made to the slice holding the node it visited.
+type listEdit struct {
+	deleted bool
+	before  []ast.Node
+	after   []ast.Node
+}
+
+func (e listEdit) empty() bool {
+	return !e.deleted && len(e.before) == 0 && len(e.after) == 0
 }
@@ -5,9 +5,14 @@ import (
 	"fmt"
 	"go/ast"
 	"go/format"
+	"go/importer"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
 
 	"testing"
 
@@ -77,3 +82,1066 @@ func TestChangePointerToInterface(t *testing.T) {
 		"test-samples/pointer-to-interface.go.out",
 		visitor)
 }
+
+func TestReplaceAndRevisit(t *testing.T) {
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n, ok := n.(*ast.Ident); ok {
+			switch n.Name {
+			case "x":
+				// the replacement is visited in turn, so its y is rewritten too
+				i.ReplaceAndRevisit(&ast.CallExpr{
+					Fun:  ast.NewIdent("wrap"),
+					Args: []ast.Expr{ast.NewIdent("y")},
+				})
+			case "y":
+				i.Replace(ast.NewIdent("z"))
+			}
+		}
+
+		return true
+	}
+
+	runInspector(
+		t,
+		"test-samples/revisit-replacement.go.in",
+		"test-samples/revisit-replacement.go.out",
+		visitor)
+}
+
+func TestReplaceAndRevisitLoop(t *testing.T) {
+	visitor := func(i Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.Ident); ok {
+			i.ReplaceAndRevisit(ast.NewIdent("x"))
+		}
+
+		return true
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), "loop.go", "package foo\n\nvar a, b = x, y\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	name := f.Name
+	var visited []string
+	inspector := NewInspector(func(i Inspector, n ast.Node) bool {
+		if n != nil {
+			visited = append(visited, Sequence(n)[0])
+		}
+		return visitor(i, n)
+	})
+	inspector.Inspect(f)
+	var revisitErr *RevisitError
+	assert.ErrorAs(t, inspector.Err(), &revisitErr)
+	assert.Equal(t, 10, revisitErr.MaxRevisits)
+	assert.Equal(t, name, revisitErr.Node)
+	assert.Equal(t, "astor: possible rewrite cycle at *ast.Ident (position 9): ReplaceAndRevisit called 10 times in a row",
+		revisitErr.Error())
+	// the walk stops there, keeping the last replacement
+	assert.Len(t, visited, 1+11)
+	assert.Equal(t, revisitErr.Replacement, f.Name)
+	assert.Equal(t, "x", f.Name.Name)
+
+	// the limit can be set
+	inspector = NewInspector(visitor, WithMaxRevisits(3))
+	inspector.Inspect(ast.NewIdent("x"))
+	assert.ErrorAs(t, inspector.Err(), &revisitErr)
+	assert.Equal(t, 3, revisitErr.MaxRevisits)
+
+	// a walk within the limit has no error
+	var revisits int
+	inspector = NewInspector(func(i Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.Ident); ok && revisits < 5 {
+			revisits++
+			i.ReplaceAndRevisit(ast.NewIdent("x"))
+		}
+		return true
+	}, WithMaxRevisits(5))
+	inspector.Inspect(ast.NewIdent("x"))
+	assert.NoError(t, inspector.Err())
+}
+
+const typesSrc = `package foo
+
+import "errors"
+
+func a() error { return errors.New("a") }
+func b() int   { return 1 }
+
+func Bar() {
+	a()
+	b()
+}
+`
+
+func TestTypeOf(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "types.go", typesSrc, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	conf := types.Config{Importer: importer.Default()}
+	_, err = conf.Check("foo", fset, []*ast.File{f}, info)
+	assert.NoError(t, err, "Error type-checking input")
+
+	errorType := types.Universe.Lookup("error").Type()
+	var errorCalls []string
+	visitor := func(i Inspector, n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && types.Identical(i.TypeOf(call), errorType) {
+			if fun, ok := call.Fun.(*ast.Ident); ok {
+				errorCalls = append(errorCalls, fun.Name)
+			}
+		}
+		return true
+	}
+	NewInspectorWithTypes(visitor, info).Inspect(f)
+	assert.Equal(t, []string{"a"}, errorCalls)
+
+	// Without type information nothing is known
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		if e, ok := n.(ast.Expr); ok {
+			assert.Nil(t, i.TypeOf(e))
+		}
+		return true
+	}).Inspect(f)
+}
+
+func TestReplaceChecksSlotType(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "import.go", "package foo\n\nimport \"fmt\"\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	visitor := func(i Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.BasicLit); ok {
+			i.Replace(ast.NewIdent("fmt"))
+		}
+		return true
+	}
+	assert.PanicsWithValue(t, "astor: cannot replace *ast.ImportSpec.Path (*ast.BasicLit) with *ast.Ident", func() {
+		NewInspector(visitor).Inspect(f)
+	})
+
+	f, err = parser.ParseFile(token.NewFileSet(), "call.go", "package foo\n\nvar a = b(c, d)\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	visitor = func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "d" {
+			i.Replace(&ast.EmptyStmt{})
+		}
+		return true
+	}
+	assert.PanicsWithValue(t, "astor: cannot replace *ast.CallExpr.Args[1] (ast.Expr) with *ast.EmptyStmt", func() {
+		NewInspector(visitor).Inspect(f)
+	})
+}
+
+func TestReplaceListElement(t *testing.T) {
+	for name, walk := range map[string]func(Inspector, ast.Node) ast.Node{
+		"recursive": func(i Inspector, n ast.Node) ast.Node { return i.Inspect(n) },
+		"iterative": InspectIterative,
+	} {
+		for _, opts := range [][]Option{nil, {WithReverseOrder()}} {
+			f, err := parser.ParseFile(token.NewFileSet(), "args.go", "package foo\n\nvar a = b(c, d, e)\n", parserFlags)
+			assert.NoError(t, err, "Error parsing input")
+			call := f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0].(*ast.CallExpr)
+			replacement := ast.NewIdent("x")
+
+			walk(NewInspector(func(i Inspector, n ast.Node) bool {
+				if i.Parent() == ast.Node(call) && i.Index() == 1 && n != nil {
+					i.Replace(replacement)
+				}
+				return true
+			}, opts...), f)
+			assert.Len(t, call.Args, 3, name)
+			assert.Same(t, replacement, call.Args[1], name)
+			assert.Equal(t, "e", call.Args[2].(*ast.Ident).Name, name)
+		}
+	}
+}
+
+// callStmt returns a statement calling the named function with no arguments.
+func callStmt(name string) *ast.ExprStmt {
+	return &ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent(name)}}
+}
+
+// calledName returns the name of the function called by an expression statement, or "".
+func calledName(n ast.Node) string {
+	if stmt, ok := n.(*ast.ExprStmt); ok {
+		if call, ok := stmt.X.(*ast.CallExpr); ok {
+			if fun, ok := call.Fun.(*ast.Ident); ok {
+				return fun.Name
+			}
+		}
+	}
+	return ""
+}
+
+func deleteAndInsertVisitor(i Inspector, n ast.Node) bool {
+	switch calledName(n) {
+	case "a":
+		i.InsertBefore(callStmt("w"))
+		i.InsertAfter(callStmt("x"))
+		i.InsertAfter(callStmt("y"))
+	case "b":
+		i.Delete()
+	case "c":
+		i.Replace(callStmt("d"))
+	}
+
+	return true
+}
+
+func TestDeleteAndInsert(t *testing.T) {
+	runInspector(
+		t,
+		"test-samples/delete-and-insert.go.in",
+		"test-samples/delete-and-insert.go.out",
+		deleteAndInsertVisitor)
+}
+
+func TestReplaceTwice(t *testing.T) {
+	var visited []string
+	dryRun := false
+	visitor := func(i Inspector, n ast.Node) bool {
+		switch calledName(n) {
+		case "a":
+			i.Replace(callStmt("x"))
+			if !dryRun {
+				assert.Equal(t, "x", calledName(i.Current()))
+			}
+			i.Replace(callStmt("y"))
+			if !dryRun {
+				assert.Equal(t, "y", calledName(i.Current()))
+			}
+		case "b":
+			i.Delete()
+			i.Replace(callStmt("z"))
+		case "c":
+			i.Replace(callStmt("w"))
+			i.Delete()
+		}
+		if id, ok := n.(*ast.Ident); ok {
+			visited = append(visited, id.Name)
+		}
+		return true
+	}
+
+	f := parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\ta()\n\tb()\n\tc()\n}\n")
+	NewInspector(visitor).Inspect(f)
+	assert.Empty(t, Diff(parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\ty()\n\tz()\n}\n"), f))
+	// only the last replacement is inspected
+	assert.Equal(t, []string{"foo", "Bar", "y", "z"}, visited)
+
+	f, dryRun = parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\ta()\n\tb()\n\tc()\n}\n"), true
+	edits := InspectDryRun(NewInspector(visitor), f)
+	kinds := make([]EditKind, len(edits))
+	for l, e := range edits {
+		kinds[l] = e.Kind
+	}
+	assert.Equal(t, []EditKind{EditReplace, EditReplace, EditDelete}, kinds)
+	assert.Equal(t, "y", calledName(edits[0].New))
+	assert.Equal(t, "z", calledName(edits[1].New))
+}
+
+func TestReplaceWhenFinishing(t *testing.T) {
+	// a + b is replaced by a once its children have been inspected, and a by c as it's visited
+	var stack []ast.Node
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n != nil {
+			if id, ok := n.(*ast.Ident); ok && id.Name == "a" {
+				i.Replace(ast.NewIdent("c"))
+			}
+			stack = append(stack, i.Current())
+			return true
+		}
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if b, ok := top.(*ast.BinaryExpr); ok {
+			i.Replace(b.X)
+		}
+		return true
+	}
+	src := "package foo\n\nvar x = a + b\n"
+	for _, walk := range []func(Inspector, ast.Node) ast.Node{
+		func(i Inspector, n ast.Node) ast.Node { return i.Inspect(n) },
+		InspectIterative,
+	} {
+		f := parseEqualSrc(t, src)
+		i := NewInspector(visitor)
+		assert.Equal(t, f, walk(i, f))
+		assert.Empty(t, Diff(parseEqualSrc(t, "package foo\n\nvar x = c\n"), f))
+		assert.Empty(t, stack)
+	}
+
+	f := parseEqualSrc(t, src)
+	binary := f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0]
+	edits := InspectDryRun(NewInspector(visitor), f)
+	assert.Len(t, edits, 2)
+	assert.Equal(t, "a", edits[0].Old.(*ast.Ident).Name)
+	assert.Equal(t, binary, edits[1].Old)
+	assert.Equal(t, binary.(*ast.BinaryExpr).X, edits[1].New)
+}
+
+func TestDeleteOutsideSlice(t *testing.T) {
+	visitor := func(i Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.FuncType); ok {
+			i.Delete()
+		}
+		return true
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "func.go", "package foo\n\nfunc Bar() {}\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	assert.PanicsWithValue(t, "astor: Delete called for *ast.FuncDecl.Type, which isn't held in a slice", func() {
+		NewInspector(visitor).Inspect(f)
+	})
+}
+
+func TestSiblings(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "siblings.go", "package foo\n\nfunc Bar() { a(); b(); c() }\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var log []string
+	visitor := func(i Inspector, n ast.Node) bool {
+		if name := calledName(n); name != "" {
+			log = append(log, fmt.Sprintf("%s<%s>%s", calledName(i.PrevSibling()), name, calledName(i.NextSibling())))
+			// siblings are as they were before the walk changed them
+			i.Replace(callStmt(name + "2"))
+		} else if id, ok := n.(*ast.Ident); ok && id.Name == "Bar" {
+			assert.Nil(t, i.PrevSibling())
+			assert.Nil(t, i.NextSibling())
+		}
+		return true
+	}
+	NewInspector(visitor).Inspect(f)
+	assert.Equal(t, []string{"<a>b", "a<b>c", "b<c>"}, log)
+}
+
+func TestClearOptional(t *testing.T) {
+	src := "package foo\n\nvar a int = 1\n\nfunc Bar() {\n\tif b := c(); b {\n\t\td()\n\t}\n}\n"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "optional.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var visited []string
+	visitor := func(i Inspector, n ast.Node) bool {
+		switch i.FieldName() {
+		case "Init":
+			i.Replace(nil)
+		case "Type":
+			if _, ok := i.Parent().(*ast.ValueSpec); ok {
+				i.Delete()
+			}
+		}
+		if id, ok := n.(*ast.Ident); ok {
+			visited = append(visited, id.Name)
+		}
+		return true
+	}
+	NewInspector(visitor).Inspect(f)
+
+	var out strings.Builder
+	assert.NoError(t, format.Node(&out, fset, f))
+	assert.Equal(t, "package foo\n\nvar a = 1\n\nfunc Bar() {\n\tif b {\n\t\td()\n\t}\n}\n", out.String())
+	// the children of the removed nodes aren't inspected
+	assert.Equal(t, []string{"foo", "a", "int", "Bar", "b", "d"}, visited)
+
+	visitor = func(i Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.BinaryExpr); ok {
+			i.Replace(nil)
+		}
+		return true
+	}
+	f, err = parser.ParseFile(token.NewFileSet(), "cond.go", "package foo\n\nfunc Bar() {\n\tif a == b {\n\t}\n}\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	assert.PanicsWithValue(t, "astor: cannot replace *ast.IfStmt.Cond (ast.Expr) with nil", func() {
+		NewInspector(visitor).Inspect(f)
+	})
+}
+
+func TestParentAndIndex(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "func.go", "package foo\n\nfunc Bar() { a(); b() }\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var indices []int
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		if n == f {
+			assert.Nil(t, i.Parent())
+			assert.Equal(t, -1, i.Index())
+		}
+		if _, ok := n.(*ast.ExprStmt); ok {
+			assert.IsType(t, &ast.BlockStmt{}, i.Parent())
+			indices = append(indices, i.Index())
+		}
+		if _, ok := n.(*ast.BlockStmt); ok {
+			assert.IsType(t, &ast.FuncDecl{}, i.Parent())
+			assert.Equal(t, -1, i.Index())
+		}
+		return true
+	}).Inspect(f)
+	assert.Equal(t, []int{0, 1}, indices)
+}
+
+func TestFieldName(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "func.go", "package foo\n\nfunc Bar() { if a { b() } }\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var names []string
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		if n != nil {
+			names = append(names, fmt.Sprintf("%T %s", n, i.FieldName()))
+		}
+		return true
+	}).Inspect(f)
+	assert.Equal(t, []string{
+		"*ast.File ",
+		"*ast.Ident Name",
+		"*ast.FuncDecl Decls[0]",
+		"*ast.Ident Name",
+		"*ast.FuncType Type",
+		"*ast.FieldList Params",
+		"*ast.BlockStmt Body",
+		"*ast.IfStmt List[0]",
+		"*ast.Ident Cond",
+		"*ast.BlockStmt Body",
+		"*ast.ExprStmt List[0]",
+		"*ast.CallExpr X",
+		"*ast.Ident Fun",
+	}, names)
+}
+
+func TestCloneParallel(t *testing.T) {
+	const n = 8
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n, ok := n.(*ast.FuncDecl); ok {
+			i.Replace(&ast.FuncDecl{
+				Name: ast.NewIdent(fmt.Sprintf("Foo%s", n.Name.Name)),
+				Type: n.Type,
+				Body: n.Body,
+			})
+			return false
+		}
+		return true
+	}
+	inspector := NewInspector(visitor, WithNodeIDs())
+
+	fset := token.NewFileSet()
+	files := make([]*ast.File, n)
+	for l := range files {
+		src := fmt.Sprintf("package foo\n\nfunc Bar%d() {}\n", l)
+		f, err := parser.ParseFile(fset, fmt.Sprintf("file%d.go", l), src, parserFlags)
+		assert.NoError(t, err, "Error parsing input")
+		files[l] = f
+	}
+
+	var wg sync.WaitGroup
+	for _, f := range files {
+		wg.Add(1)
+		go func(i Inspector, f *ast.File) {
+			defer wg.Done()
+			i.Inspect(f)
+			assert.Equal(t, uint64(1), i.ID(f))
+		}(inspector.Clone(), f)
+	}
+	wg.Wait()
+
+	for l, f := range files {
+		assert.Equal(t, fmt.Sprintf("FooBar%d", l), f.Decls[0].(*ast.FuncDecl).Name.Name)
+	}
+}
+
+func TestOnReplace(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "replace.go", "package foo\n\nvar a = b + c\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var log []string
+	onReplace := func(old, new ast.Node) {
+		log = append(log, fmt.Sprintf("%s -> %s", old.(*ast.Ident).Name, new.(*ast.Ident).Name))
+	}
+	visitor := func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name != "a" && id.Name != "foo" {
+			i.Replace(ast.NewIdent(id.Name + id.Name))
+		}
+		return true
+	}
+	NewInspector(visitor, OnReplace(onReplace)).Inspect(f)
+	assert.Equal(t, []string{"b -> bb", "c -> cc"}, log)
+
+	// Nothing is reported for edits which aren't applied
+	log = nil
+	InspectDryRun(NewInspector(visitor, OnReplace(onReplace)), f)
+	assert.Empty(t, log)
+}
+
+func TestReplaceRoot(t *testing.T) {
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n, ok := n.(*ast.File); ok {
+			i.Replace(&ast.File{
+				Package: n.Package,
+				Name:    ast.NewIdent("baz"),
+				Decls:   n.Decls,
+			})
+		}
+		return true
+	}
+
+	runInspector(
+		t,
+		"test-samples/replace-file.go.in",
+		"test-samples/replace-file.go.out",
+		visitor)
+}
+
+func TestSkipChildren(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "skip.go", "package foo\n\nfunc Bar() { a() }\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var stack, log []string
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n == nil {
+			log = append(log, "exit "+stack[len(stack)-1])
+			stack = stack[:len(stack)-1]
+			return true
+		}
+
+		name := fmt.Sprintf("%T", n)
+		stack = append(stack, name)
+		log = append(log, "enter "+name)
+		if _, ok := n.(*ast.FuncDecl); ok {
+			i.SkipChildren()
+		}
+		return true
+	}
+	NewInspector(visitor).Inspect(f)
+	assert.Equal(t, []string{
+		"enter *ast.File",
+		"enter *ast.Ident",
+		"exit *ast.Ident",
+		"enter *ast.FuncDecl",
+		"exit *ast.FuncDecl",
+		"exit *ast.File",
+	}, log)
+	assert.Empty(t, stack)
+}
+
+func TestDirty(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "dirty.go", "package foo\n\nfunc A() { a() }\n\nfunc B() { b() }\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var stack []ast.Node
+	dirty := make(map[string]bool)
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n == nil {
+			n, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			if fd, ok := n.(*ast.FuncDecl); ok {
+				dirty[fd.Name.Name] = i.Dirty()
+			}
+			return true
+		}
+
+		stack = append(stack, n)
+		if calledName(n) == "b" {
+			assert.False(t, i.Dirty())
+			i.Replace(callStmt("c"))
+			assert.True(t, i.Dirty())
+		}
+		return true
+	}
+	NewInspector(visitor).Inspect(f)
+	assert.Equal(t, map[string]bool{"A": false, "B": true}, dirty)
+}
+
+// recoverPanic calls f and returns the value it panicked with, if any.
+func recoverPanic(f func()) (recovered interface{}) {
+	defer func() {
+		recovered = recover()
+	}()
+	f()
+	return nil
+}
+
+func TestReplaceFromOtherGoroutines(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "race.go", "package foo\n\nvar a = b\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	// A goroutine which outlives the visit can't change the AST
+	var late sync.WaitGroup
+	var latePanic interface{}
+	visitor := func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "b" {
+			late.Add(1)
+			go func() {
+				defer late.Done()
+				time.Sleep(10 * time.Millisecond)
+				latePanic = recoverPanic(func() { i.Replace(ast.NewIdent("c")) })
+			}()
+		}
+		return true
+	}
+	NewInspector(visitor).Inspect(f)
+	late.Wait()
+	assert.Equal(t, "astor: Replace called while no node is being visited", latePanic)
+
+	// Nor can two goroutines change it at once: the first is held inside Replace (by its OnReplace hook) while the
+	// second tries
+	inReplace, secondDone := make(chan struct{}), make(chan struct{})
+	onReplace := func(old, new ast.Node) {
+		close(inReplace)
+		<-secondDone
+	}
+	var secondPanic interface{}
+	visitor = func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "b" {
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				i.Replace(ast.NewIdent("c"))
+			}()
+			go func() {
+				defer wg.Done()
+				defer close(secondDone)
+				<-inReplace
+				secondPanic = recoverPanic(func() { i.Replace(ast.NewIdent("d")) })
+			}()
+			wg.Wait()
+		}
+		return true
+	}
+	NewInspector(visitor, OnReplace(onReplace)).Inspect(f)
+	assert.Equal(t, "astor: Replace called concurrently with another change to the AST", secondPanic)
+	assert.Equal(t, "c", f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0].(*ast.Ident).Name)
+}
+
+func TestReverseOrder(t *testing.T) {
+	src := "package foo\n\nfunc A(x int) { a(x); b() }\n\nvar c, d = e, f\n"
+	order := func(opts ...Option) []string {
+		f, err := parser.ParseFile(token.NewFileSet(), "reverse.go", src, parserFlags)
+		assert.NoError(t, err, "Error parsing input")
+
+		var log []string
+		visitor := func(i Inspector, n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				log = append(log, id.Name)
+			}
+			return true
+		}
+		NewInspector(visitor, opts...).Inspect(f)
+		return log
+	}
+
+	forward := order()
+	assert.Equal(t, []string{"foo", "A", "x", "int", "a", "x", "b", "c", "d", "e", "f"}, forward)
+	reverse := order(WithReverseOrder())
+	assert.Len(t, reverse, len(forward))
+	for l := range forward {
+		assert.Equal(t, forward[len(forward)-1-l], reverse[l])
+	}
+}
+
+func TestReverseOrderDeleteAndInsert(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "reverse.go", "package foo\n\nfunc A() { a(); b(); c() }\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var visited []string
+	visitor := func(i Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.ExprStmt); !ok {
+			return true
+		}
+		name := calledName(n)
+		visited = append(visited, fmt.Sprintf("%s@%d", name, i.Index()))
+		switch name {
+		case "c":
+			i.InsertBefore(callStmt("b2"))
+			i.InsertAfter(callStmt("c2"))
+		case "b":
+			i.Delete()
+		}
+		return true
+	}
+	NewInspector(visitor, WithReverseOrder()).Inspect(f)
+	assert.Equal(t, []string{"c@2", "b@1", "a@0"}, visited)
+
+	var names []string
+	for _, stmt := range f.Decls[0].(*ast.FuncDecl).Body.List {
+		names = append(names, calledName(stmt))
+	}
+	assert.Equal(t, []string{"a", "b2", "c", "c2"}, names)
+}
+
+func TestNestedInspect(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "nested.go", "package foo\n\nvar a = b + c\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var log []string
+	visitor := func(i Inspector, n ast.Node) bool {
+		assert.True(t, Visiting(i))
+		id, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		log = append(log, id.Name+" in "+i.FieldName())
+		if id.Name == "b" {
+			// a detached subtree, walked by the same Inspector from within its Visitor
+			detached := &ast.BinaryExpr{X: ast.NewIdent("x"), Op: token.ADD, Y: ast.NewIdent("y")}
+			assert.Equal(t, detached, i.Inspect(detached))
+			assert.Equal(t, "yy", detached.Y.(*ast.Ident).Name)
+			assert.Equal(t, "X", i.FieldName())
+			assert.Equal(t, ast.Node(id), i.Current())
+			i.Replace(ast.NewIdent("bb"))
+		}
+		if id.Name == "y" {
+			i.Replace(ast.NewIdent("yy"))
+		}
+		return true
+	}
+	i := NewInspector(visitor)
+	assert.False(t, Visiting(i))
+	i.Inspect(f)
+	assert.Equal(t, []string{"foo in Name", "a in Names[0]", "b in X", "x in X", "y in Y", "c in Y"}, log)
+	assert.Empty(t, Diff(f, parseEqualSrc(t, "package foo\n\nvar a = bb + c\n")))
+	assert.False(t, Visiting(i))
+}
+
+func TestStop(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "stop.go", "package foo\n\nvar a = b + c\n\nvar d = e\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var log []string
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n == nil {
+			log = append(log, "exit")
+			return true
+		}
+		if id, ok := n.(*ast.Ident); ok {
+			log = append(log, id.Name)
+			if id.Name == "b" {
+				i.Replace(ast.NewIdent("bb"))
+				i.Stop()
+			}
+		}
+		return true
+	}
+	inspector := NewInspector(visitor)
+	inspector.Inspect(f)
+	assert.Equal(t, []string{"foo", "exit", "a", "exit", "b"}, log)
+	assert.Equal(t, "bb", f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0].(*ast.BinaryExpr).X.(*ast.Ident).Name)
+
+	// The next walk starts afresh
+	log = nil
+	inspector.Inspect(f.Decls[1])
+	assert.Equal(t, []string{"d", "exit", "e", "exit", "exit", "exit"}, log)
+}
+
+func TestMaxDepth(t *testing.T) {
+	src := "package foo\n\nvar a = " + strings.Repeat("(", 100) + "b" + strings.Repeat(")", 100) + "\n"
+	f, err := parser.ParseFile(token.NewFileSet(), "deep.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var visited int
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n != nil {
+			visited++
+		}
+		return true
+	}
+
+	// The file, foo, the declaration, its spec and a, then the parentheses at depths 3 to 50
+	inspector := NewInspector(visitor, WithMaxDepth(50))
+	inspector.Inspect(f)
+	assert.Equal(t, 5+48, visited)
+	var depthErr *DepthError
+	assert.ErrorAs(t, inspector.Err(), &depthErr)
+	assert.Equal(t, 50, depthErr.MaxDepth)
+	assert.IsType(t, &ast.ParenExpr{}, depthErr.Node)
+
+	// A walk within the limit has no error
+	visited = 0
+	inspector = NewInspector(visitor, WithMaxDepth(200))
+	inspector.Inspect(f)
+	assert.NoError(t, inspector.Err())
+	assert.Equal(t, 5+100+1, visited)
+}
+
+func TestMaxNodes(t *testing.T) {
+	src := "package foo\n\nvar a = []int{" + strings.Repeat("1, ", 100) + "}\n"
+	f, err := parser.ParseFile(token.NewFileSet(), "wide.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var visited int
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n != nil {
+			visited++
+			i.Replace(n)
+		}
+		return true
+	}
+
+	inspector := NewInspector(visitor, WithMaxNodes(50))
+	assert.Equal(t, f, inspector.Inspect(f))
+	assert.Equal(t, 50, visited)
+	var limitErr *NodeLimitError
+	assert.ErrorAs(t, inspector.Err(), &limitErr)
+	assert.Equal(t, 50, limitErr.MaxNodes)
+	assert.IsType(t, &ast.BasicLit{}, limitErr.Node)
+
+	// The count starts again with each walk, and a walk within the limit has no error
+	visited = 0
+	inspector = NewInspector(visitor, WithMaxNodes(200))
+	inspector.Inspect(f)
+	inspector.Inspect(f)
+	assert.NoError(t, inspector.Err())
+	assert.Equal(t, 2*(8+100), visited)
+
+	visited = 0
+	inspector = NewInspector(visitor, WithMaxNodes(50))
+	InspectIterative(inspector, f)
+	assert.Equal(t, 50, visited)
+	assert.ErrorAs(t, inspector.Err(), &limitErr)
+}
+
+func TestOriginal(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "original.go", "package foo\n\nvar a = b\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var log []string
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n == nil {
+			if id, ok := i.Original().(*ast.Ident); ok {
+				log = append(log, "exit "+id.Name)
+			}
+			return true
+		}
+		if id, ok := n.(*ast.Ident); ok && id.Name == "b" {
+			i.Replace(ast.NewIdent("c"))
+			assert.Same(t, id, i.Original())
+			assert.Equal(t, "c", i.Current().(*ast.Ident).Name)
+		}
+		return true
+	}
+	NewInspector(visitor).Inspect(f)
+	assert.Equal(t, []string{"exit foo", "exit a", "exit b"}, log)
+}
+
+func TestWrap(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "wrap.go", "package foo\n\nfunc Bar() {\n\ta(b)\n}\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	visitor := func(i Inspector, n ast.Node) bool {
+		switch n.(type) {
+		case *ast.CallExpr:
+			i.WrapExpr(func(x ast.Expr) ast.Expr {
+				return &ast.CallExpr{Fun: ast.NewIdent("instrument"), Args: []ast.Expr{x}}
+			})
+		case *ast.ExprStmt:
+			i.WrapStmt(func(stmt ast.Stmt) ast.Stmt {
+				return &ast.BlockStmt{List: []ast.Stmt{stmt}}
+			})
+		case *ast.Ident:
+			assert.PanicsWithValue(t, "astor: WrapStmt called for *ast.Ident, which isn't an ast.Stmt", func() {
+				i.WrapStmt(func(stmt ast.Stmt) ast.Stmt { return stmt })
+			})
+		}
+		return true
+	}
+	NewInspector(visitor).Inspect(f)
+
+	out := new(bytes.Buffer)
+	assert.NoError(t, format.Node(out, fset, f))
+	assert.Equal(t, "package foo\n\nfunc Bar() {\n\t{\n\t\tinstrument(a(b))\n\t}\n}\n", out.String())
+}
+
+func TestSkipBodies(t *testing.T) {
+	src := "package foo\n\nfunc Bar(a int) { b(func(c int) { d() }) }\n\nvar e = func(f int) { g() }\n"
+	f, err := parser.ParseFile(token.NewFileSet(), "bodies.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var names []string
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			names = append(names, id.Name)
+		}
+		return true
+	}, SkipBodies()).Inspect(f)
+	assert.Equal(t, []string{"foo", "Bar", "a", "int", "e", "f", "int"}, names)
+}
+
+func TestExportedOnly(t *testing.T) {
+	src := `package foo
+
+import "fmt"
+
+type A struct{ B int }
+
+type c int
+
+func (a *A) D(e int) { fmt.Println(e) }
+
+func (c) F() {}
+
+func g() {}
+
+var (
+	H, i = 1, 2
+	j    = 3
+)
+
+const k = 4
+`
+	f, err := parser.ParseFile(token.NewFileSet(), "exported.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var names []string
+	visitor := func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			names = append(names, id.Name)
+		}
+		return true
+	}
+	NewInspector(visitor, ExportedOnly()).Inspect(f)
+	assert.Equal(t, []string{"foo", "A", "B", "int", "a", "A", "D", "e", "int", "H", "i"}, names)
+
+	names = nil
+	InspectIterative(NewInspector(visitor, ExportedOnly()), f)
+	assert.Equal(t, []string{"foo", "A", "B", "int", "a", "A", "D", "e", "int", "H", "i"}, names)
+}
+
+func TestSkipField(t *testing.T) {
+	src := "package foo\n\nfunc Bar() {\n\tif a {\n\t\tb()\n\t} else {\n\t\tc()\n\t}\n\td()\n}\n"
+	f, err := parser.ParseFile(token.NewFileSet(), "field.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var names []string
+	visitor := func(i Inspector, n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IfStmt:
+			i.SkipField("Else")
+		case *ast.Ident:
+			names = append(names, n.Name)
+		}
+		return true
+	}
+	expected := []string{"foo", "Bar", "a", "b", "d"}
+	NewInspector(visitor).Inspect(f)
+	assert.Equal(t, expected, names)
+
+	names = nil
+	InspectIterative(NewInspector(visitor), f)
+	assert.Equal(t, expected, names)
+
+	names = nil
+	NewInspector(visitor, WithReverseOrder()).Inspect(f)
+	assert.Equal(t, []string{"d", "b", "a", "Bar", "foo"}, names)
+
+	assert.PanicsWithValue(t, `astor: SkipField called with "Else", which isn't a field of *ast.File`, func() {
+		NewInspector(func(i Inspector, n ast.Node) bool {
+			i.SkipField("Else")
+			return true
+		}).Inspect(f)
+	})
+}
+
+func TestData(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "data.go", "package foo\n\nfunc Bar() { a(); b(); c() }\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	type calledKey struct{}
+
+	// The first pass annotates the statements, and the second replaces or deletes some of them
+	first := true
+	var stmts []ast.Node
+	inspector := NewInspector(func(i Inspector, n ast.Node) bool {
+		name := calledName(n)
+		if name == "" {
+			return true
+		}
+		if first {
+			i.SetData(n, calledKey{}, name)
+			stmts = append(stmts, n)
+			return true
+		}
+		value, ok := i.Data(n, calledKey{})
+		assert.True(t, ok)
+		assert.Equal(t, name, value)
+		switch name {
+		case "a":
+			i.Replace(callStmt("d"))
+		case "b":
+			i.Delete()
+		}
+		return true
+	})
+	inspector.Inspect(f)
+	first = false
+	inspector.Inspect(f)
+
+	// Only the data for the statement left alone is kept
+	for l, stmt := range stmts {
+		_, ok := inspector.Data(stmt, calledKey{})
+		assert.Equal(t, l == 2, ok)
+	}
+	_, ok := inspector.Data(f, calledKey{})
+	assert.False(t, ok)
+}
+
+func TestInspectTyped(t *testing.T) {
+	expr, err := parser.ParseExpr("a + b")
+	assert.NoError(t, err, "Error parsing input")
+
+	visitor := func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "a" {
+			i.Replace(ast.NewIdent("c"))
+		}
+		return true
+	}
+	var result ast.Expr = InspectExpr(NewInspector(visitor), expr)
+	assert.Equal(t, "c", result.(*ast.BinaryExpr).X.(*ast.Ident).Name)
+
+	// A root expression can't be replaced with a statement
+	inspector := NewInspector(func(i Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.BinaryExpr); ok {
+			i.Replace(callStmt("d"))
+		}
+		return true
+	})
+	assert.PanicsWithValue(t, "astor: cannot replace the root node (ast.Expr) with *ast.ExprStmt", func() {
+		InspectExpr(inspector, expr)
+	})
+
+	var stmt ast.Stmt = InspectStmt(NewInspector(visitor), &ast.ExprStmt{X: ast.NewIdent("a")})
+	assert.Equal(t, "c", stmt.(*ast.ExprStmt).X.(*ast.Ident).Name)
+	var decl ast.Decl = InspectDecl(NewInspector(visitor), &ast.FuncDecl{Name: ast.NewIdent("a"), Type: &ast.FuncType{}})
+	assert.Equal(t, "c", decl.(*ast.FuncDecl).Name.Name)
+
+	// A root file may be replaced with another file
+	other := &ast.File{Name: ast.NewIdent("other")}
+	file := InspectFileNode(NewInspector(func(i Inspector, n ast.Node) bool {
+		if f, ok := n.(*ast.File); ok && f != other {
+			i.Replace(other)
+		}
+		return true
+	}), &ast.File{Name: ast.NewIdent("foo")})
+	assert.Same(t, other, file)
+	assert.Panics(t, func() {
+		InspectFileNode(NewInspector(func(i Inspector, n ast.Node) bool {
+			if _, ok := n.(*ast.File); ok {
+				i.Replace(ast.NewIdent("a"))
+			}
+			return true
+		}), other)
+	})
+}
+
+func TestOnDeclaration(t *testing.T) {
+	src := "package foo\n\nfunc Bar(a int) int {\n\tb := a\n\treturn b + c\n}\n"
+	f, err := parser.ParseFile(token.NewFileSet(), "decl.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var log []string
+	onDeclaration := func(id *ast.Ident, decl ast.Node) {
+		log = append(log, fmt.Sprintf("%s %T", id.Name, decl))
+	}
+	NewInspector(func(i Inspector, n ast.Node) bool { return true }, OnDeclaration(onDeclaration)).Inspect(f)
+	// c is unresolved and int is predeclared, so neither has a declaration
+	assert.Equal(t, []string{
+		"Bar *ast.FuncDecl",
+		"a *ast.Field",
+		"b *ast.AssignStmt",
+		"a *ast.Field",
+		"b *ast.AssignStmt",
+	}, log)
+}
@@ -0,0 +1,49 @@
+package astor
+
+import (
+	"context"
+	"go/ast"
+)
+
+// Iterate returns a channel yielding each node in the AST rooted at node (including node itself) in pre-order, so
+// they can be read with a range loop rather than a Visitor. It is read-only: the nodes can't be replaced, and nor
+// should they be changed until the channel is closed, since the walk runs in another goroutine. The channel is closed
+// once every node has been yielded.
+//
+// A consumer which may stop reading before then should use IterateContext instead, and cancel its context when it
+// does: otherwise the goroutine walking the AST is left blocked forever.
+func Iterate(node ast.Node) <-chan ast.Node {
+	return IterateContext(context.Background(), node)
+}
+
+// IterateContext is Iterate, but the walk stops, and the channel is closed, once ctx is done.
+func IterateContext(ctx context.Context, node ast.Node) <-chan ast.Node {
+	it := &iterator{ctx: ctx, nodes: make(chan ast.Node)}
+	go func() {
+		defer close(it.nodes)
+		NewInspector(it.visit).Inspect(node)
+	}()
+	return it.nodes
+}
+
+type iterator struct {
+	ctx   context.Context
+	nodes chan ast.Node
+}
+
+func (it *iterator) visit(i Inspector, n ast.Node) bool {
+	if n == nil {
+		return true
+	}
+	// select chooses at random between a consumer which is still reading and a cancelled context
+	if it.ctx.Err() != nil {
+		i.Stop()
+		return true
+	}
+	select {
+	case it.nodes <- n:
+	case <-it.ctx.Done():
+		i.Stop()
+	}
+	return true
+}
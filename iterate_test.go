@@ -0,0 +1,41 @@
+package astor
+
+import (
+	"context"
+	"go/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterate(t *testing.T) {
+	f := parseQuerySrc(t)
+
+	var names []string
+	for n := range Iterate(f) {
+		if id, ok := n.(*ast.Ident); ok {
+			names = append(names, id.Name)
+		}
+	}
+	assert.Equal(t, []string{"foo", "Bar", "a", "b", "int", "int", "a", "b"}, names)
+	assert.Equal(t, Collect(f, func(ast.Node) bool { return true }), drain(Iterate(f)))
+}
+
+func TestIterateContext(t *testing.T) {
+	f := parseQuerySrc(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	nodes := IterateContext(ctx, f)
+	assert.Equal(t, ast.Node(f), <-nodes)
+	cancel()
+	// the walk stops once the context is done, though the next node may already have been sent
+	assert.LessOrEqual(t, len(drain(nodes)), 1)
+}
+
+func drain(nodes <-chan ast.Node) []ast.Node {
+	var all []ast.Node
+	for n := range nodes {
+		all = append(all, n)
+	}
+	return all
+}
@@ -0,0 +1,176 @@
+package astor
+
+import (
+	"go/ast"
+)
+
+// InspectIterative walks the AST rooted at node like Inspect, returning the modified tree, but keeps track of the
+// nodes it is inside on the heap rather than by recursing, so the depth of the AST isn't limited by the size of the
+// goroutine's stack. The Visitor is called in the same order, and may make the same changes, as with Inspect. The
+// bookkeeping makes it slower than Inspect (see the benchmarks), so it is best kept for ASTs which may be too deep
+// for it, such as those from untrusted source.
+//
+// An Inspector not constructed by this package walks node recursively, with its own Inspect.
+func InspectIterative(i Inspector, node ast.Node) ast.Node {
+	impl, ok := i.(*inspectorImpl)
+	if !ok {
+		return i.Inspect(node)
+	}
+	defer startWalk(i)()
+	return impl.inspectIterative(node)
+}
+
+// An iterFrame holds the state of a node whose children InspectIterative is inspecting: what inspect would keep on
+// the stack while it recursed into them.
+type iterFrame struct {
+	node       ast.Node
+	original   ast.Node
+	slot       slot
+	edit       listEdit
+	outerDirty bool
+	skip       bool
+	opened     bool
+	// refs are where the node's children are held, of which the one at index ref is being inspected through cursor
+	refs   []childRef
+	ref    int
+	cursor childCursor
+}
+
+func (i *inspectorImpl) inspectIterative(root ast.Node) ast.Node {
+	node, _, f := i.enterIterative(root, i.slot)
+	if f == nil {
+		return node
+	}
+
+	stack := []*iterFrame{f}
+	i.ancestors = append(i.ancestors, f.node)
+	for {
+		f := stack[len(stack)-1]
+		s, child, ok := i.nextChild(f)
+		if ok {
+			i.depth = len(stack)
+			node, edit, g := i.enterIterative(child, s)
+			if g != nil {
+				stack = append(stack, g)
+				i.ancestors = append(i.ancestors, g.node)
+			} else {
+				i.visitedFileList(child, node, edit)
+				f.cursor.store(node, edit)
+			}
+			continue
+		}
+
+		stack = stack[:len(stack)-1]
+		i.ancestors = i.ancestors[:len(i.ancestors)-1]
+		i.depth = len(stack)
+		node, edit := i.exitIterative(f)
+		i.visitedFileList(f.original, node, edit)
+		if len(stack) == 0 {
+			return node
+		}
+		stack[len(stack)-1].cursor.store(node, edit)
+	}
+}
+
+// enterIterative is the first half of inspect: it visits original, held in s, and returns a frame for inspecting its
+// children. If its children aren't to be inspected, there is no frame and the node's replacement and edits are
+// returned instead.
+func (i *inspectorImpl) enterIterative(original ast.Node, s slot) (ast.Node, listEdit, *iterFrame) {
+	i.slot = s
+	if r, ok := i.fileLists[original]; ok && r.visited {
+		node, edit := r.in(s)
+		return node, edit, nil
+	}
+	if i.stopped || original == nil || i.unvisited[original] || i.shared(original) {
+		return original, listEdit{}, nil
+	}
+	if i.excluded(original) {
+		return original, listEdit{}, nil
+	}
+	if i.overLimit(original) {
+		return original, listEdit{}, nil
+	}
+
+	outerDirty := i.dirty
+	i.edit, i.skip, i.skipFields, i.dirty = listEdit{}, false, nil, false
+	node, ii := i.Visit(original)
+	edit, skip := i.edit, i.skip
+	if i.cmap != nil && node != original {
+		i.moveComments(original, node)
+	}
+	if ii == nil || edit.deleted || i.stopped || node == nil {
+		if i.scopes && !edit.deleted && node != nil {
+			i.declare(node)
+		}
+		i.dirty = outerDirty || i.dirty
+		return node, edit, nil
+	}
+
+	f := &iterFrame{node: node, original: original, slot: i.slot, edit: edit, outerDirty: outerDirty, skip: skip}
+	if !skip {
+		f.opened = i.scopes && i.openScope(node)
+		if i.cmap != nil && i.reverse {
+			i.inspectComments(i, node)
+		}
+		outerSkipping := i.skipping
+		i.skipping = i.skipFields
+		f.refs = i.children(node)
+		i.skipping = outerSkipping
+	}
+	return node, edit, f
+}
+
+// nextChild returns the slot and node of the next child of f to be inspected, or false if there are no more.
+func (i *inspectorImpl) nextChild(f *iterFrame) (slot, ast.Node, bool) {
+	for {
+		if f.cursor == nil {
+			if f.ref == len(f.refs) {
+				return slot{}, nil, false
+			}
+			ref := f.refs[f.ref]
+			if i.reverse {
+				ref = f.refs[len(f.refs)-1-f.ref]
+			}
+			f.cursor = ref.cursor(i.reverse)
+			if c, ok := f.cursor.(hookedCursor); ok {
+				c.hook(i)
+			}
+		}
+
+		if s, child, ok := f.cursor.next(); ok {
+			return s, child, true
+		}
+		f.cursor.finish()
+		f.cursor = nil
+		f.ref++
+	}
+}
+
+// exitIterative is the second half of inspect, once the children of f have been inspected: it returns the node's
+// replacement and edits.
+func (i *inspectorImpl) exitIterative(f *iterFrame) (ast.Node, listEdit) {
+	if !f.skip {
+		if i.cmap != nil && !i.reverse {
+			i.inspectComments(i, f.node)
+		}
+		if f.opened {
+			i.scope = i.scope.Outer
+		}
+	}
+	i.slot, i.edit, i.original = f.slot, f.edit, f.original
+	if i.stopped {
+		i.dirty = f.outerDirty || i.dirty
+		return f.node, i.edit
+	}
+	if r := i.finish(i, f.node); r != f.node {
+		if i.cmap != nil {
+			i.moveComments(f.node, r)
+		}
+		f.node = r
+	}
+	if i.scopes && f.node != nil {
+		i.declare(f.node)
+	}
+	i.dirty = f.outerDirty || i.dirty
+	return f.node, i.edit
+}
@@ -0,0 +1,103 @@
+package astor
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// walkLog walks the file in infile with an Inspector constructed with opts, using walk, and returns the order its
+// nodes were visited in and the modified source.
+func walkLog(t *testing.T, infile string, walk func(Inspector, ast.Node) ast.Node, opts ...Option) ([]string, string) {
+	src, err := ioutil.ReadFile(infile)
+	assert.NoError(t, err, "Error reading input")
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, infile, src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var log []string
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n == nil {
+			log = append(log, "exit")
+			return true
+		}
+		log = append(log, fmt.Sprintf("%T %T[%d]", n, i.Parent(), i.Index()))
+		if _, ok := n.(*ast.IfStmt); ok {
+			i.SkipChildren()
+		}
+		return deleteAndInsertVisitor(i, n)
+	}
+	result := walk(NewInspector(visitor, opts...), f)
+
+	out := new(bytes.Buffer)
+	assert.NoError(t, format.Node(out, fset, result), "Error formatting output AST")
+	return log, out.String()
+}
+
+func TestInspectIterative(t *testing.T) {
+	inspect := func(i Inspector, n ast.Node) ast.Node { return i.Inspect(n) }
+	for _, infile := range []string{
+		"test-samples/delete-and-insert.go.in",
+		"test-samples/remove-empty.go.in",
+		"test-samples/revisit-replacement.go.in",
+	} {
+		for _, opts := range [][]Option{nil, {WithReverseOrder()}, {WithScopes(), WithNodeIDs()}} {
+			expectedLog, expectedOut := walkLog(t, infile, inspect, opts...)
+			log, out := walkLog(t, infile, InspectIterative, opts...)
+			assert.Equal(t, expectedLog, log, infile)
+			assert.Equal(t, expectedOut, out, infile)
+		}
+	}
+}
+
+func TestInspectIterativeDeep(t *testing.T) {
+	src := "package foo\n\nvar a = " + strings.Repeat("(", 10000) + "b" + strings.Repeat(")", 10000) + "\n"
+	f, err := parser.ParseFile(token.NewFileSet(), "deep.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	visitor := func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "b" {
+			i.Replace(ast.NewIdent("c"))
+		}
+		return true
+	}
+	InspectIterative(NewInspector(visitor), f)
+	var x ast.Expr = f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0]
+	for p, ok := x.(*ast.ParenExpr); ok; p, ok = x.(*ast.ParenExpr) {
+		x = p.X
+	}
+	assert.Equal(t, "c", x.(*ast.Ident).Name)
+
+	inspector := NewInspector(visitor, WithMaxDepth(100))
+	InspectIterative(inspector, f)
+	assert.IsType(t, &DepthError{}, inspector.Err())
+}
+
+func benchmarkInspect(b *testing.B, walk func(Inspector, ast.Node) ast.Node) {
+	src, err := ioutil.ReadFile("inspect.go")
+	assert.NoError(b, err, "Error reading input")
+	f, err := parser.ParseFile(token.NewFileSet(), "inspect.go", src, parserFlags)
+	assert.NoError(b, err, "Error parsing input")
+	i := NewInspector(func(i Inspector, n ast.Node) bool { return true })
+
+	b.ResetTimer()
+	for l := 0; l < b.N; l++ {
+		walk(i, f)
+	}
+}
+
+func BenchmarkInspect(b *testing.B) {
+	benchmarkInspect(b, func(i Inspector, n ast.Node) ast.Node { return i.Inspect(n) })
+}
+
+func BenchmarkInspectIterative(b *testing.B) {
+	benchmarkInspect(b, InspectIterative)
+}
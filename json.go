@@ -0,0 +1,110 @@
+package astor
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"reflect"
+)
+
+var tokenType = reflect.TypeOf(token.ILLEGAL)
+
+// MarshalJSON returns the AST rooted at node, as the Inspector walks it, encoded as JSON. Each node is an object
+// holding its type (without the package) as "Node", its positions as "Pos" and "End", and its fields: those holding
+// strings, booleans, tokens (as their text) and other numbers as they are, and those holding child nodes as their
+// objects, or arrays of them. Fields holding no child nodes, or an empty list of them, are left out, as are other
+// positions (such as the Lparen of a call) and the *ast.Object and *ast.Scope of resolved identifiers. The files of an
+// *ast.Package are an object keyed by file name. Keys are written in sorted order, so the encoding of an AST is stable.
+//
+// Positions are resolved through fset, as the fields of a token.Position, if it isn't nil; otherwise they are
+// written as the token.Pos. Invalid positions are left out.
+func MarshalJSON(node ast.Node, fset *token.FileSet) ([]byte, error) {
+	m := &jsonMarshaler{fset: fset}
+	NewInspector(m.visit).Inspect(node)
+	return json.Marshal(m.root)
+}
+
+type jsonMarshaler struct {
+	fset *token.FileSet
+	root map[string]interface{}
+	// stack holds the objects of the node being visited and its ancestors
+	stack []map[string]interface{}
+}
+
+func (m *jsonMarshaler) visit(i Inspector, n ast.Node) bool {
+	if n == nil {
+		m.stack = m.stack[:len(m.stack)-1]
+		return true
+	}
+
+	obj := m.object(n)
+	if len(m.stack) == 0 {
+		m.root = obj
+	} else {
+		parent, s := m.stack[len(m.stack)-1], i.(*inspectorImpl).slot
+		switch {
+		case s.index >= 0:
+			list, _ := parent[s.field].([]interface{})
+			parent[s.field] = append(list, obj)
+		case s.field == "Files":
+			files, _ := parent[s.field].(map[string]interface{})
+			if files == nil {
+				files = map[string]interface{}{}
+				parent[s.field] = files
+			}
+			for name, f := range s.parent.(*ast.Package).Files {
+				if ast.Node(f) == n {
+					files[name] = obj
+				}
+			}
+		default:
+			parent[s.field] = obj
+		}
+	}
+	m.stack = append(m.stack, obj)
+	return true
+}
+
+// object returns the object for n, holding all but its child nodes.
+func (m *jsonMarshaler) object(n ast.Node) map[string]interface{} {
+	obj := map[string]interface{}{"Node": reflect.TypeOf(n).Elem().Name()}
+	m.position(obj, "Pos", n.Pos())
+	m.position(obj, "End", n.End())
+	scalarFields(n, func(name string, value interface{}) {
+		if tok, ok := value.(token.Token); ok {
+			value = tok.String()
+		}
+		obj[name] = value
+	})
+	return obj
+}
+
+// scalarFields calls f with the name and value of each of the fields of n holding a string, boolean, token or other
+// number, other than positions, in the order they are declared.
+func scalarFields(n ast.Node, f func(name string, value interface{})) {
+	v := reflect.ValueOf(n).Elem()
+	for l := 0; l < v.NumField(); l++ {
+		field := v.Field(l)
+		switch name := v.Type().Field(l).Name; {
+		case field.Type() == posType:
+		case field.Type() == tokenType:
+			f(name, field.Interface())
+		case field.Kind() == reflect.String:
+			f(name, field.String())
+		case field.Kind() == reflect.Bool:
+			f(name, field.Bool())
+		case field.CanInt():
+			f(name, field.Int())
+		}
+	}
+}
+
+func (m *jsonMarshaler) position(obj map[string]interface{}, key string, pos token.Pos) {
+	switch {
+	case !pos.IsValid():
+	case m.fset != nil:
+		obj[key] = m.fset.Position(pos)
+	default:
+		obj[key] = pos
+	}
+}
@@ -0,0 +1,63 @@
+package astor
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	x, err := parser.ParseExpr(`a(b, "c")`)
+	assert.NoError(t, err, "Error parsing input")
+
+	out, err := MarshalJSON(x, nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"Node": "CallExpr", "Pos": 1, "End": 10,
+		"Fun": {"Node": "Ident", "Pos": 1, "End": 2, "Name": "a"},
+		"Args": [
+			{"Node": "Ident", "Pos": 3, "End": 4, "Name": "b"},
+			{"Node": "BasicLit", "Pos": 6, "End": 9, "Kind": "STRING", "Value": "\"c\""}
+		]
+	}`, string(out))
+
+	// Positions are resolved through the FileSet
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "json.go", "package foo\n\nvar a = -b\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	out, err = MarshalJSON(f, fset)
+	assert.NoError(t, err)
+	var decoded struct {
+		Name  struct{ Name string }
+		Decls []struct {
+			Node  string
+			Tok   string
+			Specs []struct {
+				Values []struct {
+					Op  string
+					Pos token.Position
+				}
+			}
+		}
+	}
+	assert.NoError(t, json.Unmarshal(out, &decoded))
+	assert.Equal(t, "foo", decoded.Name.Name)
+	assert.Equal(t, "GenDecl", decoded.Decls[0].Node)
+	assert.Equal(t, "var", decoded.Decls[0].Tok)
+	assert.Equal(t, "-", decoded.Decls[0].Specs[0].Values[0].Op)
+	assert.Equal(t, token.Position{Filename: "json.go", Offset: 21, Line: 3, Column: 9},
+		decoded.Decls[0].Specs[0].Values[0].Pos)
+
+	// The files of a package are keyed by name
+	out, err = MarshalJSON(&ast.Package{Name: "foo", Files: map[string]*ast.File{"json.go": f}}, nil)
+	assert.NoError(t, err)
+	var pkg struct {
+		Files map[string]struct{ Node string }
+	}
+	assert.NoError(t, json.Unmarshal(out, &pkg))
+	assert.Equal(t, "File", pkg.Files["json.go"].Node)
+}
@@ -0,0 +1,126 @@
+package astor
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// Labels returns the labeled statements in the AST rooted at node, by label. Labels are scoped to the function
+// declaring them, so those of the functions declared in node, and of its function literals (other than node itself),
+// aren't included. If a label is declared more than once, as it may be in an invalid AST, the first is returned.
+func Labels(node ast.Node) map[string]*ast.LabeledStmt {
+	labels := map[string]*ast.LabeledStmt{}
+	walkFuncScope(node, func(n ast.Node, enclosing []ast.Node) {
+		if l, ok := n.(*ast.LabeledStmt); ok && labels[l.Label.Name] == nil {
+			labels[l.Label.Name] = l
+		}
+	})
+	return labels
+}
+
+// LabelRefs returns the branch statements (goto, break and continue) in the AST rooted at node which refer to a
+// label, by label, scoped as for Labels. Along with Labels, this lets a Visitor moving or deleting a statement check
+// that it isn't separating a label from the statements referring to it: those in LabelRefs of the enclosing function
+// and not in the statement, for each label in Labels of the statement.
+func LabelRefs(node ast.Node) map[string][]*ast.BranchStmt {
+	refs := map[string][]*ast.BranchStmt{}
+	walkFuncScope(node, func(n ast.Node, enclosing []ast.Node) {
+		if b, ok := n.(*ast.BranchStmt); ok && b.Label != nil {
+			refs[b.Label.Name] = append(refs[b.Label.Name], b)
+		}
+	})
+	return refs
+}
+
+// CheckLabels checks the labels and branch statements in the AST rooted at node, function by function, returning a
+// *ValidationError for each of these problems the compiler would reject:
+//
+//   - a label declared more than once in a function, or never referred to
+//   - a branch statement referring to a label not declared in its function
+//   - a break referring to a label which isn't of an enclosing for, switch or select statement, or a continue
+//     referring to one which isn't of an enclosing for statement
+//
+// A node which isn't (or isn't in) a function is checked as though it were the body of one. The rules about jumping
+// over variable declarations and into blocks with goto aren't checked.
+func CheckLabels(node ast.Node) []error {
+	v := &validator{}
+	funcs := []ast.Node{node}
+	for len(funcs) > 0 {
+		fn := funcs[0]
+		funcs = funcs[1:]
+		declared, labels, refs := map[string]bool{}, Labels(fn), LabelRefs(fn)
+		walkFuncScope(fn, func(n ast.Node, enclosing []ast.Node) {
+			switch n := n.(type) {
+			case *ast.FuncDecl, *ast.FuncLit:
+				if n != fn {
+					funcs = append(funcs, n)
+				}
+			case *ast.LabeledStmt:
+				name := n.Label.Name
+				switch {
+				case declared[name]:
+					v.fail(n, "label %s already declared", name)
+				case len(refs[name]) == 0:
+					v.fail(n, "label %s declared and not used", name)
+				}
+				declared[name] = true
+			case *ast.BranchStmt:
+				if n.Label != nil {
+					checkBranch(v, n, labels[n.Label.Name], enclosing)
+				}
+			}
+		})
+	}
+	return v.errs
+}
+
+// checkBranch checks that the branch statement b, enclosed by the nodes enclosing, can refer to target, the
+// statement with its label in its function.
+func checkBranch(v *validator, b *ast.BranchStmt, target *ast.LabeledStmt, enclosing []ast.Node) {
+	name := b.Label.Name
+	if target == nil {
+		v.fail(b, "label %s not defined", name)
+		return
+	}
+	if b.Tok == token.GOTO {
+		return
+	}
+
+	encloses := false
+	for _, n := range enclosing {
+		encloses = encloses || n == target
+	}
+	switch target.Stmt.(type) {
+	case *ast.ForStmt, *ast.RangeStmt:
+	case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+		if b.Tok == token.CONTINUE {
+			encloses = false
+		}
+	default:
+		encloses = false
+	}
+	if !encloses {
+		v.fail(b, "invalid %s label %s", b.Tok, name)
+	}
+}
+
+// walkFuncScope calls f with each node in the AST rooted at node, other than those within the function declarations
+// and literals in it (other than node itself), along with the nodes enclosing it.
+func walkFuncScope(node ast.Node, f func(n ast.Node, enclosing []ast.Node)) {
+	var stack []ast.Node
+	Walk(node, func(i Inspector, n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		f(n, stack)
+		switch n.(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			if n != node {
+				return false
+			}
+		}
+		stack = append(stack, n)
+		return true
+	})
+}
@@ -0,0 +1,107 @@
+package astor
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const labelsSrc = `package foo
+
+func Bar() {
+outer:
+	for {
+		switch {
+		case true:
+			break outer
+		case false:
+			continue outer
+		}
+	}
+	goto end
+end:
+	func() {
+	inner:
+		for {
+			break inner
+		}
+	}()
+}
+`
+
+func TestLabels(t *testing.T) {
+	f := parseEqualSrc(t, labelsSrc)
+	fd := f.Decls[0].(*ast.FuncDecl)
+
+	labels := Labels(fd)
+	assert.Len(t, labels, 2, "The labels of the function literal aren't included")
+	assert.IsType(t, &ast.ForStmt{}, labels["outer"].Stmt)
+	assert.IsType(t, &ast.ExprStmt{}, labels["end"].Stmt)
+
+	refs := LabelRefs(fd)
+	assert.Len(t, refs["outer"], 2)
+	assert.Len(t, refs["end"], 1)
+	assert.Nil(t, refs["inner"])
+
+	assert.Empty(t, CheckLabels(f))
+}
+
+func TestCheckLabels(t *testing.T) {
+	f := parseEqualSrc(t, `package foo
+
+func Bar() {
+unused:
+	for {
+	}
+sw:
+	switch {
+	default:
+		continue sw
+	}
+blk:
+	{
+		break blk
+	}
+	goto missing
+	func() {
+		goto blk
+	}()
+dup:
+	goto dup
+dup:
+	for {
+		break dup
+	}
+}
+`)
+	var reasons []string
+	for _, err := range CheckLabels(f) {
+		reasons = append(reasons, err.(*ValidationError).Reason)
+	}
+	assert.Equal(t, []string{
+		"label unused declared and not used",
+		"invalid continue label sw",
+		"invalid break label blk",
+		"label missing not defined",
+		"label dup already declared",
+		"invalid break label dup",
+		"label blk not defined",
+	}, reasons)
+}
+
+func TestCheckLabelsAcrossFuncs(t *testing.T) {
+	// each declared function has its own labels
+	f := parseEqualSrc(t, "package foo\n\nfunc A() {\nL:\n\tfor {\n\t\tbreak L\n\t}\n}\n\n"+
+		"func B() {\nL:\n\tfor {\n\t\tbreak L\n\t}\n}\n")
+	assert.Empty(t, CheckLabels(f))
+	assert.Len(t, Labels(f), 0, "The labels of the declared functions aren't included")
+
+	// so a goto can't jump into another
+	f = parseEqualSrc(t, "package foo\n\nfunc A() {\n\tgoto M\n}\n\nfunc B() {\nM:\n\tfor {\n\t}\n}\n")
+	var reasons []string
+	for _, err := range CheckLabels(f) {
+		reasons = append(reasons, err.(*ValidationError).Reason)
+	}
+	assert.Equal(t, []string{"label M not defined", "label M declared and not used"}, reasons)
+}
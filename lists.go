@@ -0,0 +1,82 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+)
+
+// A ListHook is called by an Inspector with a slice of nodes it walks, such as the List of an *ast.BlockStmt (a
+// []ast.Stmt) or the Specs of an *ast.GenDecl (a []ast.Spec), along with the node holding it and the name of its field.
+// It returns a slice of the same type to replace it with, or nil to leave it as it is.
+type ListHook func(parent ast.Node, field string, items interface{}) interface{}
+
+// OnEnterList registers a function to be called with each slice of nodes the Inspector walks, before any of its
+// elements are visited, so the slice can be changed as a whole: sorted, or filtered, or added to. The elements of the
+// slice it returns are the ones visited, and what the slice holds once they have been. Each slice is walked, and so
+// passed to the function, even if it's empty. Functions are called in the order they were registered, each with the
+// slice returned by the one before.
+//
+// Like the other changes made by a walk, those made by the functions aren't made by InspectDryRun. Neither they nor
+// the OnExitList functions are called once the walk is stopped, nor for the slices of a node only part of which is
+// walked, as by InspectFrom.
+func OnEnterList(f ListHook) Option {
+	return func(i *inspectorImpl) {
+		i.onEnterList = append(i.onEnterList, f)
+	}
+}
+
+// OnExitList registers a function to be called with each slice of nodes the Inspector walks, once all its elements
+// have been visited (and so replaced, deleted and inserted alongside), as for OnEnterList.
+func OnExitList(f ListHook) Option {
+	return func(i *inspectorImpl) {
+		i.onExitList = append(i.onExitList, f)
+	}
+}
+
+// enterList calls the OnEnterList functions with the list held in the named field of parent, storing the list they
+// return.
+func enterList[T ast.Node](i *inspectorImpl, parent ast.Node, field string, ptr *[]T) {
+	if len(i.onEnterList) == 0 || i.stopped {
+		return
+	}
+	if list, changed := i.callListHooks(i.onEnterList, "OnEnterList", parent, field, *ptr); changed {
+		*ptr = list.([]T)
+	}
+}
+
+// exitList calls the OnExitList functions with the list held in the named field of parent, once it has been walked,
+// storing the list they return.
+func exitList[T ast.Node](i *inspectorImpl, parent ast.Node, field string, ptr *[]T) {
+	if len(i.onExitList) == 0 || i.stopped {
+		return
+	}
+	if list, changed := i.callListHooks(i.onExitList, "OnExitList", parent, field, *ptr); changed {
+		*ptr = list.([]T)
+	}
+}
+
+// callListHooks calls hooks, the functions registered with the named Option, with list, returning the list they
+// return and whether any of them changed it.
+func (i *inspectorImpl) callListHooks(hooks []ListHook, name string, parent ast.Node, field string,
+	list interface{}) (interface{}, bool) {
+	changed := false
+	for _, f := range hooks {
+		r := f(parent, field, list)
+		if r == nil || i.dryRun {
+			continue
+		}
+		if reflect.TypeOf(r) != reflect.TypeOf(list) {
+			panic(fmt.Sprintf("astor: %s function returned a %T for %T.%s, which holds a %T", name, r, parent, field,
+				list))
+		}
+		if i.readOnly {
+			panic(fmt.Sprintf("astor: %s function changed %T.%s with a read-only Inspector", name, parent, field))
+		}
+		list, changed = r, true
+	}
+	if changed {
+		i.dirty = true
+	}
+	return list, changed
+}
@@ -0,0 +1,101 @@
+package astor
+
+import (
+	"go/ast"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const listsSrc = `package foo
+
+import (
+	"os"
+	"io"
+	"os"
+)
+
+func Bar() {
+	c()
+	a()
+	b()
+}
+`
+
+func TestListHooks(t *testing.T) {
+	// sorts the statements of each block as it's entered, by the name of the function they call
+	sortStmts := func(parent ast.Node, field string, items interface{}) interface{} {
+		stmts, ok := items.([]ast.Stmt)
+		if !ok {
+			return nil
+		}
+		sorted := append([]ast.Stmt{}, stmts...)
+		sort.Slice(sorted, func(x, y int) bool { return calledName(sorted[x]) < calledName(sorted[y]) })
+		return sorted
+	}
+	// removes duplicate imports once they've been visited
+	var lists []string
+	dedupe := func(parent ast.Node, field string, items interface{}) interface{} {
+		lists = append(lists, field)
+		specs, ok := items.([]ast.Spec)
+		if !ok {
+			return nil
+		}
+		seen := map[string]bool{}
+		var deduped []ast.Spec
+		for _, s := range specs {
+			if s, ok := s.(*ast.ImportSpec); ok && !seen[s.Path.Value] {
+				seen[s.Path.Value] = true
+				deduped = append(deduped, s)
+			}
+		}
+		return deduped
+	}
+
+	for _, iterative := range []bool{false, true} {
+		lists = nil
+		var called []string
+		i := NewInspector(func(i Inspector, n ast.Node) bool {
+			if name := calledName(n); name != "" {
+				called = append(called, name)
+			}
+			return true
+		}, OnEnterList(sortStmts), OnExitList(dedupe))
+
+		f := parseEqualSrc(t, listsSrc)
+		if iterative {
+			InspectIterative(i, f)
+		} else {
+			i.Inspect(f)
+		}
+		assert.Empty(t, Diff(parseEqualSrc(t, `package foo
+
+import (
+	"os"
+	"io"
+)
+
+func Bar() {
+	a()
+	b()
+	c()
+}
+`), f))
+		assert.Equal(t, []string{"a", "b", "c"}, called, "The sorted statements are visited in order")
+		assert.Equal(t, []string{"Specs", "List", "Args", "Args", "Args", "List", "Decls"}, lists)
+	}
+
+	// the changes aren't made by a dry run
+	f := parseEqualSrc(t, listsSrc)
+	assert.Empty(t, InspectDryRun(NewInspector(func(Inspector, ast.Node) bool { return true }, OnEnterList(sortStmts)), f))
+	assert.Empty(t, Diff(parseEqualSrc(t, listsSrc), f))
+
+	wrongType := func(parent ast.Node, field string, items interface{}) interface{} {
+		return []ast.Node{}
+	}
+	assert.PanicsWithValue(t, "astor: OnExitList function returned a []ast.Node for *ast.File.Decls, which holds a []ast.Decl",
+		func() {
+			NewInspector(func(Inspector, ast.Node) bool { return true }, OnExitList(wrongType)).Inspect(&ast.File{})
+		})
+}
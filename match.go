@@ -0,0 +1,91 @@
+package astor
+
+import (
+	"go/ast"
+	"reflect"
+)
+
+// A Binding is a match for a pattern found by Match: the node which matches, and the subtree captured by each of the
+// pattern's wildcards.
+type Binding struct {
+	Node      ast.Node
+	Wildcards map[string]ast.Node
+}
+
+// Match returns a Binding for each node in the AST rooted at node (including node itself) which matches pattern, in
+// pre-order. A node matches if it is structurally the same as pattern, as for Equal, except that each of pattern's
+// wildcards matches any subtree. Comments are ignored too. Matches may overlap, where one contains another.
+//
+// The wildcards are the identifiers in pattern whose names are keys in wildcards. The value for a key may be nil,
+// for every identifier with that name, or a particular *ast.Ident in pattern. A wildcard may appear more than once in
+// pattern, in which case the subtrees it matches must be Equal, as with gofmt -r.
+func Match(node ast.Node, pattern ast.Node, wildcards map[string]*ast.Ident) []Binding {
+	m := &matcher{pattern: pattern, wildcards: wildcards}
+	NewInspector(m.visit).Inspect(node)
+	return m.bindings
+}
+
+type matcher struct {
+	pattern   ast.Node
+	wildcards map[string]*ast.Ident
+	bindings  []Binding
+}
+
+func (m *matcher) visit(i Inspector, n ast.Node) bool {
+	if n == nil {
+		return true
+	}
+	if captured, ok := matchPattern(m.pattern, n, m.wildcards); ok {
+		m.bindings = append(m.bindings, Binding{Node: n, Wildcards: captured})
+	}
+	return true
+}
+
+// matchPattern reports whether n matches pattern, returning the subtrees captured by its wildcards if it does.
+func matchPattern(pattern, n ast.Node, wildcards map[string]*ast.Ident) (map[string]ast.Node, bool) {
+	captured := make(map[string]ast.Node)
+	d := differ{stopAtFirst: true, ignoreComments: true}
+	d.wildcard = func(path string, a, b reflect.Value) bool {
+		id := wildcardIdent(a, wildcards)
+		if id == nil {
+			return false
+		}
+		if b.Kind() == reflect.Interface || b.Kind() == reflect.Ptr {
+			if b.IsNil() {
+				d.differ(path, id, nil)
+				return true
+			}
+		}
+		node, ok := b.Interface().(ast.Node)
+		if !ok {
+			d.differ(path, id, b.Interface())
+			return true
+		}
+		if prev, ok := captured[id.Name]; !ok {
+			captured[id.Name] = node
+		} else if !Equal(prev, node) {
+			d.differ(path, prev, node)
+		}
+		return true
+	}
+	d.compare("", reflect.ValueOf(&pattern).Elem(), reflect.ValueOf(&n).Elem())
+	if len(d.diffs) > 0 {
+		return nil, false
+	}
+	return captured, true
+}
+
+// wildcardIdent returns the identifier held by v, if it is one of wildcards, or nil.
+func wildcardIdent(v reflect.Value, wildcards map[string]*ast.Ident) *ast.Ident {
+	if (v.Kind() != reflect.Interface && v.Kind() != reflect.Ptr) || v.IsNil() {
+		return nil
+	}
+	id, ok := v.Interface().(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	if w, ok := wildcards[id.Name]; ok && (w == nil || w == id) {
+		return id
+	}
+	return nil
+}
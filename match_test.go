@@ -0,0 +1,39 @@
+package astor
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	src := "package foo\n\nfunc Bar() {\n\ta = append(a, b)\n\tc = append(d, e)\n\tf = append(f, g(h))\n}\n"
+	f, err := parser.ParseFile(token.NewFileSet(), "match.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	// Statements can't be parsed on their own, so the pattern comes from a function
+	p, err := parser.ParseFile(token.NewFileSet(), "pattern.go", "package p\n\nfunc _() { x = append(x, y) }\n", 0)
+	assert.NoError(t, err, "Error parsing pattern")
+	pattern := p.Decls[0].(*ast.FuncDecl).Body.List[0]
+
+	bindings := Match(f, pattern, map[string]*ast.Ident{"x": nil, "y": nil})
+	assert.Len(t, bindings, 2)
+	var matched []string
+	for _, b := range bindings {
+		matched = append(matched, types.ExprString(b.Wildcards["x"].(ast.Expr))+" "+
+			types.ExprString(b.Wildcards["y"].(ast.Expr)))
+	}
+	assert.Equal(t, []string{"a b", "f g(h)"}, matched)
+
+	// Without wildcards, the pattern only matches itself
+	call, err := parser.ParseExpr("append(d, e)")
+	assert.NoError(t, err, "Error parsing pattern")
+	bindings = Match(f, call, nil)
+	assert.Len(t, bindings, 1)
+	assert.Empty(t, bindings[0].Wildcards)
+	assert.Equal(t, "append(d, e)", types.ExprString(bindings[0].Node.(ast.Expr)))
+}
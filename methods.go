@@ -0,0 +1,46 @@
+package astor
+
+import (
+	"go/ast"
+)
+
+// IsMethod reports whether fd declares a method, rather than a function: whether it has a receiver.
+func IsMethod(fd *ast.FuncDecl) bool {
+	return fd.Recv != nil && len(fd.Recv.List) > 0
+}
+
+// ReceiverType returns the name of the type of the receiver of the method fd declares, such as T for a receiver of
+// type T, *T, (*T) or *T[K, V], and whether the receiver is a pointer. ok is false if fd isn't a method, or its
+// receiver isn't a (possibly generic) type name or a pointer to one, as can happen in an invalid AST. The receiver's
+// own name, if it has one, is in fd.Recv.List[0].Names.
+func ReceiverType(fd *ast.FuncDecl) (name string, ptr bool, ok bool) {
+	if !IsMethod(fd) {
+		return "", false, false
+	}
+
+	typ := unparen(fd.Recv.List[0].Type)
+	if star, isStar := typ.(*ast.StarExpr); isStar {
+		typ, ptr = unparen(star.X), true
+	}
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		typ = t.X
+	case *ast.IndexListExpr:
+		typ = t.X
+	}
+	if id, isIdent := typ.(*ast.Ident); isIdent {
+		return id.Name, ptr, true
+	}
+	return "", false, false
+}
+
+// unparen returns x without any parentheses around it.
+func unparen(x ast.Expr) ast.Expr {
+	for {
+		p, ok := x.(*ast.ParenExpr)
+		if !ok {
+			return x
+		}
+		x = p.X
+	}
+}
@@ -0,0 +1,102 @@
+package astor
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+func TestReceiverType(t *testing.T) {
+	f := parseEqualSrc(t, `package foo
+
+func A() {}
+
+func (T) B() {}
+
+func (t *T) C() {}
+
+func (t (*T)) D() {}
+
+func (l *List[E]) E() {}
+
+func (m Map[K, V]) F() {}
+`)
+
+	type receiver struct {
+		name   string
+		ptr    bool
+		ok     bool
+		method bool
+	}
+	var receivers []receiver
+	for _, d := range f.Decls {
+		fd := d.(*ast.FuncDecl)
+		name, ptr, ok := ReceiverType(fd)
+		receivers = append(receivers, receiver{name: name, ptr: ptr, ok: ok, method: IsMethod(fd)})
+	}
+	assert.Equal(t, []receiver{
+		{},
+		{name: "T", ok: true, method: true},
+		{name: "T", ptr: true, ok: true, method: true},
+		{name: "T", ptr: true, ok: true, method: true},
+		{name: "List", ptr: true, ok: true, method: true},
+		{name: "Map", ok: true, method: true},
+	}, receivers)
+
+	// an invalid receiver
+	fd := &ast.FuncDecl{Recv: &ast.FieldList{List: []*ast.Field{{Type: &ast.ArrayType{Elt: ast.NewIdent("T")}}}}}
+	assert.True(t, IsMethod(fd))
+	_, _, ok := ReceiverType(fd)
+	assert.False(t, ok)
+}
+
+func TestEnclosingFunc(t *testing.T) {
+	f := parseEqualSrc(t, `package foo
+
+var x = y
+
+func A() int {
+	return int(func() int8 { return 1 }())
+}
+
+func B() string { return 2 }
+`)
+
+	enclosing := map[string]string{}
+	name := func(n ast.Node) string {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			return n.Name.Name
+		case *ast.FuncLit:
+			return "lit"
+		}
+		return ""
+	}
+	visitor := func(i Inspector, n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			enclosing[n.Name] = name(i.EnclosingFunc())
+		case *ast.BasicLit:
+			enclosing[n.Value] = name(i.EnclosingFunc())
+		case *ast.FuncLit:
+			enclosing["lit"] = name(i.EnclosingFunc())
+		case *ast.FuncDecl:
+			assert.Nil(t, i.EnclosingFunc())
+		}
+		return true
+	}
+	expected := map[string]string{
+		"foo": "", "x": "", "y": "",
+		"A": "A", "int": "A", "lit": "A", "int8": "lit", "1": "lit",
+		"B": "B", "string": "B", "2": "B",
+	}
+	NewInspector(visitor).Inspect(f)
+	assert.Equal(t, expected, enclosing)
+
+	enclosing = map[string]string{}
+	pre, post := ApplyFuncs(visitor)
+	astutil.Apply(f, pre, post)
+	assert.Equal(t, expected, enclosing)
+}
@@ -0,0 +1,125 @@
+package astor
+
+import (
+	"go/ast"
+	"go/build"
+	"go/token"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InspectPackage walks pkg like Inspect, returning the modified tree, but visits its files in the order they were
+// added to fset (for a package from parser.ParseDir, the order they were parsed in). Files which aren't in fset, or
+// all files if fset is nil, are visited in order of their names, which is also the order Inspect uses.
+func InspectPackage(i Inspector, pkg *ast.Package, fset *token.FileSet) ast.Node {
+	return inspectPackageFiles(i, pkg, packageFileNames(pkg, fset))
+}
+
+// InspectPackageForContext walks pkg like Inspect, returning the modified tree, but only visits the files which would
+// be built in ctx: those whose name has no non-matching _GOOS/_GOARCH suffix, whose //go:build (or, failing that,
+// // +build) constraints are satisfied, and which don't import "C" unless ctx.CgoEnabled is set. Matching files are
+// visited in order of their names.
+func InspectPackageForContext(i Inspector, pkg *ast.Package, ctx build.Context) ast.Node {
+	var names []string
+	for _, name := range packageFileNames(pkg, nil) {
+		f := pkg.Files[name]
+		// the file has already been parsed, so MatchFile is given its header, from the AST, rather than reading it
+		ctx.OpenFile = func(string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(fileHeader(pkg.Name, f))), nil
+		}
+		if ok, err := ctx.MatchFile(filepath.Dir(name), filepath.Base(name)); err != nil || !ok {
+			continue
+		}
+		if f != nil && !ctx.CgoEnabled && importsC(f) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return inspectPackageFiles(i, pkg, names)
+}
+
+// InspectFiles walks each of files like Inspect, in the order given, and returns the modified files. The files are the
+// roots of their walks, so have no Parent, but are held in a slice: the Visitor may replace them with other files, or
+// delete them or insert files alongside them. The Inspector (and Visitor) is shared by all the walks, so can carry
+// context from one file to the next.
+func InspectFiles(i Inspector, files []*ast.File) []*ast.File {
+	defer startWalk(i)()
+	inspectList(i, nil, "", &files)
+	return files
+}
+
+// inspectPackageFiles walks pkg like Inspect, but only visits the named files, in the given order. If the Visitor
+// replaces pkg, the replacement's files are all inspected as usual.
+func inspectPackageFiles(i Inspector, pkg *ast.Package, names []string) ast.Node {
+	impl, ok := i.(*inspectorImpl)
+	if !ok {
+		// other Inspectors walk all of the package's files, in their own order
+		return i.Inspect(pkg)
+	}
+	defer startWalk(impl)()
+	impl.packageFiles = map[*ast.Package][]string{pkg: names}
+	node, _ := impl.inspect(pkg)
+	return node
+}
+
+// packageFileNames returns the names of the files in pkg, ordered by their base in fset and then by name.
+func packageFileNames(pkg *ast.Package, fset *token.FileSet) []string {
+	names := make([]string, 0, len(pkg.Files))
+	bases := make(map[string]int, len(pkg.Files))
+	for name, f := range pkg.Files {
+		names = append(names, name)
+		bases[name] = -1
+		if fset != nil && f != nil {
+			if tf := fset.File(f.Pos()); tf != nil {
+				bases[name] = tf.Base()
+			}
+		}
+	}
+
+	sort.Slice(names, func(a, b int) bool {
+		if bases[names[a]] != bases[names[b]] {
+			// files missing from fset sort last
+			if bases[names[a]] == -1 || bases[names[b]] == -1 {
+				return bases[names[b]] == -1
+			}
+			return bases[names[a]] < bases[names[b]]
+		}
+		return names[a] < names[b]
+	})
+	return names
+}
+
+// fileHeader returns the source of f up to its package clause, for build.Context.MatchFile to evaluate the build
+// constraints of: the comments before the package clause, each group followed by a blank line unless it is the
+// package's doc comment, and the clause itself.
+func fileHeader(pkgName string, f *ast.File) string {
+	var b strings.Builder
+	if f != nil {
+		for _, g := range f.Comments {
+			if g.End() >= f.Package {
+				break
+			}
+			for _, c := range g.List {
+				b.WriteString(c.Text + "\n")
+			}
+			if g != f.Doc {
+				b.WriteString("\n")
+			}
+		}
+	}
+	b.WriteString("package " + pkgName + "\n")
+	return b.String()
+}
+
+// importsC reports whether f imports the pseudo-package "C", making it a cgo file.
+func importsC(f *ast.File) bool {
+	for _, spec := range f.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil && path == "C" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// parsePackage parses a package containing a trivial file for each of the passed names, in the order given.
+func parsePackage(t *testing.T, fset *token.FileSet, names ...string) *ast.Package {
+	pkg := &ast.Package{
+		Name:  "foo",
+		Files: make(map[string]*ast.File, len(names)),
+	}
+	for _, name := range names {
+		f, err := parser.ParseFile(fset, name, "package foo\n", parserFlags)
+		assert.NoError(t, err, "Error parsing input")
+		pkg.Files[name] = f
+	}
+	return pkg
+}
+
+// fileOrderVisitor returns a Visitor which records the names of the files it visits in order.
+func fileOrderVisitor(fset *token.FileSet, order *[]string) Visitor {
+	return func(i Inspector, n ast.Node) bool {
+		if f, ok := n.(*ast.File); ok {
+			*order = append(*order, fset.Position(f.Pos()).Filename)
+			return false
+		}
+		return true
+	}
+}
+
+func TestInspectPackageOrderedByName(t *testing.T) {
+	fset := token.NewFileSet()
+	var names []string
+	for l := 19; l >= 0; l-- {
+		names = append(names, fmt.Sprintf("file%02d.go", l))
+	}
+	pkg := parsePackage(t, fset, names...)
+
+	var order []string
+	NewInspector(fileOrderVisitor(fset, &order)).Inspect(pkg)
+	for l, name := range order {
+		assert.Equal(t, fmt.Sprintf("file%02d.go", l), name)
+	}
+	assert.Len(t, order, 20)
+}
+
+func TestInspectPackageOrderedByFileSet(t *testing.T) {
+	fset := token.NewFileSet()
+	pkg := parsePackage(t, fset, "c.go", "a.go", "b.go")
+
+	var order []string
+	InspectPackage(NewInspector(fileOrderVisitor(fset, &order)), pkg, fset)
+	assert.Equal(t, []string{"c.go", "a.go", "b.go"}, order)
+
+	order = nil
+	InspectPackage(NewInspector(fileOrderVisitor(fset, &order)), pkg, nil)
+	assert.Equal(t, []string{"a.go", "b.go", "c.go"}, order)
+}
+
+func TestInspectPackageForContext(t *testing.T) {
+	sources := map[string]string{
+		"a.go":       "package foo\n",
+		"b_linux.go": "package foo\n",
+		"c.go":       "//go:build windows\n\npackage foo\n",
+		"d.go":       "// +build ignore\n\npackage foo\n",
+		"e.go":       "package foo\n\nimport \"C\"\n",
+		"f.go":       "//go:build !windows\n// +build windows\n\npackage foo\n",
+		"g.go":       "//go:build unix && go1.1\n\npackage foo\n",
+		"h.go":       "// +build custom\n\npackage foo\n",
+	}
+	fset := token.NewFileSet()
+	pkg := &ast.Package{Name: "foo", Files: make(map[string]*ast.File)}
+	for name, src := range sources {
+		f, err := parser.ParseFile(fset, name, src, parserFlags)
+		assert.NoError(t, err, "Error parsing input")
+		pkg.Files[name] = f
+	}
+
+	var order []string
+	ctx := build.Default
+	ctx.GOOS, ctx.GOARCH, ctx.CgoEnabled = "linux", "amd64", false
+	InspectPackageForContext(NewInspector(fileOrderVisitor(fset, &order)), pkg, ctx)
+	assert.Equal(t, []string{"a.go", "b_linux.go", "f.go", "g.go"}, order)
+
+	order = nil
+	ctx.GOOS, ctx.CgoEnabled, ctx.BuildTags = "windows", true, []string{"custom"}
+	InspectPackageForContext(NewInspector(fileOrderVisitor(fset, &order)), pkg, ctx)
+	assert.Equal(t, []string{"a.go", "c.go", "e.go", "h.go"}, order)
+}
+
+func TestInspectPackageReplaceRoot(t *testing.T) {
+	fset := token.NewFileSet()
+	pkg := parsePackage(t, fset, "a.go")
+	replacement := &ast.Package{Name: "bar", Files: map[string]*ast.File{}}
+
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n == pkg {
+			i.Replace(replacement)
+		}
+		return true
+	}
+	assert.Equal(t, replacement, InspectPackage(NewInspector(visitor), pkg, fset))
+	assert.Equal(t, replacement, InspectPackageForContext(NewInspector(visitor), pkg, build.Default))
+}
+
+func TestInspectFiles(t *testing.T) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range []string{"c.go", "a.go", "b.go"} {
+		f, err := parser.ParseFile(fset, name, "package foo\n", parserFlags)
+		assert.NoError(t, err, "Error parsing input")
+		files = append(files, f)
+	}
+
+	var order []string
+	visitor := fileOrderVisitor(fset, &order)
+	deleteA := func(i Inspector, n ast.Node) bool {
+		if f, ok := n.(*ast.File); ok && fset.Position(f.Pos()).Filename == "a.go" {
+			i.Delete()
+			return false
+		}
+		return visitor(i, n)
+	}
+	result := InspectFiles(NewInspector(deleteA), files)
+	assert.Equal(t, []string{"c.go", "b.go"}, order)
+	assert.Equal(t, []*ast.File{files[0], files[2]}, result)
+
+	replaceB := func(i Inspector, n ast.Node) bool {
+		if f, ok := n.(*ast.File); ok && fset.Position(f.Pos()).Filename == "b.go" {
+			i.Replace(ast.NewIdent("b"))
+		}
+		return true
+	}
+	assert.PanicsWithValue(t, "astor: cannot replace root node 2 (*ast.File) with *ast.Ident", func() {
+		InspectFiles(NewInspector(replaceB), files)
+	})
+}
+
+func TestInspectPackageSkipAndStop(t *testing.T) {
+	fset := token.NewFileSet()
+	pkg := parsePackage(t, fset, "a.go", "b.go")
+
+	for _, walk := range []func(Inspector) ast.Node{
+		func(i Inspector) ast.Node { return i.Inspect(pkg) },
+		func(i Inspector) ast.Node { return InspectPackage(i, pkg, fset) },
+		func(i Inspector) ast.Node { return InspectPackageForContext(i, pkg, build.Default) },
+	} {
+		var visited int
+		walk(NewInspector(func(i Inspector, n ast.Node) bool {
+			if n != nil {
+				visited++
+				if _, ok := n.(*ast.Package); ok {
+					i.SkipChildren()
+				}
+			}
+			return true
+		}))
+		assert.Equal(t, 1, visited)
+
+		visited = 0
+		walk(NewInspector(func(i Inspector, n ast.Node) bool {
+			visited++
+			i.Stop()
+			return true
+		}))
+		// the terminating nil visit isn't made either
+		assert.Equal(t, 1, visited)
+
+		i := NewInspector(func(Inspector, ast.Node) bool { return true }, WithMaxNodes(2))
+		walk(i)
+		assert.IsType(t, &NodeLimitError{}, i.Err())
+	}
+}
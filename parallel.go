@@ -0,0 +1,84 @@
+package astor
+
+import (
+	"go/ast"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// InspectParallel walks the top-level declarations of node, an *ast.File or *ast.Package, concurrently on a pool of
+// workers goroutines (GOMAXPROCS, if workers is 0 or less), for transforms of large files which are bound by the CPU.
+// Each worker walks its declarations with its own Inspector, returned by newInspector, so Visitors must not share
+// mutable state unless they synchronise access to it. Any other node is walked by a single Inspector, like Inspect.
+//
+// Each declaration is the root of its walk, as with InspectDecl: it has no Parent, and nor do the file and package
+// holding it, which aren't visited. The Visitor may replace a declaration with another, but not delete it or insert
+// declarations alongside it, and a Visitor calling Stop ends only the walk of the declaration it is visiting. Nothing
+// outside the declarations is changed, so the Imports and Comments of a file aren't kept up to date by replacing
+// their nodes.
+//
+// The error returned is that of the first declaration (in the order of the file, and of the files by name) whose walk
+// ended with one, as returned by Err.
+func InspectParallel(newInspector func() Inspector, node ast.Node, workers int) error {
+	var decls []*ast.Decl
+	switch n := node.(type) {
+	case *ast.File:
+		decls = fileDecls(decls, n)
+	case *ast.Package:
+		names := make([]string, 0, len(n.Files))
+		for name := range n.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			decls = fileDecls(decls, n.Files[name])
+		}
+	default:
+		i := newInspector()
+		i.Inspect(node)
+		return i.Err()
+	}
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	errs := make([]error, len(decls))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(decls); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			i := newInspector()
+			for l := range next {
+				// each worker stores into its own elements of the lists of declarations
+				*decls[l] = InspectDecl(i, *decls[l])
+				errs[l] = i.Err()
+			}
+		}()
+	}
+	for l := range decls {
+		next <- l
+	}
+	close(next)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileDecls appends pointers to each of the declarations of f to decls.
+func fileDecls(decls []*ast.Decl, f *ast.File) []*ast.Decl {
+	if f == nil {
+		return decls
+	}
+	for l := range f.Decls {
+		decls = append(decls, &f.Decls[l])
+	}
+	return decls
+}
@@ -0,0 +1,79 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspectParallel(t *testing.T) {
+	src := func(call string) string {
+		var b strings.Builder
+		b.WriteString("package foo\n")
+		for l := 0; l < 100; l++ {
+			fmt.Fprintf(&b, "\nfunc f%d() {\n\t%s(%d)\n}\n", l, call, l)
+		}
+		return b.String()
+	}
+	f := parseEqualSrc(t, src("a"))
+
+	// the count is shared between the Visitors, so is synchronised (which is checked when run with -race)
+	var inspectors, visited int64
+	newInspector := func() Inspector {
+		atomic.AddInt64(&inspectors, 1)
+		return NewInspector(func(i Inspector, n ast.Node) bool {
+			if n == nil {
+				return true
+			}
+			atomic.AddInt64(&visited, 1)
+			if id, ok := n.(*ast.Ident); ok && id.Name == "a" {
+				i.Replace(ast.NewIdent("b"))
+			}
+			return true
+		})
+	}
+	assert.NoError(t, InspectParallel(newInspector, f, 4))
+	assert.Empty(t, Diff(parseEqualSrc(t, src("b")), f))
+	assert.Equal(t, int64(4), inspectors)
+	// each declaration, its name, type, parameters, body, statement, call, function and argument
+	assert.Equal(t, int64(100*9), visited)
+
+	// the declarations of a package's files are walked too, and may be replaced
+	pkg := &ast.Package{Name: "foo", Files: map[string]*ast.File{"a.go": parseEqualSrc(t, src("a")), "b.go": f}}
+	assert.NoError(t, InspectParallel(func() Inspector {
+		return NewInspector(func(i Inspector, n ast.Node) bool {
+			if fd, ok := n.(*ast.FuncDecl); ok {
+				i.Replace(&ast.FuncDecl{Name: ast.NewIdent(fd.Name.Name + "x"), Type: fd.Type, Body: fd.Body})
+			}
+			return true
+		})
+	}, pkg, 0))
+	for _, file := range pkg.Files {
+		for _, d := range file.Decls {
+			assert.True(t, strings.HasSuffix(d.(*ast.FuncDecl).Name.Name, "x"))
+		}
+	}
+
+	// the first error ends only its own walk
+	visited = 0
+	limited := func() Inspector {
+		return NewInspector(func(i Inspector, n ast.Node) bool {
+			if n != nil {
+				atomic.AddInt64(&visited, 1)
+			}
+			return true
+		}, WithMaxNodes(3))
+	}
+	var limitErr *NodeLimitError
+	assert.ErrorAs(t, InspectParallel(limited, f, 4), &limitErr)
+	assert.Equal(t, int64(100*3), visited)
+
+	// other nodes are walked as usual
+	visited = 0
+	assert.ErrorAs(t, InspectParallel(limited, f.Decls[0].(*ast.FuncDecl).Body, 4), &limitErr)
+	assert.Equal(t, int64(3), visited)
+}
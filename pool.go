@@ -0,0 +1,44 @@
+package astor
+
+import (
+	"sync"
+)
+
+// An InspectorPool reuses Inspectors, so that a program walking many ASTs (eg. generating code for many small files)
+// doesn't construct one for each walk. It is safe to use from several goroutines at once, but each Inspector it
+// returns is for one goroutine, as usual, until it is Put back.
+type InspectorPool struct {
+	factory func() Visitor
+	pool    sync.Pool
+}
+
+// NewInspectorPool constructs an InspectorPool of Inspectors constructed with the passed Options. Each Inspector it
+// returns calls a Visitor returned by factory, which is called each time an Inspector is taken from the pool, so a
+// Visitor keeping state about a walk starts afresh.
+func NewInspectorPool(factory func() Visitor, opts ...Option) *InspectorPool {
+	p := &InspectorPool{factory: factory}
+	p.pool.New = func() interface{} {
+		return NewInspector(nil, opts...)
+	}
+	return p
+}
+
+// Get returns an Inspector from the pool, constructing one if the pool is empty. It is as though it were newly
+// constructed: none of the state of the walks it made before it was Put back is kept, such as the identifiers
+// assigned by WithNodeIDs.
+func (p *InspectorPool) Get() Inspector {
+	i := p.pool.Get().(*inspectorImpl)
+	i.visitorImpl = p.factory()
+	return i
+}
+
+// Put returns an Inspector taken from the pool with Get, once its walk is over, so it can be reused. It must not be
+// used again afterwards. Put panics if a node is being visited.
+func (p *InspectorPool) Put(i Inspector) {
+	impl := i.(*inspectorImpl)
+	if impl.visiting.Load() {
+		panic("astor: InspectorPool.Put called while a node is being visited")
+	}
+	impl.reset()
+	p.pool.Put(impl)
+}
@@ -0,0 +1,96 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspectorPool(t *testing.T) {
+	var visitors int
+	var mtx sync.Mutex
+	pool := NewInspectorPool(func() Visitor {
+		mtx.Lock()
+		defer mtx.Unlock()
+		visitors++
+		// each Visitor counts the nodes of its own walk
+		var n int
+		return func(i Inspector, node ast.Node) bool {
+			if node != nil {
+				n++
+				if _, ok := node.(*ast.File); ok {
+					assert.Equal(t, uint64(1), i.ID(node))
+				}
+			}
+			return true
+		}
+	}, WithNodeIDs())
+
+	fset := token.NewFileSet()
+	var wg sync.WaitGroup
+	for l := 0; l < 8; l++ {
+		f, err := parser.ParseFile(fset, fmt.Sprintf("file%d.go", l), "package foo\n\nvar a = b\n", parserFlags)
+		assert.NoError(t, err, "Error parsing input")
+
+		wg.Add(1)
+		go func(f *ast.File) {
+			defer wg.Done()
+			for k := 0; k < 4; k++ {
+				i := pool.Get()
+				i.Inspect(f)
+				// identifiers, like the rest of the walk's state, don't survive being put back
+				assert.Equal(t, uint64(1), i.ID(f))
+				pool.Put(i)
+			}
+		}(f)
+	}
+	wg.Wait()
+	assert.Equal(t, 32, visitors)
+}
+
+// smallFiles parses n small files for the pool benchmarks.
+func smallFiles(b *testing.B, n int) []*ast.File {
+	fset := token.NewFileSet()
+	files := make([]*ast.File, n)
+	for l := range files {
+		src := fmt.Sprintf("package foo\n\nfunc Bar%d(a int) int { return a + %d }\n", l, l)
+		f, err := parser.ParseFile(fset, fmt.Sprintf("file%d.go", l), src, parserFlags)
+		assert.NoError(b, err, "Error parsing input")
+		files[l] = f
+	}
+	return files
+}
+
+func noopVisitor(i Inspector, n ast.Node) bool {
+	return true
+}
+
+func BenchmarkNewInspectorPerFile(b *testing.B) {
+	files := smallFiles(b, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for l := 0; l < b.N; l++ {
+		for _, f := range files {
+			NewInspector(noopVisitor).Inspect(f)
+		}
+	}
+}
+
+func BenchmarkInspectorPool(b *testing.B) {
+	files := smallFiles(b, 100)
+	pool := NewInspectorPool(func() Visitor { return noopVisitor })
+	b.ReportAllocs()
+	b.ResetTimer()
+	for l := 0; l < b.N; l++ {
+		for _, f := range files {
+			i := pool.Get()
+			i.Inspect(f)
+			pool.Put(i)
+		}
+	}
+}
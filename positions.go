@@ -0,0 +1,100 @@
+package astor
+
+import (
+	"go/ast"
+	"go/token"
+	"reflect"
+)
+
+// SetPositions gives every missing (token.NoPos) position in the AST rooted at n the position of ref, so that a
+// synthetic node replacing ref is formatted, and has comments placed, as though it were written where ref was. It is
+// typically called just before replacing ref with n:
+//
+//	astor.SetPositions(replacement, i.Current())
+//	i.Replace(replacement)
+//
+// Positions whose absence is meaningful, such as the Ellipsis of an *ast.CallExpr or the Assign of an *ast.TypeSpec,
+// are left missing. Positions which are already set are left alone.
+func SetPositions(n ast.Node, ref ast.Node) {
+	if n == nil || ref == nil {
+		return
+	}
+	pos := ref.Pos()
+	mapPositions(n, func(p token.Pos, significant bool) token.Pos {
+		if p.IsValid() || significant {
+			return p
+		}
+		return pos
+	})
+}
+
+// StripPositions sets every position in the AST rooted at node to token.NoPos: the position fields of each node
+// (including the Slash of each comment), so the AST holds no positions from the FileSet it was parsed with, and
+// formats cleanly with a fresh one, even if it is built from fragments of several files.
+//
+// The positions whose presence is meaningful, the Ellipsis of a variadic *ast.CallExpr, the Assign of an alias
+// *ast.TypeSpec and the Lparen and Rparen of a grouped *ast.GenDecl, are set to token.Pos(1) instead, which is in no
+// file of a fresh FileSet, so the nodes keep their meaning.
+//
+// Without positions, the printer can only place doc comments, just before the nodes holding them, so the rest are
+// removed: the Comments of each *ast.File are cleared, as is the Comment (the line comment) of each field and spec.
+func StripPositions(node ast.Node) {
+	if node == nil {
+		return
+	}
+	mapPositions(node, func(p token.Pos, significant bool) token.Pos {
+		if significant && p.IsValid() {
+			return 1
+		}
+		return token.NoPos
+	})
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.File:
+			n.Comments = nil
+		case *ast.Field:
+			n.Comment = nil
+		case *ast.ImportSpec:
+			n.Comment = nil
+		case *ast.ValueSpec:
+			n.Comment = nil
+		case *ast.TypeSpec:
+			n.Comment = nil
+		}
+		return true
+	}).Inspect(node)
+}
+
+// significantNoPos holds the position fields of each node type whose absence changes the meaning of the node.
+var significantNoPos = map[reflect.Type]map[string]bool{
+	reflect.TypeOf(ast.CallExpr{}): {"Ellipsis": true},
+	reflect.TypeOf(ast.TypeSpec{}): {"Assign": true},
+	reflect.TypeOf(ast.GenDecl{}):  {"Lparen": true, "Rparen": true},
+}
+
+var posType = reflect.TypeOf(token.NoPos)
+
+// mapPositions replaces each of the positions held by the nodes in the AST rooted at n with the result of calling f
+// with it, and whether its absence is significant.
+func mapPositions(n ast.Node, f func(p token.Pos, significant bool) token.Pos) {
+	NewInspector(func(i Inspector, node ast.Node) bool {
+		if node == nil {
+			return true
+		}
+		v := reflect.ValueOf(node)
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			return true
+		}
+
+		v = v.Elem()
+		significant := significantNoPos[v.Type()]
+		for l := 0; l < v.NumField(); l++ {
+			field := v.Field(l)
+			if field.Type() != posType {
+				continue
+			}
+			field.SetInt(int64(f(token.Pos(field.Int()), significant[v.Type().Field(l).Name])))
+		}
+		return true
+	}).Inspect(n)
+}
@@ -0,0 +1,107 @@
+package astor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const positionsSrc = `package foo
+
+func Bar() {
+	// before
+	x := a + b // sum
+	y := x
+}
+`
+
+func TestSetPositions(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "positions.go", positionsSrc, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	visitor := func(i Inspector, n ast.Node) bool {
+		if assign, ok := n.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE && len(assign.Rhs) == 1 {
+			if _, ok := assign.Rhs[0].(*ast.BinaryExpr); ok {
+				replacement := &ast.AssignStmt{
+					Lhs: []ast.Expr{ast.NewIdent("x")},
+					Tok: token.DEFINE,
+					Rhs: []ast.Expr{&ast.CallExpr{
+						Fun:  ast.NewIdent("add"),
+						Args: []ast.Expr{ast.NewIdent("a"), ast.NewIdent("b")},
+					}},
+				}
+				SetPositions(replacement, n)
+				i.Replace(replacement)
+				return false
+			}
+		}
+		return true
+	}
+	NewInspector(visitor).Inspect(f)
+
+	out := new(bytes.Buffer)
+	assert.NoError(t, format.Node(out, fset, f), "Error formatting output AST")
+	assert.Equal(t, `package foo
+
+func Bar() {
+	// before
+	x := add(a, b) // sum
+	y := x
+}
+`, out.String())
+}
+
+func TestSetPositionsKeepsSignificantNoPos(t *testing.T) {
+	call := &ast.CallExpr{Fun: ast.NewIdent("f"), Args: []ast.Expr{ast.NewIdent("xs")}}
+	SetPositions(call, &ast.Ident{NamePos: 10, Name: "ref"})
+	assert.Equal(t, token.Pos(10), call.Lparen)
+	assert.Equal(t, token.Pos(10), call.Args[0].Pos())
+	assert.Equal(t, token.NoPos, call.Ellipsis)
+}
+
+func TestStripPositions(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "strip.go", `package foo
+
+// Bar is a bar
+func Bar(x ...int) {
+	// lost
+	Bar(x...)
+}
+
+type (
+	T = int // an alias
+)
+`, parser.ParseComments)
+	assert.NoError(t, err)
+
+	StripPositions(f)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if n != nil {
+			assert.False(t, n.Pos().IsValid() && n.Pos() != 1, "%T has a position", n)
+		}
+		return true
+	})
+	assert.Nil(t, f.Comments)
+
+	out := new(bytes.Buffer)
+	assert.NoError(t, format.Node(out, token.NewFileSet(), f))
+	assert.Equal(t, `package foo
+// Bar is a bar
+func Bar(x ...int) {
+	Bar(x...)
+}
+
+type (
+	T = int
+)
+`, out.String())
+
+	assert.NotPanics(t, func() { StripPositions(nil) })
+}
@@ -0,0 +1,108 @@
+package astor
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+)
+
+// printfFuncs are the names of the Printf-like functions and methods of the standard library, and of popular logging
+// packages, by the index of their format argument.
+var printfFuncs = map[string]int{
+	"Printf": 0, "Sprintf": 0, "Errorf": 0, "Fatalf": 0, "Panicf": 0, "Logf": 0, "Skipf": 0,
+	"Debugf": 0, "Infof": 0, "Warnf": 0, "Warningf": 0,
+	"Fprintf": 1, "Appendf": 1,
+}
+
+// PrintfFormat returns the index in the arguments of call of its format string, if it's a call of a Printf-like
+// function. With type information (see NewInspectorWithTypes), any function or method whose last two parameters are a
+// string and a variadic ...interface{} is Printf-like, and the format is the string. Without it, the name of the
+// function or method called is looked up in a list of the Printf-like functions of the standard library and popular
+// logging packages (such as fmt.Printf, fmt.Fprintf, log.Fatalf and testing.T's Errorf).
+func PrintfFormat(i Inspector, call *ast.CallExpr) (int, bool) {
+	var idx int
+	if sig, ok := i.TypeOf(call.Fun).(*types.Signature); ok {
+		params := sig.Params()
+		if !sig.Variadic() || params.Len() < 2 {
+			return 0, false
+		}
+		last, _ := params.At(params.Len() - 1).Type().(*types.Slice)
+		elem, _ := last.Elem().Underlying().(*types.Interface)
+		format, _ := params.At(params.Len() - 2).Type().Underlying().(*types.Basic)
+		if elem == nil || !elem.Empty() || format == nil || format.Kind() != types.String {
+			return 0, false
+		}
+		idx = params.Len() - 2
+	} else {
+		var name string
+		switch fun := unparen(call.Fun).(type) {
+		case *ast.Ident:
+			name = fun.Name
+		case *ast.SelectorExpr:
+			name = fun.Sel.Name
+		}
+		var ok bool
+		if idx, ok = printfFuncs[name]; !ok {
+			return 0, false
+		}
+	}
+	if idx >= len(call.Args) {
+		return 0, false
+	}
+	return idx, true
+}
+
+// IsPrintfFormat reports whether the node i is visiting is the format string argument of a Printf-like call (see
+// PrintfFormat), so the Visitor can rewrite it with Replace.
+func IsPrintfFormat(i Inspector) bool {
+	call, ok := i.Parent().(*ast.CallExpr)
+	if !ok || i.Index() < 0 {
+		return false
+	}
+	idx, ok := PrintfFormat(i, call)
+	return ok && idx == i.Index()
+}
+
+// PrintfFormatString returns the format string of call, a Printf-like call, if it's a constant: a string literal, or
+// (with type information) any constant expression, such as a named constant or a concatenation. It returns false if
+// call isn't Printf-like, or its format isn't known until the program runs.
+func PrintfFormatString(i Inspector, call *ast.CallExpr) (string, bool) {
+	idx, ok := PrintfFormat(i, call)
+	if !ok {
+		return "", false
+	}
+	arg := call.Args[idx]
+	if lit, ok := unparen(arg).(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		s, err := strconv.Unquote(lit.Value)
+		return s, err == nil
+	}
+	if impl, ok := i.(*inspectorImpl); ok && impl.info != nil {
+		if tv, ok := impl.info.Types[arg]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+			return constant.StringVal(tv.Value), true
+		}
+	}
+	return "", false
+}
+
+// SetPrintfFormat replaces the *ast.CallExpr i is visiting, if it's a Printf-like call, with a copy whose format
+// argument is a string literal holding format, where the format was. It reports whether the call is Printf-like. The
+// format is replaced whether or not it was a literal, so a format held in a variable or built by the program is
+// replaced too.
+func SetPrintfFormat(i Inspector, format string) bool {
+	call, ok := i.Current().(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	idx, ok := PrintfFormat(i, call)
+	if !ok {
+		return false
+	}
+
+	edited := *call
+	edited.Args = append([]ast.Expr{}, call.Args...)
+	edited.Args[idx] = &ast.BasicLit{ValuePos: call.Args[idx].Pos(), Kind: token.STRING, Value: strconv.Quote(format)}
+	i.Replace(&edited)
+	return true
+}
@@ -0,0 +1,96 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const printfSrc = `package foo
+
+const greeting = "hello %s"
+
+type logger struct{}
+
+func (logger) Printf(format string, args ...interface{}) {}
+
+func (logger) Println(args ...interface{}) {}
+
+func report(w int, format string, args ...interface{}) {}
+
+func Bar(l logger, format string) {
+	l.Printf("a %d", 1)
+	l.Printf(greeting+"!", "world")
+	l.Printf(format)
+	l.Println("b %d", 2)
+	report(1, "c %d", 3)
+}
+`
+
+func TestPrintfFormat(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "printf.go", printfSrc, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	_, err = (&types.Config{}).Check("foo", fset, []*ast.File{f}, info)
+	assert.NoError(t, err, "Error type-checking input")
+
+	formats := func(newInspector func(Visitor) Inspector) []string {
+		var formats []string
+		newInspector(func(i Inspector, n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				if format, ok := PrintfFormatString(i, call); ok {
+					formats = append(formats, format)
+				} else if _, ok := PrintfFormat(i, call); ok {
+					formats = append(formats, "?")
+				}
+			}
+			if n != nil && IsPrintfFormat(i) {
+				formats = append(formats, fmt.Sprintf("%T", n))
+			}
+			return true
+		}).Inspect(f)
+		return formats
+	}
+	assert.Equal(t, []string{"a %d", "*ast.BasicLit", "hello %s!", "*ast.BinaryExpr", "?", "*ast.Ident", "c %d",
+		"*ast.BasicLit"},
+		formats(func(v Visitor) Inspector { return NewInspectorWithTypes(v, info) }))
+	// without types, the functions are known by name, and only literals are known
+	assert.Equal(t, []string{"a %d", "*ast.BasicLit", "?", "*ast.BinaryExpr", "?", "*ast.Ident"}, formats(func(v Visitor) Inspector { return NewInspector(v) }))
+}
+
+func TestSetPrintfFormat(t *testing.T) {
+	f := parseEqualSrc(t, printfSrc)
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			format, _ := PrintfFormatString(i, call)
+			SetPrintfFormat(i, "["+format+"]")
+		}
+		return true
+	}).Inspect(f)
+	assert.Empty(t, Diff(parseEqualSrc(t, `package foo
+
+const greeting = "hello %s"
+
+type logger struct{}
+
+func (logger) Printf(format string, args ...interface{}) {}
+
+func (logger) Println(args ...interface{}) {}
+
+func report(w int, format string, args ...interface{}) {}
+
+func Bar(l logger, format string) {
+	l.Printf("[a %d]", 1)
+	l.Printf("[]", "world")
+	l.Printf("[]")
+	l.Println("b %d", 2)
+	report(1, "c %d", 3)
+}
+`), f))
+}
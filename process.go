@@ -0,0 +1,122 @@
+package astor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A ProcessError is returned by ProcessDir when some of the files in the directory couldn't be processed. Errs holds
+// the error for each of them, keyed by path: a *ParseError, *FormatError or error ending the walk as for
+// InspectSource, or the error reading or writing the file. The other files were processed as usual.
+type ProcessError struct {
+	Dir string
+	// Processed is the number of files processed, including those which failed
+	Processed int
+	Errs      map[string]error
+}
+
+func (e *ProcessError) Error() string {
+	paths := make([]string, 0, len(e.Errs))
+	for path := range e.Errs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	descs := make([]string, len(paths))
+	for l, path := range paths {
+		descs[l] = fmt.Sprintf("%s: %v", path, e.Errs[path])
+	}
+	return fmt.Sprintf("astor: processing %d of %d files in %s failed: %s", len(e.Errs), e.Processed, e.Dir,
+		strings.Join(descs, "; "))
+}
+
+// ProcessTestFiles makes ProcessDir process the _test.go files in the directory, which it otherwise leaves alone. It
+// has no effect on walks made other than by ProcessDir.
+func ProcessTestFiles() Option {
+	return func(i *inspectorImpl) {
+		i.testFiles = true
+	}
+}
+
+// ProcessDir rewrites each of the Go source files in dir (but not its subdirectories), as InspectSource does, with
+// one Inspector constructed with the passed Visitor and Options, which suits a codemod run from a go:generate
+// directive. Files whose names start with . or _, like those the go tool ignores, and _test.go files (unless the
+// ProcessTestFiles Option is passed) are skipped, as are generated files if the SkipGenerated Option is passed. Each
+// file changed by the walk is written back atomically, by renaming a temporary file over it, so it is never left
+// partly written; unchanged files aren't touched.
+//
+// A file which can't be parsed, formatted or written, or whose walk ends with an error (see Inspector.Err), is left as
+// it was, and doesn't stop the rest from being processed: if any fail, the error is a *ProcessError holding the error
+// for each. An error reading dir itself is returned as it is.
+func ProcessDir(dir string, v Visitor, opts ...Option) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	i := NewInspector(v, opts...)
+	testFiles := i.(*inspectorImpl).testFiles
+	perr := &ProcessError{Dir: dir, Errs: map[string]error{}}
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case !entry.Type().IsRegular(), !strings.HasSuffix(name, ".go"):
+			continue
+		case strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_"):
+			continue
+		case strings.HasSuffix(name, "_test.go") && !testFiles:
+			continue
+		}
+
+		perr.Processed++
+		path := filepath.Join(dir, name)
+		if err := processFile(i, path); err != nil {
+			perr.Errs[path] = err
+		}
+	}
+	if len(perr.Errs) > 0 {
+		return perr
+	}
+	return nil
+}
+
+func processFile(i Inspector, path string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	out, err := inspectSource(i, path, src)
+	if err != nil || bytes.Equal(out, src) {
+		return err
+	}
+	return writeFileAtomic(path, out)
+}
+
+// writeFileAtomic replaces the contents of the file at path with data, keeping its permissions, by writing a
+// temporary file in the same directory and renaming it over the original.
+func writeFileAtomic(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
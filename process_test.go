@@ -0,0 +1,94 @@
+package astor
+
+import (
+	"errors"
+	"go/ast"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":       "package foo\n\nfunc Bar() {}\n",
+		"b.go":       "package foo\n\nfunc {",
+		"c.go":       "package foo\n\nvar x = 1\n",
+		"a_test.go":  "package foo\n\nfunc Bar2() {}\n",
+		"_ignore.go": "package foo\n\nfunc Bar3() {}\n",
+		"d.txt":      "func Bar() {}\n",
+	}
+	for name, src := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0o640))
+	}
+	old := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "c.go"), old, old))
+
+	rename := func(i Inspector, n ast.Node) bool {
+		if n, ok := n.(*ast.FuncDecl); ok {
+			i.Replace(&ast.FuncDecl{Name: ast.NewIdent("Foo" + n.Name.Name), Type: n.Type, Body: n.Body})
+			return false
+		}
+		return true
+	}
+	read := func(name string) string {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		assert.NoError(t, err)
+		return string(b)
+	}
+
+	err := ProcessDir(dir, rename)
+	var perr *ProcessError
+	if assert.True(t, errors.As(err, &perr), "Expected a *ProcessError, got %v", err) {
+		assert.Equal(t, 3, perr.Processed)
+		assert.Len(t, perr.Errs, 1)
+		var parseErr *ParseError
+		assert.True(t, errors.As(perr.Errs[filepath.Join(dir, "b.go")], &parseErr))
+	}
+	assert.Equal(t, "package foo\n\nfunc FooBar() {}\n", read("a.go"))
+	assert.Equal(t, files["a_test.go"], read("a_test.go"))
+	assert.Equal(t, files["_ignore.go"], read("_ignore.go"))
+	assert.Equal(t, files["d.txt"], read("d.txt"))
+	info, err := os.Stat(filepath.Join(dir, "a.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	// unchanged files aren't written
+	info, err = os.Stat(filepath.Join(dir, "c.go"))
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Before(time.Now().Add(-time.Minute)))
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "b.go")))
+	assert.NoError(t, ProcessDir(dir, rename, ProcessTestFiles()))
+	assert.Equal(t, "package foo\n\nfunc FooBar2() {}\n", read("a_test.go"))
+	// generated files are left as they are, unformatted
+	generated := "// Code generated by hand. DO NOT EDIT.\n\npackage foo\n\nfunc   Bar4() {}\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "e.go"), []byte(generated), 0o640))
+	assert.NoError(t, ProcessDir(dir, rename, SkipGenerated()))
+	assert.Equal(t, generated, read("e.go"))
+	assert.Equal(t, "package foo\n\nfunc FooFooFooBar() {}\n", read("a.go"))
+	// no temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 6)
+
+	// a file whose walk ends with an error is left as it was
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "f.go"), []byte("package foo\n\nfunc F() { a(); b(); c() }\n"),
+		0o640))
+	renameA := func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "a" {
+			i.Replace(ast.NewIdent("aX"))
+		}
+		return true
+	}
+	err = ProcessDir(dir, renameA, WithMaxNodes(10))
+	if assert.True(t, errors.As(err, &perr), "Expected a *ProcessError, got %v", err) {
+		var limitErr *NodeLimitError
+		assert.ErrorAs(t, perr.Errs[filepath.Join(dir, "f.go")], &limitErr)
+	}
+	assert.Equal(t, "package foo\n\nfunc F() { a(); b(); c() }\n", read("f.go"))
+
+	assert.Error(t, ProcessDir(filepath.Join(dir, "missing"), rename))
+}
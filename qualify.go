@@ -0,0 +1,109 @@
+package astor
+
+import (
+	"go/ast"
+	"sort"
+)
+
+// Qualify replaces each identifier in the AST rooted at node (including node itself) which refers to one of the names
+// in names, a map from names to import paths, with a selector of the name from the package imported from its path:
+// Foo becomes pkg.Foo. It returns the modified tree. This moves code out of the package declaring those names.
+//
+// Only identifiers left unresolved by go/parser are qualified, so declarations, and references to anything declared
+// in the file (such as a local variable shadowing the name), are left alone; as are selected fields and methods, and
+// the keys of composite literal elements, which may be field names. If node is an *ast.File, the package is referred
+// to by the name of the file's import of its path, which is added (with EnsureImport) if there is none; otherwise
+// it's taken to be the last element of the path, as for RemoveUnusedImports.
+func Qualify(node ast.Node, names map[string]string) ast.Node {
+	file, _ := node.(*ast.File)
+	qualifiers := map[string]string{}
+	var missing []string
+	qualifier := func(path string) string {
+		if q, ok := qualifiers[path]; ok {
+			return q
+		}
+		q, imported := importedName(file, path)
+		if file != nil && !imported {
+			missing = append(missing, path)
+		}
+		qualifiers[path] = q
+		return q
+	}
+
+	result := NewInspector(func(i Inspector, n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || id.Obj != nil || !qualifiable(i) {
+			return true
+		}
+		if path, ok := names[id.Name]; ok {
+			x := &ast.Ident{NamePos: id.NamePos, Name: qualifier(path)}
+			i.Replace(&ast.SelectorExpr{X: x, Sel: &ast.Ident{NamePos: id.NamePos, Name: id.Name}})
+		}
+		return true
+	}).Inspect(node)
+
+	// the imports are added once the walk is done, rather than changing the file's declarations as they're walked
+	sort.Strings(missing)
+	for _, path := range missing {
+		EnsureImport(file, path, "")
+	}
+	return result
+}
+
+// Unqualify is the inverse of Qualify: it replaces each selector in the AST rooted at node (including node itself) of
+// one of the names in names from the package imported from its path, pkg.Foo, with the bare name Foo, returning the
+// modified tree. This moves code into the package declaring those names. If node is an *ast.File, the package is
+// recognised by the name of the file's import of the path; otherwise by the last element of the path. The imports
+// themselves are left, as the file may still refer to the package; RemoveUnusedImports removes them if not.
+func Unqualify(node ast.Node, names map[string]string) ast.Node {
+	file, _ := node.(*ast.File)
+	return NewInspector(func(i Inspector, n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		x, ok := sel.X.(*ast.Ident)
+		path, named := names[sel.Sel.Name]
+		if !ok || !named || x.Obj != nil || !importedAs(file, path, x.Name) {
+			return true
+		}
+		i.Replace(&ast.Ident{NamePos: x.NamePos, Name: sel.Sel.Name})
+		return false
+	}).Inspect(node)
+}
+
+// qualifiable reports whether the identifier i is visiting may refer to a declaration of another package.
+func qualifiable(i Inspector) bool {
+	switch i.Parent().(type) {
+	case *ast.SelectorExpr:
+		return i.FieldName() != "Sel"
+	case *ast.KeyValueExpr:
+		return i.FieldName() != "Key"
+	case *ast.File, *ast.ImportSpec, *ast.LabeledStmt, *ast.BranchStmt:
+		return false
+	}
+	return true
+}
+
+// importedName returns the name by which file refers to the package imported from path, and whether file imports it
+// (other than as a blank or dot import). If it doesn't, or file is nil, the name is the last element of the path.
+func importedName(file *ast.File, path string) (string, bool) {
+	if file != nil {
+		for _, spec := range file.Imports {
+			if name := importName(spec); importPath(spec) == path && name != "_" && name != "." {
+				if name == "" {
+					name = guessPackageName(path)
+				}
+				return name, true
+			}
+		}
+	}
+	return guessPackageName(path), false
+}
+
+// importedAs reports whether name refers to the package imported from path in file, or (if file is nil) whether it's
+// the name of the package guessed from its path.
+func importedAs(file *ast.File, path, name string) bool {
+	q, imported := importedName(file, path)
+	return q == name && (imported || file == nil)
+}
@@ -0,0 +1,73 @@
+package astor
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQualify(t *testing.T) {
+	f := parseEqualSrc(t, `package foo
+
+import str "strings"
+
+type T struct{ Reader int }
+
+func Bar(x int) Reader {
+	Builder := 1
+	_ = T{Reader: Builder}
+	_ = x.Reader
+	_ = NewReader(Join(nil, ""))
+	return nil
+}
+`)
+	names := map[string]string{"Reader": "io", "NewReader": "bufio", "Builder": "strings", "Join": "strings"}
+	Qualify(f, names)
+	assert.Empty(t, Diff(parseEqualSrc(t, `package foo
+
+import (
+	"bufio"
+	"io"
+	str "strings"
+)
+
+type T struct{ Reader int }
+
+func Bar(x int) io.Reader {
+	Builder := 1
+	_ = T{Reader: Builder}
+	_ = x.Reader
+	_ = bufio.NewReader(str.Join(nil, ""))
+	return nil
+}
+`), f))
+
+	Unqualify(f, names)
+	assert.Empty(t, Diff(parseEqualSrc(t, `package foo
+
+import (
+	"bufio"
+	"io"
+	str "strings"
+)
+
+type T struct{ Reader int }
+
+func Bar(x int) Reader {
+	Builder := 1
+	_ = T{Reader: Builder}
+	_ = x.Reader
+	_ = NewReader(Join(nil, ""))
+	return nil
+}
+`), f))
+
+	// outside a file, the package is named after its path
+	call := &ast.CallExpr{Fun: ast.NewIdent("Join")}
+	q := Qualify(call, names)
+	assert.Empty(t, Diff(&ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent("strings"), Sel: ast.NewIdent("Join")}}, q))
+	assert.Empty(t, Diff(&ast.CallExpr{Fun: ast.NewIdent("Join")}, Unqualify(q, names)))
+	assert.Empty(t, Diff(&ast.SelectorExpr{X: ast.NewIdent("io"), Sel: ast.NewIdent("Reader")},
+		Qualify(ast.NewIdent("Reader"), names)))
+}
@@ -0,0 +1,103 @@
+package astor
+
+import (
+	"go/ast"
+	"reflect"
+)
+
+// Collect returns every node in the AST rooted at node (including node itself) for which pred returns true, in the
+// order they are first visited (pre-order).
+func Collect(node ast.Node, pred func(ast.Node) bool) []ast.Node {
+	c := &collector{pred: pred}
+	NewInspector(c.visit).Inspect(node)
+	return c.matches
+}
+
+// Find returns the first node in the AST rooted at node (including node itself) for which pred returns true, in the
+// order they are first visited (pre-order), and whether there was one. The walk stops as soon as it is found.
+func Find(node ast.Node, pred func(ast.Node) bool) (ast.Node, bool) {
+	f := &finder{pred: pred}
+	NewInspector(f.visit).Inspect(node)
+	return f.match, f.match != nil
+}
+
+// Count returns the number of nodes in the AST rooted at node (including node itself) for which pred returns true.
+func Count(node ast.Node, pred func(ast.Node) bool) int {
+	c := &counter{pred: pred}
+	NewInspector(c.visit).Inspect(node)
+	return c.n
+}
+
+// Stats returns the number of nodes of each type in the AST rooted at node (including node itself), keyed by the name
+// of the type as it is printed with %T, such as "*ast.CallExpr".
+func Stats(node ast.Node) map[string]int {
+	s := &stats{counts: map[string]int{}}
+	NewInspector(s.visit).Inspect(node)
+	return s.counts
+}
+
+// BadNodes returns the *ast.BadExpr, *ast.BadStmt and *ast.BadDecl nodes in the AST rooted at node, in pre-order.
+// go/parser leaves these in place of source it couldn't parse (eg. with parser.AllErrors), spanning the broken
+// region from their From to their To position, so they can be reported or skipped.
+func BadNodes(node ast.Node) []ast.Node {
+	return Collect(node, isBadNode)
+}
+
+func isBadNode(n ast.Node) bool {
+	switch n.(type) {
+	case *ast.BadExpr, *ast.BadStmt, *ast.BadDecl:
+		return true
+	}
+	return false
+}
+
+// collector, counter and stats keep their state in a struct so the walk uses a single method value for its Visitor, rather
+// than allocating a closure per call or per node.
+
+type collector struct {
+	pred    func(ast.Node) bool
+	matches []ast.Node
+}
+
+func (c *collector) visit(i Inspector, n ast.Node) bool {
+	if n != nil && c.pred(n) {
+		c.matches = append(c.matches, n)
+	}
+	return true
+}
+
+type finder struct {
+	pred  func(ast.Node) bool
+	match ast.Node
+}
+
+func (f *finder) visit(i Inspector, n ast.Node) bool {
+	if n != nil && f.pred(n) {
+		f.match = n
+		i.Stop()
+	}
+	return true
+}
+
+type counter struct {
+	pred func(ast.Node) bool
+	n    int
+}
+
+func (c *counter) visit(i Inspector, n ast.Node) bool {
+	if n != nil && c.pred(n) {
+		c.n++
+	}
+	return true
+}
+
+type stats struct {
+	counts map[string]int
+}
+
+func (s *stats) visit(i Inspector, n ast.Node) bool {
+	if n != nil {
+		s.counts[reflect.TypeOf(n).String()]++
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const querySrc = `package foo
+
+func Bar(a, b int) int {
+	return a + b
+}
+`
+
+func parseQuerySrc(t *testing.T) *ast.File {
+	f, err := parser.ParseFile(token.NewFileSet(), "query.go", querySrc, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	return f
+}
+
+func TestCollect(t *testing.T) {
+	f := parseQuerySrc(t)
+
+	idents := Collect(f, func(n ast.Node) bool {
+		_, ok := n.(*ast.Ident)
+		return ok
+	})
+	names := make([]string, len(idents))
+	for l, n := range idents {
+		names[l] = n.(*ast.Ident).Name
+	}
+	assert.Equal(t, []string{"foo", "Bar", "a", "b", "int", "int", "a", "b"}, names)
+
+	// The root node is itself eligible
+	roots := Collect(f, func(n ast.Node) bool {
+		return n == f
+	})
+	assert.Equal(t, []ast.Node{f}, roots)
+
+	assert.Empty(t, Collect(f, func(n ast.Node) bool { return false }))
+}
+
+func TestCount(t *testing.T) {
+	f := parseQuerySrc(t)
+
+	assert.Equal(t, 8, Count(f, func(n ast.Node) bool {
+		_, ok := n.(*ast.Ident)
+		return ok
+	}))
+	assert.Equal(t, 1, Count(f, func(n ast.Node) bool {
+		_, ok := n.(*ast.BinaryExpr)
+		return ok
+	}))
+}
+
+func TestStats(t *testing.T) {
+	f := parseQuerySrc(t)
+
+	assert.Equal(t, map[string]int{
+		"*ast.File":       1,
+		"*ast.FuncDecl":   1,
+		"*ast.FuncType":   1,
+		"*ast.FieldList":  2,
+		"*ast.Field":      2,
+		"*ast.Ident":      8,
+		"*ast.BlockStmt":  1,
+		"*ast.ReturnStmt": 1,
+		"*ast.BinaryExpr": 1,
+	}, Stats(f))
+}
+
+func TestFind(t *testing.T) {
+	f := parseQuerySrc(t)
+
+	var visited int
+	n, ok := Find(f, func(n ast.Node) bool {
+		visited++
+		id, isIdent := n.(*ast.Ident)
+		return isIdent && id.Name == "int"
+	})
+	assert.True(t, ok)
+	assert.Same(t, f.Decls[0].(*ast.FuncDecl).Type.Params.List[0].Type, n)
+	// File, foo, FuncDecl, Bar, FuncType, FieldList, Field, a, b, int
+	assert.Equal(t, 10, visited)
+
+	n, ok = Find(f, func(n ast.Node) bool { return false })
+	assert.False(t, ok)
+	assert.Nil(t, n)
+}
+
+func TestBadNodes(t *testing.T) {
+	for src, expected := range map[string][]string{
+		"package foo\n\nfunc Bar() {\n\ta := 1 +\n}\n": {"*ast.BadExpr bad.go:5:1"},
+		"package foo\n\nvar a = 1\nb c\n":              {"*ast.BadDecl bad.go:4:1"},
+		querySrc:                                       nil,
+	} {
+		fset := token.NewFileSet()
+		f, _ := parser.ParseFile(fset, "bad.go", src, parser.AllErrors)
+		var bad []string
+		for _, n := range BadNodes(f) {
+			bad = append(bad, fmt.Sprintf("%T %s", n, fset.Position(n.Pos())))
+		}
+		assert.Equal(t, expected, bad, src)
+	}
+}
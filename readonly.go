@@ -0,0 +1,39 @@
+package astor
+
+import (
+	"go/ast"
+	"sync"
+)
+
+// NewReadOnlyInspector constructs an Inspector which never changes the AST it walks, so a walk can safely share the
+// AST with other goroutines reading it (such as other read-only walks). The methods which would change the AST
+// (Replace, ReplaceAndRevisit, WrapExpr, WrapStmt, Delete, InsertBefore and InsertAfter) panic instead, as do
+// OnEnterList and OnExitList functions which return a list, so a Visitor which tries to change the AST by mistake is
+// found rather than corrupting it. Nodes the Visitor changes in place, or helpers which change the AST themselves
+// (such as EnsureImport), can't be stopped. The Inspector is otherwise as NewInspector constructs it with opts.
+func NewReadOnlyInspector(v Visitor, opts ...Option) Inspector {
+	// as an Option, it's kept by the Inspector's clones
+	return NewInspector(v, append(opts[:len(opts):len(opts)], readOnly)...)
+}
+
+func readOnly(i *inspectorImpl) {
+	i.readOnly = true
+}
+
+// readOnlyPool holds the Inspectors Walk reuses.
+var readOnlyPool = sync.Pool{
+	New: func() interface{} {
+		return NewReadOnlyInspector(nil)
+	},
+}
+
+// Walk walks the AST rooted at node with v, which may not change it, as with an Inspector constructed by
+// NewReadOnlyInspector. Like an InspectorPool, it reuses its Inspectors rather than constructing one for each walk, so
+// repeated walks make no allocations of their own, and v must not keep the Inspector it is passed once Walk returns.
+func Walk(node ast.Node, v Visitor) {
+	i := readOnlyPool.Get().(*inspectorImpl)
+	i.visitorImpl = v
+	i.Inspect(node)
+	i.reset()
+	readOnlyPool.Put(i)
+}
@@ -0,0 +1,70 @@
+package astor
+
+import (
+	"go/ast"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyInspector(t *testing.T) {
+	f := parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\ta()\n\tb()\n}\n")
+
+	// walks sharing the AST don't race (when run with -race)
+	var wg sync.WaitGroup
+	counts := make([]int, 4)
+	for l := range counts {
+		wg.Add(1)
+		go func(l int) {
+			defer wg.Done()
+			Walk(f, func(i Inspector, n ast.Node) bool {
+				if n != nil {
+					counts[l]++
+				}
+				return true
+			})
+		}(l)
+	}
+	wg.Wait()
+	assert.Equal(t, []int{13, 13, 13, 13}, counts)
+
+	changes := map[string]func(i Inspector){
+		"Replace":           func(i Inspector) { i.Replace(callStmt("x")) },
+		"ReplaceAndRevisit": func(i Inspector) { i.ReplaceAndRevisit(callStmt("x")) },
+		"WrapStmt":          func(i Inspector) { i.WrapStmt(func(s ast.Stmt) ast.Stmt { return s }) },
+		"Delete":            func(i Inspector) { i.Delete() },
+		"InsertBefore":      func(i Inspector) { i.InsertBefore(callStmt("x")) },
+		"InsertAfter":       func(i Inspector) { i.InsertAfter(callStmt("x")) },
+	}
+	for method, change := range changes {
+		i := NewReadOnlyInspector(func(i Inspector, n ast.Node) bool {
+			if calledName(n) == "a" {
+				change(i)
+			}
+			return true
+		}).Clone()
+		assert.PanicsWithValue(t, "astor: "+method+" called on a read-only Inspector", func() { i.Inspect(f) })
+	}
+
+	hook := OnEnterList(func(parent ast.Node, field string, items interface{}) interface{} {
+		if stmts, ok := items.([]ast.Stmt); ok {
+			return stmts[1:]
+		}
+		return nil
+	})
+	assert.PanicsWithValue(t, "astor: OnEnterList function changed *ast.BlockStmt.List with a read-only Inspector",
+		func() { NewReadOnlyInspector(func(Inspector, ast.Node) bool { return true }, hook).Inspect(f) })
+	assert.Empty(t, Diff(parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\ta()\n\tb()\n}\n"), f))
+}
+
+func BenchmarkWalk(b *testing.B) {
+	files := smallFiles(b, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for l := 0; l < b.N; l++ {
+		for _, f := range files {
+			Walk(f, noopVisitor)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// Recover makes the Inspector recover from panics in its Visitor, so that a buggy Visitor doesn't end the whole walk.
+// A visit which panics is undone: any replacement or edits it made are discarded (as are the edits it recorded in a
+// dry run, and the change it made to Dirty), its node's children aren't inspected, and nor is it visited again with
+// nil. The walk carries on with the node's siblings, and Err returns a *VisitorPanicError for the first panic once
+// it's over. Only the Inspector's record of the visit is undone: the OnReplace functions already called for a
+// discarded replacement, changes the Visitor made to nodes itself, and the data SetData held for a node it replaced
+// (which is dropped with the replacement) aren't restored.
+func Recover() Option {
+	return func(i *inspectorImpl) {
+		i.recover = true
+	}
+}
+
+// OnVisitorPanic registers a function to be called with the node being visited and the recovered value each time the
+// Visitor panics, implying Recover. If any of the functions returns false the walk stops, as it does when the Visitor
+// calls Stop. Functions are called in the order they were registered.
+func OnVisitorPanic(f func(node ast.Node, recovered interface{}) bool) Option {
+	return func(i *inspectorImpl) {
+		i.recover = true
+		i.onPanic = append(i.onPanic, f)
+	}
+}
+
+// A VisitorPanicError is returned by Err after a walk in which the Visitor panicked, for an Inspector constructed with
+// Recover. Node is the node being visited (or finished with the nil visit) when it panicked.
+type VisitorPanicError struct {
+	Node      ast.Node
+	Recovered interface{}
+}
+
+func (e *VisitorPanicError) Error() string {
+	return fmt.Sprintf("astor: Visitor panicked visiting %T: %v", e.Node, e.Recovered)
+}
+
+// callVisitor calls the Visitor for n, recovering from a panic if i was constructed with Recover.
+func (i *inspectorImpl) callVisitor(n ast.Node) (result bool) {
+	if !i.recover {
+		return i.visitorImpl(i, n)
+	}
+
+	node, edit, skip, skipFields, dirty, edits := i.node, i.edit, i.skip, i.skipFields, i.dirty, len(i.edits)
+	finished := i.finished
+	defer func() {
+		if r := recover(); r != nil {
+			i.node, i.edit, i.skip, i.skipFields, i.revisit = node, edit, skip, skipFields, false
+			i.dirty, i.edits, i.finished = dirty, i.edits[:edits], finished
+			i.recovered(r)
+			result = false
+		}
+	}()
+	return i.visitorImpl(i, n)
+}
+
+// recovered records the value r recovered from a panic in the Visitor, and calls the OnVisitorPanic functions.
+func (i *inspectorImpl) recovered(r interface{}) {
+	node := i.node
+	if node == nil {
+		node = i.original
+	}
+	if i.err == nil {
+		i.err = &VisitorPanicError{Node: node, Recovered: r}
+	}
+	for _, f := range i.onPanic {
+		if !f(node, r) {
+			i.stopped = true
+		}
+	}
+}
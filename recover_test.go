@@ -0,0 +1,96 @@
+package astor
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecover(t *testing.T) {
+	src := "package foo\n\nfunc Bar() {\n\ta(b)\n\tc()\n}\n"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "recover.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var names []string
+	visitor := func(i Inspector, n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		names = append(names, id.Name)
+		switch id.Name {
+		case "a":
+			// the replacement is discarded along with the visit
+			i.Replace(ast.NewIdent("d"))
+			panic("a")
+		case "c":
+			i.Replace(ast.NewIdent("e"))
+		}
+		return true
+	}
+	inspector := NewInspector(visitor, Recover())
+	inspector.Inspect(f)
+	assert.Equal(t, []string{"foo", "Bar", "a", "b", "c"}, names)
+	var panicErr *VisitorPanicError
+	assert.ErrorAs(t, inspector.Err(), &panicErr)
+	assert.Equal(t, "a", panicErr.Node.(*ast.Ident).Name)
+	assert.Equal(t, "a", panicErr.Recovered)
+	assert.EqualError(t, panicErr, "astor: Visitor panicked visiting *ast.Ident: a")
+
+	var out strings.Builder
+	assert.NoError(t, format.Node(&out, fset, f))
+	assert.Equal(t, "package foo\n\nfunc Bar() {\n\ta(b)\n\te()\n}\n", out.String())
+
+	// The Inspector can be used again after a panic, and the hook can stop the walk
+	names = nil
+	var panicked []ast.Node
+	inspector = NewInspector(visitor, OnVisitorPanic(func(n ast.Node, recovered interface{}) bool {
+		panicked = append(panicked, n)
+		return false
+	}))
+	inspector.Inspect(f)
+	inspector.Inspect(f)
+	assert.Equal(t, []string{"foo", "Bar", "a", "foo", "Bar", "a"}, names)
+	assert.Len(t, panicked, 2)
+	assert.ErrorAs(t, inspector.Err(), &panicErr)
+
+	// Without Recover, the panic isn't caught
+	assert.Panics(t, func() {
+		NewInspector(visitor).Inspect(f)
+	})
+}
+
+func TestRecoverUndoesVisitState(t *testing.T) {
+	f := parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\ta(b)\n}\n")
+	visitor := func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "a" {
+			i.Replace(ast.NewIdent("d"))
+			panic("a")
+		}
+		return true
+	}
+
+	// the failed visit doesn't leave its node, or those enclosing it, dirty
+	var dirty []bool
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		if n == nil {
+			dirty = append(dirty, i.Dirty())
+			return true
+		}
+		return visitor(i, n)
+	}, Recover()).Inspect(f)
+	assert.NotEmpty(t, dirty)
+	assert.NotContains(t, dirty, true)
+
+	// nor are its edits recorded in a dry run
+	inspector := NewInspector(visitor, Recover())
+	assert.Empty(t, InspectDryRun(inspector, f))
+	var panicErr *VisitorPanicError
+	assert.ErrorAs(t, inspector.Err(), &panicErr)
+}
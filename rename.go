@@ -0,0 +1,47 @@
+package astor
+
+import (
+	"go/ast"
+)
+
+// Rename renames every identifier named from in the AST rooted at node (including node itself) to to, returning the
+// number renamed. It matches names textually, so also renames unrelated identifiers which share the name, such as
+// fields selected from other types or variables shadowing the one intended; RenameObject avoids this.
+func Rename(node ast.Node, from, to string) int {
+	r := &renamer{to: to, match: func(id *ast.Ident) bool {
+		return id.Name == from
+	}}
+	NewInspector(r.visit).Inspect(node)
+	return r.n
+}
+
+// RenameObject renames each identifier in the AST rooted at node (including node itself) which refers to obj, as
+// resolved by go/parser, to to, returning the number renamed. obj is renamed too. Only identifiers resolved within
+// the file are linked to their objects, so references from other files (and selectors such as the fields and methods
+// of a type) are left alone. If obj is nil, as it is for an unresolved identifier, nothing is renamed.
+func RenameObject(node ast.Node, obj *ast.Object, to string) int {
+	if obj == nil {
+		// unresolved identifiers, such as package names and selectors, all have a nil Obj
+		return 0
+	}
+	r := &renamer{to: to, match: func(id *ast.Ident) bool {
+		return id.Obj == obj
+	}}
+	NewInspector(r.visit).Inspect(node)
+	obj.Name = to
+	return r.n
+}
+
+type renamer struct {
+	to    string
+	match func(*ast.Ident) bool
+	n     int
+}
+
+func (r *renamer) visit(i Inspector, n ast.Node) bool {
+	if id, ok := n.(*ast.Ident); ok && id.Name != r.to && r.match(id) {
+		i.Replace(&ast.Ident{NamePos: id.NamePos, Name: r.to, Obj: id.Obj})
+		r.n++
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package astor
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const renameSrc = `package foo
+
+func Bar(x int) int {
+	y := x + 1
+	{
+		x := y
+		y = x
+	}
+	return y
+}
+`
+
+func TestRename(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "rename.go", renameSrc, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	assert.Equal(t, 4, Rename(f, "x", "z"))
+	assert.Equal(t, 0, Rename(f, "x", "z"))
+
+	var out strings.Builder
+	assert.NoError(t, format.Node(&out, fset, f))
+	assert.Equal(t, strings.ReplaceAll(renameSrc, "x", "z"), out.String())
+}
+
+func TestRenameObject(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "rename.go", renameSrc, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	// Only the parameter, not the x shadowing it in the block
+	param := f.Decls[0].(*ast.FuncDecl).Type.Params.List[0].Names[0].Obj
+	assert.Equal(t, 2, RenameObject(f, param, "z"))
+	assert.Equal(t, "z", param.Name)
+
+	var out strings.Builder
+	assert.NoError(t, format.Node(&out, fset, f))
+	assert.Equal(t, strings.Replace(strings.Replace(renameSrc, "x", "z", 1), "x", "z", 1), out.String())
+
+	// package names, builtins and selectors are all unresolved
+	const unresolvedSrc = "package foo\n\nimport \"fmt\"\n\nfunc Bar() {\n\tfmt.Println(len(\"x\"))\n}\n"
+	f = parseEqualSrc(t, unresolvedSrc)
+	assert.Equal(t, 0, RenameObject(f, nil, "y"))
+	assert.Empty(t, Diff(parseEqualSrc(t, unresolvedSrc), f))
+}
@@ -0,0 +1,83 @@
+package astor
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// ReplaceWithMany replaces the node i is visiting, which must be held in a list, with nodes: the first replaces it,
+// and is inspected as a replacement is, and the rest are inserted after it, as with InsertAfter. If there are no
+// nodes, the node is deleted. Each node must fit in the list, as with Replace.
+//
+// This splits a declaration of a file into several, such as a grouped var (...) into a var for each of its specs. A
+// declaration's doc comment moves to the first of the declarations replacing it, unless that has its own. Since the
+// imports of a file must come before its other declarations, ReplaceWithMany panics if the declarations would put an
+// import after a declaration which isn't one.
+func ReplaceWithMany(i Inspector, nodes ...ast.Node) {
+	if len(nodes) == 0 {
+		i.Delete()
+		return
+	}
+
+	if f, ok := i.Parent().(*ast.File); ok && i.Index() >= 0 {
+		decls := append(append(append([]ast.Node{}, declNodes(f.Decls[:i.Index()])...), nodes...),
+			declNodes(f.Decls[i.Index()+1:])...)
+		if !importsFirst(decls) {
+			panic("astor: ReplaceWithMany called with declarations which put an import after other declarations")
+		}
+		moveDoc(i.Current(), nodes[0])
+	}
+
+	i.Replace(nodes[0])
+	for _, n := range nodes[1:] {
+		i.InsertAfter(n)
+	}
+}
+
+func declNodes(decls []ast.Decl) []ast.Node {
+	nodes := make([]ast.Node, len(decls))
+	for l, d := range decls {
+		nodes[l] = d
+	}
+	return nodes
+}
+
+// importsFirst reports whether all the import declarations in decls come before all the other declarations.
+func importsFirst(decls []ast.Node) bool {
+	others := false
+	for _, d := range decls {
+		d, ok := d.(*ast.GenDecl)
+		switch {
+		case !ok || d.Tok != token.IMPORT:
+			others = true
+		case others:
+			return false
+		}
+	}
+	return true
+}
+
+// moveDoc moves the doc comment of the declaration from to the declaration to, if to has none of its own.
+func moveDoc(from, to ast.Node) {
+	var doc *ast.CommentGroup
+	switch from := from.(type) {
+	case *ast.GenDecl:
+		doc = from.Doc
+	case *ast.FuncDecl:
+		doc = from.Doc
+	}
+	if doc == nil {
+		return
+	}
+
+	switch to := to.(type) {
+	case *ast.GenDecl:
+		if to.Doc == nil {
+			to.Doc = doc
+		}
+	case *ast.FuncDecl:
+		if to.Doc == nil {
+			to.Doc = doc
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package astor
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceWithMany(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "replace.go", `package foo
+
+import "fmt"
+
+// xy are x and y
+var (
+	x = 1
+	y = 2
+)
+
+func Bar() {
+	a()
+	b()
+}
+`, parser.ParseComments)
+	assert.NoError(t, err)
+
+	var visited []string
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GenDecl:
+			if n.Tok == token.IMPORT {
+				assert.Panics(t, func() {
+					ReplaceWithMany(i, n, &ast.GenDecl{Tok: token.VAR}, &ast.GenDecl{Tok: token.IMPORT})
+				})
+				return false
+			}
+			// split the group into a var for each spec
+			var decls []ast.Node
+			for _, s := range n.Specs {
+				decls = append(decls, &ast.GenDecl{Tok: n.Tok, Specs: []ast.Spec{s}})
+			}
+			ReplaceWithMany(i, decls...)
+		case *ast.ExprStmt:
+			if n.X.(*ast.CallExpr).Fun.(*ast.Ident).Name == "a" {
+				ReplaceWithMany(i)
+			}
+		case *ast.Ident:
+			visited = append(visited, n.Name)
+		}
+		return true
+	}).Inspect(f)
+	// the specs are inspected as the children of the first replacement, but the rest of the replacements aren't
+	assert.Equal(t, []string{"foo", "x", "Bar", "b"}, visited)
+
+	assert.Empty(t, Diff(parseEqualSrc(t, `package foo
+
+import "fmt"
+
+// xy are x and y
+var x = 1
+
+var y = 2
+
+func Bar() {
+	b()
+}
+`), f))
+}
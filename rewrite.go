@@ -0,0 +1,170 @@
+package astor
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"unicode"
+)
+
+// Rewrite applies a rewrite rule, like those of gofmt -r, to the AST rooted at node (including node itself), returning
+// the modified tree. pattern and replacement are Go expressions, in which identifiers made of a single lowercase
+// letter are wildcards: each expression matching pattern (as for Match) is replaced with replacement, with each of its
+// wildcards substituted by the subtree it captured. If pattern or replacement can't be parsed the error is a
+// *ParseError.
+//
+// Like gofmt, the innermost matches are rewritten first: a node's children are rewritten before it is matched, so the
+// subtrees its wildcards capture are already rewritten, and a replacement is not rewritten again. Nodes are left alone
+// where their replacement can't be held.
+func Rewrite(node ast.Node, pattern, replacement string) (ast.Node, error) {
+	p, err := parser.ParseExpr(pattern)
+	if err != nil {
+		return nil, &ParseError{Err: err}
+	}
+	r, err := parser.ParseExpr(replacement)
+	if err != nil {
+		return nil, &ParseError{Err: err}
+	}
+
+	rw := &rewriter{pattern: p, replacement: r, wildcards: make(map[string]*ast.Ident)}
+	ast.Inspect(p, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && isWildcardName(id.Name) {
+			rw.wildcards[id.Name] = nil
+		}
+		return true
+	})
+	return NewInspector(rw.visit).Inspect(node), nil
+}
+
+// isWildcardName reports whether name is a wildcard in a rewrite rule: a single lowercase letter.
+func isWildcardName(name string) bool {
+	runes := []rune(name)
+	return len(runes) == 1 && unicode.IsLower(runes[0])
+}
+
+type rewriter struct {
+	pattern, replacement ast.Node
+	wildcards            map[string]*ast.Ident
+}
+
+// visit matches each node against the pattern in its terminating nil visit, once its children have been rewritten.
+func (rw *rewriter) visit(i Inspector, n ast.Node) bool {
+	if n != nil {
+		return true
+	}
+	// the node being finished hasn't been replaced, as nodes are only replaced here
+	n = i.Original()
+	if _, ok := n.(ast.Expr); !ok {
+		// the pattern is an expression, though a bare wildcard would match anything
+		return true
+	}
+	captured, ok := matchPattern(rw.pattern, n, rw.wildcards)
+	if !ok {
+		return true
+	}
+
+	r := substitute(reflect.ValueOf(&rw.replacement).Elem(), captured).Interface().(ast.Node)
+	SetPositions(r, n)
+	if impl, ok := i.(*inspectorImpl); ok && !impl.slot.holds(r) {
+		return true
+	}
+	i.Replace(r)
+	return true
+}
+
+// substitute returns a copy of v, a value in a rewrite rule's replacement, with each wildcard replaced by a copy of the
+// subtree it captured, so a wildcard used more than once doesn't share nodes. Positions in the copy are missing, other
+// than those of the captured subtrees, since they are from the rule rather than the AST being rewritten.
+func substitute(v reflect.Value, captured map[string]ast.Node) reflect.Value {
+	if v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if id, ok := v.Interface().(*ast.Ident); ok && id != nil {
+			if c, ok := captured[id.Name]; ok && reflect.TypeOf(c).AssignableTo(v.Type()) {
+				r := reflect.New(v.Type()).Elem()
+				r.Set(clone(reflect.ValueOf(c)))
+				return r
+			}
+		}
+	}
+
+	switch t := v.Type(); {
+	case t == posType:
+		return reflect.ValueOf(token.NoPos)
+	case t == objectType, t == scopeType:
+		return reflect.Zero(t)
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		r := reflect.New(v.Type()).Elem()
+		if v.Kind() == reflect.Interface {
+			r.Set(substitute(v.Elem(), captured))
+		} else {
+			r.Set(reflect.New(v.Type().Elem()))
+			r.Elem().Set(substitute(v.Elem(), captured))
+		}
+		return r
+
+	case reflect.Struct:
+		r := reflect.New(v.Type()).Elem()
+		for l := 0; l < v.NumField(); l++ {
+			r.Field(l).Set(substitute(v.Field(l), captured))
+		}
+		return r
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		r := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for l := 0; l < v.Len(); l++ {
+			r.Index(l).Set(substitute(v.Index(l), captured))
+		}
+		return r
+	}
+	return v
+}
+
+// clone returns a deep copy of v, a value in a subtree captured by a wildcard, with its positions. The *ast.Object and
+// *ast.Scope of resolved identifiers are shared with the original, since they aren't part of the tree.
+func clone(v reflect.Value) reflect.Value {
+	if t := v.Type(); t == objectType || t == scopeType {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		r := reflect.New(v.Type()).Elem()
+		if v.Kind() == reflect.Interface {
+			r.Set(clone(v.Elem()))
+		} else {
+			r.Set(reflect.New(v.Type().Elem()))
+			r.Elem().Set(clone(v.Elem()))
+		}
+		return r
+
+	case reflect.Struct:
+		r := reflect.New(v.Type()).Elem()
+		for l := 0; l < v.NumField(); l++ {
+			r.Field(l).Set(clone(v.Field(l)))
+		}
+		return r
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		r := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for l := 0; l < v.Len(); l++ {
+			r.Index(l).Set(clone(v.Index(l)))
+		}
+		return r
+	}
+	return v
+}
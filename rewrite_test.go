@@ -0,0 +1,60 @@
+package astor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewrite(t *testing.T) {
+	src := "package foo\n\nfunc Bar() {\n\tx := a[1:len(a)]\n\ty := b[2:len(b)]\n\tz := c[1:len(d)]\n\t_ = e[0:len(e)][f[0:len(f)][0]:len(e[0:len(e)])]\n}\n"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "rewrite.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	result, err := Rewrite(f, "a[b:len(a)]", "a[b:]")
+	assert.NoError(t, err)
+
+	out := new(bytes.Buffer)
+	assert.NoError(t, format.Node(out, fset, result))
+	assert.Equal(t, "package foo\n\nfunc Bar() {\n\tx := a[1:]\n\ty := b[2:]\n\tz := c[1:len(d)]\n\t_ = e[0:][f[0:][0]:]\n}\n",
+		out.String())
+
+	_, err = Rewrite(f, "a[", "a")
+	assert.IsType(t, &ParseError{}, err)
+	_, err = Rewrite(f, "a", "a +")
+	assert.IsType(t, &ParseError{}, err)
+}
+
+func TestRewriteClonesCaptures(t *testing.T) {
+	f := parseEqualSrc(t, "package foo\n\nvar v = foo(h(1))\n")
+	captured := f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0].(*ast.CallExpr).Args[0]
+
+	_, err := Rewrite(f, "foo(x)", "bar(x, x)")
+	assert.NoError(t, err)
+	assert.Empty(t, Diff(parseEqualSrc(t, "package foo\n\nvar v = bar(h(1), h(1))\n"), f))
+	args := f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0].(*ast.CallExpr).Args
+	assert.NotSame(t, args[0], args[1])
+	assert.NotSame(t, captured, args[0])
+	assert.NotSame(t, args[0].(*ast.CallExpr).Args[0], args[1].(*ast.CallExpr).Args[0])
+	assert.Equal(t, captured.Pos(), args[0].Pos())
+}
+
+func TestRewriteWildcardPattern(t *testing.T) {
+	f := parseEqualSrc(t, "package foo\n\nvar v = a + b\n")
+	result, err := Rewrite(f, "x", "f(x)")
+	assert.NoError(t, err)
+	// the identifiers naming the package and the variable can't hold a call, so are left alone
+	assert.Empty(t, Diff(parseEqualSrc(t, "package foo\n\nvar v = f(f(a) + f(b))\n"), result))
+
+	x, err := parser.ParseExpr("a")
+	assert.NoError(t, err)
+	result, err = Rewrite(x, "x", "f(x)")
+	assert.NoError(t, err)
+	assert.True(t, Equal(&ast.CallExpr{Fun: ast.NewIdent("f"), Args: []ast.Expr{ast.NewIdent("a")}}, result))
+}
@@ -0,0 +1,173 @@
+package astor
+
+import (
+	"go/ast"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// A Scope is a lexical scope: the identifiers declared in a block of source, and the scope enclosing it.
+type Scope struct {
+	// Outer is the enclosing scope, or nil for the scope of a file
+	Outer *Scope
+	// Node is the node which opened the scope
+	Node ast.Node
+	// decls maps the names declared in the scope to the identifiers declaring them
+	decls map[string]*ast.Ident
+}
+
+// Lookup returns the identifier declaring name in s or, failing that, the innermost of the scopes enclosing it to
+// declare it. It returns nil if name isn't declared.
+func (s *Scope) Lookup(name string) *ast.Ident {
+	for ; s != nil; s = s.Outer {
+		if id, ok := s.decls[name]; ok {
+			return id
+		}
+	}
+	return nil
+}
+
+// Names returns the names declared in s (but not the scopes enclosing it), in order.
+func (s *Scope) Names() []string {
+	names := make([]string, 0, len(s.decls))
+	for name := range s.decls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Scope) declare(ids ...*ast.Ident) {
+	for _, id := range ids {
+		if id == nil || id.Name == "_" {
+			continue
+		}
+		if s.decls == nil {
+			s.decls = make(map[string]*ast.Ident)
+		}
+		s.decls[id.Name] = id
+	}
+}
+
+func (s *Scope) declareFields(fields *ast.FieldList) {
+	if fields == nil {
+		return
+	}
+	for _, f := range fields.List {
+		s.declare(f.Names...)
+	}
+}
+
+// WithScopes makes the Inspector track the lexical scope of each node as it walks, which the Visitor can query with
+// Scope. Scopes are opened by files (holding the file's imports and top-level declarations), function declarations
+// and literals (holding their receiver, type parameters, parameters and results), blocks, and the implicit blocks of
+// if, for, range, switch and type switch statements and their clauses. The scope opened by a node encloses its
+// children, but not the node itself.
+//
+// Names declared within a function are added to the innermost scope once their declaring statement has been
+// inspected, so they are only in scope after it, as in Go. An import is assumed to declare the last element of its
+// path, unless it is explicitly named.
+func WithScopes() Option {
+	return func(i *inspectorImpl) {
+		i.scopes = true
+	}
+}
+
+func (i *inspectorImpl) Scope() *Scope {
+	return i.scope
+}
+
+// openScope opens the scope of node, if it has one, declaring the names it declares for its children. It reports
+// whether it opened a scope.
+func (i *inspectorImpl) openScope(node ast.Node) bool {
+	s := &Scope{Outer: i.scope, Node: node}
+	switch n := node.(type) {
+	case *ast.File:
+		for _, decl := range n.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					declareSpec(s, spec)
+				}
+			case *ast.FuncDecl:
+				// methods aren't declared in the file's scope
+				if d.Recv == nil {
+					s.declare(d.Name)
+				}
+			}
+		}
+
+	case *ast.FuncDecl:
+		s.declareFields(n.Recv)
+		s.declareFields(n.Type.TypeParams)
+		s.declareFields(n.Type.Params)
+		s.declareFields(n.Type.Results)
+
+	case *ast.FuncLit:
+		s.declareFields(n.Type.Params)
+		s.declareFields(n.Type.Results)
+
+	case *ast.RangeStmt:
+		if n.Tok == token.DEFINE {
+			for _, x := range []ast.Expr{n.Key, n.Value} {
+				if id, ok := x.(*ast.Ident); ok {
+					s.declare(id)
+				}
+			}
+		}
+
+	case *ast.BlockStmt, *ast.IfStmt, *ast.ForStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.CaseClause,
+		*ast.CommClause:
+		// these declare nothing themselves
+
+	default:
+		return false
+	}
+
+	i.scope = s
+	return true
+}
+
+// declare adds the names declared by node, if it is a statement declaring names, to the current scope.
+func (i *inspectorImpl) declare(node ast.Node) {
+	if i.scope == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *ast.AssignStmt:
+		if n.Tok == token.DEFINE {
+			for _, x := range n.Lhs {
+				if id, ok := x.(*ast.Ident); ok {
+					i.scope.declare(id)
+				}
+			}
+		}
+
+	case *ast.DeclStmt:
+		if d, ok := n.Decl.(*ast.GenDecl); ok {
+			for _, spec := range d.Specs {
+				declareSpec(i.scope, spec)
+			}
+		}
+	}
+}
+
+func declareSpec(s *Scope, spec ast.Spec) {
+	switch sp := spec.(type) {
+	case *ast.ImportSpec:
+		if sp.Name != nil {
+			if sp.Name.Name != "." {
+				s.declare(sp.Name)
+			}
+		} else if p, err := strconv.Unquote(sp.Path.Value); err == nil {
+			s.declare(&ast.Ident{NamePos: sp.Path.Pos(), Name: path.Base(p)})
+		}
+	case *ast.ValueSpec:
+		s.declare(sp.Names...)
+	case *ast.TypeSpec:
+		s.declare(sp.Name)
+	}
+}
@@ -0,0 +1,72 @@
+package astor
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const scopeSrc = `package foo
+
+import "fmt"
+
+var g int
+
+func Bar(a int) (r int) {
+	b := a
+	if c := b; c > 0 {
+		d := c
+		use(d)
+	}
+	for i, v := range []int{} {
+		use(i, v)
+	}
+	fn := func(p int) { use(p) }
+	use(b)
+	return
+}
+`
+
+func TestWithScopes(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "scope.go", scopeSrc, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	// names of interest visible from each call to use, in order
+	names := []string{"fmt", "g", "Bar", "a", "r", "b", "c", "d", "i", "v", "fn", "p"}
+	var visible [][]string
+	visitor := func(i Inspector, n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if fun, ok := call.Fun.(*ast.Ident); ok && fun.Name == "use" {
+				var in []string
+				for _, name := range names {
+					if i.Scope().Lookup(name) != nil {
+						in = append(in, name)
+					}
+				}
+				visible = append(visible, in)
+			}
+		}
+		return true
+	}
+	NewInspector(visitor, WithScopes()).Inspect(f)
+
+	assert.Equal(t, [][]string{
+		{"fmt", "g", "Bar", "a", "r", "b", "c", "d"},
+		{"fmt", "g", "Bar", "a", "r", "b", "i", "v"},
+		{"fmt", "g", "Bar", "a", "r", "b", "p"},
+		{"fmt", "g", "Bar", "a", "r", "b", "fn"},
+	}, visible)
+}
+
+func TestScopeWithoutOption(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "scope.go", scopeSrc, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		assert.Nil(t, i.Scope())
+		return true
+	}).Inspect(f)
+}
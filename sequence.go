@@ -0,0 +1,53 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"strings"
+)
+
+// Sequence returns a description of each node of the AST rooted at node, one per line, in the order the Inspector
+// visits them (pre-order). Each is indented by its depth, and gives the field of its parent holding it (with its
+// index, if it's in a list), its type, and those of its fields holding strings, booleans, tokens and other numbers
+// which aren't zero, such as `Decls[0]: *ast.FuncDecl` or `X: *ast.BasicLit Kind=INT Value="1"`. Positions aren't
+// included, so the description only changes when the structure of the AST, or the way it's walked, does.
+//
+// This is meant for snapshot tests of transforms, and also shows exactly which fields the Inspector descends into.
+func Sequence(node ast.Node) []string {
+	var (
+		seq   []string
+		depth int
+	)
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		if n == nil {
+			depth--
+			return true
+		}
+
+		var b strings.Builder
+		b.WriteString(strings.Repeat("  ", depth))
+		if name := i.FieldName(); name != "" {
+			b.WriteString(name + ": ")
+		}
+		fmt.Fprintf(&b, "%T", n)
+		scalarFields(n, func(name string, value interface{}) {
+			if reflect.ValueOf(value).IsZero() {
+				return
+			}
+			switch value := value.(type) {
+			case string:
+				fmt.Fprintf(&b, " %s=%q", name, value)
+			case token.Token:
+				fmt.Fprintf(&b, " %s=%s", name, value)
+			default:
+				fmt.Fprintf(&b, " %s=%v", name, value)
+			}
+		})
+		seq = append(seq, b.String())
+		depth++
+		return true
+	}).Inspect(node)
+	return seq
+}
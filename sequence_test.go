@@ -0,0 +1,36 @@
+package astor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequence(t *testing.T) {
+	f := parseEqualSrc(t, "package foo\n\n// Bar returns one.\nfunc Bar(a ...int) int { return -1 }\n")
+	assert.Equal(t, []string{
+		"*ast.File",
+		"  Name: *ast.Ident Name=\"foo\"",
+		"  Decls[0]: *ast.FuncDecl",
+		"    Doc: *ast.CommentGroup",
+		"      List[0]: *ast.Comment Text=\"// Bar returns one.\"",
+		"    Name: *ast.Ident Name=\"Bar\"",
+		"    Type: *ast.FuncType",
+		"      Params: *ast.FieldList",
+		"        List[0]: *ast.Field",
+		"          Names[0]: *ast.Ident Name=\"a\"",
+		"          Type: *ast.Ellipsis",
+		"            Elt: *ast.Ident Name=\"int\"",
+		"      Results: *ast.FieldList",
+		"        List[0]: *ast.Field",
+		"          Type: *ast.Ident Name=\"int\"",
+		"    Body: *ast.BlockStmt",
+		"      List[0]: *ast.ReturnStmt",
+		"        Results[0]: *ast.UnaryExpr Op=-",
+		"          X: *ast.BasicLit Kind=INT Value=\"1\"",
+	}, Sequence(f))
+
+	// the description doesn't depend on positions
+	g := parseEqualSrc(t, "package foo\n\n\n// Bar returns one.\nfunc Bar(a ...int) int {\n\treturn -1\n}\n")
+	assert.Equal(t, Sequence(f), Sequence(g))
+}
@@ -0,0 +1,43 @@
+package astor
+
+import (
+	"go/ast"
+)
+
+// SkipSharedNodes configures an Inspector to visit each node only the first time it is reached in a walk, so a node
+// held in more than one place in the AST is left as it is in the others (which still hold it, as changed by its first
+// visit, if the Visitor changed it in place rather than replacing it). This includes a node reached again because the
+// Visitor replaced a node with one holding it, as WrapExpr does.
+func SkipSharedNodes() Option {
+	return func(i *inspectorImpl) {
+		i.skipShared = true
+	}
+}
+
+// OnSharedNode registers a function to be called each time the Inspector reaches a node it has already visited in the
+// walk, as it does for a node held in more than one place in the AST, before it visits it again (or skips it, with
+// SkipSharedNodes).
+func OnSharedNode(f func(node ast.Node)) Option {
+	return func(i *inspectorImpl) {
+		i.onShared = append(i.onShared, f)
+	}
+}
+
+// shared reports whether n has been visited already in the walk, and is to be skipped, calling the OnSharedNode
+// functions if it has been visited.
+func (i *inspectorImpl) shared(n ast.Node) bool {
+	if !i.skipShared && len(i.onShared) == 0 {
+		return false
+	}
+	if !i.seen[n] {
+		if i.seen == nil {
+			i.seen = make(map[ast.Node]bool)
+		}
+		i.seen[n] = true
+		return false
+	}
+	for _, f := range i.onShared {
+		f(n)
+	}
+	return i.skipShared
+}
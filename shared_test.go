@@ -0,0 +1,43 @@
+package astor
+
+import (
+	"go/ast"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedNodes(t *testing.T) {
+	shared := ast.NewIdent("a")
+	expr := &ast.BinaryExpr{X: shared, Op: token.ADD, Y: shared}
+
+	var visits int
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n == ast.Node(shared) {
+			visits++
+		}
+		return true
+	}
+
+	// by default, a shared node is visited each time it's reached
+	NewInspector(visitor).Inspect(expr)
+	assert.Equal(t, 2, visits)
+
+	var reported []ast.Node
+	visits = 0
+	NewInspector(visitor, OnSharedNode(func(n ast.Node) { reported = append(reported, n) })).Inspect(expr)
+	assert.Equal(t, 2, visits)
+	assert.Equal(t, []ast.Node{shared}, reported)
+
+	reported, visits = nil, 0
+	i := NewInspector(visitor, SkipSharedNodes(), OnSharedNode(func(n ast.Node) { reported = append(reported, n) }))
+	i.Inspect(expr)
+	assert.Equal(t, 1, visits)
+	assert.Equal(t, []ast.Node{shared}, reported)
+
+	// each walk starts afresh
+	visits = 0
+	InspectIterative(i, expr)
+	assert.Equal(t, 1, visits)
+}
@@ -0,0 +1,125 @@
+package astor
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+)
+
+// A ParseError is returned when source can't be parsed. Err is the error from go/parser, usually a
+// scanner.ErrorList.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return "astor: parsing source: " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// A FormatError is returned when an AST can't be formatted back into source after it has been inspected.
+type FormatError struct {
+	Err error
+}
+
+func (e *FormatError) Error() string {
+	return "astor: formatting source: " + e.Err.Error()
+}
+
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// InspectSource parses src as a Go source file (including its comments), walks it with an Inspector constructed with
+// the passed Visitor and Options, and returns the modified tree formatted as source. If src can't be parsed the error
+// is a *ParseError, and if the modified tree can't be formatted it is a *FormatError. If the walk ends with an error
+// (see Inspector.Err), such as a *DepthError, that error is returned, without the partly modified source.
+func InspectSource(src []byte, v Visitor, opts ...Option) ([]byte, error) {
+	return inspectSource(NewInspector(v, opts...), "", src)
+}
+
+// InspectReader is InspectSource for the source read from r, such as os.Stdin, which is reported as filename in the
+// positions of parse errors. An error reading r is returned as it is.
+func InspectReader(r io.Reader, filename string, v Visitor, opts ...Option) ([]byte, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return inspectSource(NewInspector(v, opts...), filename, src)
+}
+
+func inspectSource(i Inspector, filename string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, &ParseError{Err: err}
+	}
+	if impl, ok := i.(*inspectorImpl); ok && impl.skipGenerated && IsGenerated(f) {
+		return src, nil
+	}
+
+	result := i.Inspect(f)
+	if err := i.Err(); err != nil {
+		return nil, err
+	}
+
+	out := new(bytes.Buffer)
+	if err := format.Node(out, fset, result); err != nil {
+		return nil, &FormatError{Err: err}
+	}
+	return out.Bytes(), nil
+}
+
+// InspectExprString parses src as a Go expression, walks it like InspectExpr with an Inspector constructed with the
+// passed Visitor and Options, and returns the modified expression. If src can't be parsed the error is a *ParseError,
+// and if the walk ends with an error (see Inspector.Err) that error is returned, with the partly modified expression.
+// It is handy for trying a Visitor out on a snippet of code, without a file to hold it.
+func InspectExprString(src string, v Visitor, opts ...Option) (ast.Expr, error) {
+	e, err := parser.ParseExpr(src)
+	if err != nil {
+		return nil, &ParseError{Err: err}
+	}
+	i := NewInspector(v, opts...)
+	e = InspectExpr(i, e)
+	return e, i.Err()
+}
+
+// InspectStmtString is InspectExprString for a single Go statement, which is parsed as the body of a function (so a
+// return statement or a label is allowed), and walked like InspectStmt. The positions of the statement, and of any
+// parse errors, are those of the function holding it: its lines are counted from the first, but its columns are
+// not. If src holds no statements or several, the error is a *ParseError.
+func InspectStmtString(src string, v Visitor, opts ...Option) (ast.Stmt, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package p; func _() { "+src+"\n}", 0)
+	if err != nil {
+		return nil, &ParseError{Err: err}
+	}
+	stmts := f.Decls[0].(*ast.FuncDecl).Body.List
+	if len(stmts) != 1 || len(f.Decls) != 1 {
+		return nil, &ParseError{Err: errors.New("expected a single statement")}
+	}
+	i := NewInspector(v, opts...)
+	s := InspectStmt(i, stmts[0])
+	return s, i.Err()
+}
+
+// IdentityWalk parses src like InspectSource, walks it with an Inspector which replaces every node with itself, and
+// returns the tree formatted as source. Since every node is stored back where it was found, the result should be
+// exactly src as gofmt would format it (see go/format.Source): any other result means the walk itself changed the
+// source, so this can be used to check that a construct (or an Option) is walked faithfully.
+func IdentityWalk(src []byte, opts ...Option) ([]byte, error) {
+	return InspectSource(src, identityVisitor, opts...)
+}
+
+func identityVisitor(i Inspector, n ast.Node) bool {
+	if n != nil {
+		i.Replace(n)
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+package astor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/scanner"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspectSource(t *testing.T) {
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n, ok := n.(*ast.FuncDecl); ok {
+			n.Name = ast.NewIdent(fmt.Sprintf("Foo%s", n.Name.String()))
+			return false
+		}
+		return true
+	}
+
+	out, err := InspectSource([]byte("package foo\n\n// Bar is a bar\nfunc Bar() {}\n"), visitor)
+	assert.NoError(t, err)
+	assert.Equal(t, "package foo\n\n// Bar is a bar\nfunc FooBar() {}\n", string(out))
+}
+
+func TestInspectSourceErrors(t *testing.T) {
+	noop := func(i Inspector, n ast.Node) bool {
+		return true
+	}
+
+	_, err := InspectSource([]byte("package foo\n\nfunc {"), noop)
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr), "Expected a *ParseError, got %v", err)
+	var scanErrs scanner.ErrorList
+	assert.True(t, errors.As(err, &scanErrs), "Expected the parser's errors to be wrapped")
+
+	// go/format can't print a package
+	toPackage := func(i Inspector, n ast.Node) bool {
+		if _, ok := n.(*ast.File); ok {
+			i.Replace(&ast.Package{Name: "foo"})
+			return false
+		}
+		return true
+	}
+	_, err = InspectSource([]byte("package foo\n"), toPackage)
+	var formatErr *FormatError
+	assert.True(t, errors.As(err, &formatErr), "Expected a *FormatError, got %v", err)
+
+	// a walk ended by an error returns it, rather than the partly modified source
+	rename := func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "a" {
+			i.Replace(ast.NewIdent("aX"))
+		}
+		return true
+	}
+	src := []byte("package foo\n\nfunc F() { a(); b(); c() }\n")
+	out, err := InspectSource(src, rename, WithMaxNodes(10))
+	var limitErr *NodeLimitError
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Nil(t, out)
+	out, err = InspectReader(bytes.NewReader(src), "f.go", rename, WithMaxDepth(3))
+	var depthErr *DepthError
+	assert.ErrorAs(t, err, &depthErr)
+	assert.Nil(t, out)
+	out, err = InspectSource(src, func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "c" {
+			panic("oops")
+		}
+		return rename(i, n)
+	}, Recover())
+	var panicErr *VisitorPanicError
+	assert.ErrorAs(t, err, &panicErr)
+	assert.Nil(t, out)
+}
+
+func TestInspectReader(t *testing.T) {
+	noop := func(i Inspector, n ast.Node) bool {
+		return true
+	}
+
+	out, err := InspectReader(strings.NewReader("package foo\n\n// Bar is a bar\nfunc Bar()   {}\n"), "stdin.go", noop)
+	assert.NoError(t, err)
+	assert.Equal(t, "package foo\n\n// Bar is a bar\nfunc Bar() {}\n", string(out))
+
+	_, err = InspectReader(strings.NewReader("package foo\n\nfunc {"), "stdin.go", noop)
+	var scanErrs scanner.ErrorList
+	assert.True(t, errors.As(err, &scanErrs), "Expected the parser's errors to be wrapped")
+	assert.Equal(t, "stdin.go", scanErrs[0].Pos.Filename)
+
+	readErr := errors.New("connection reset")
+	_, err = InspectReader(iotest.ErrReader(readErr), "stdin.go", noop)
+	assert.Equal(t, readErr, err)
+}
+
+func TestInspectFragments(t *testing.T) {
+	rename := func(i Inspector, n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "x" {
+			i.Replace(ast.NewIdent("y"))
+		}
+		return true
+	}
+	formatNode := func(n ast.Node) string {
+		var out strings.Builder
+		assert.NoError(t, format.Node(&out, token.NewFileSet(), n))
+		return out.String()
+	}
+
+	e, err := InspectExprString("f(x) + x", rename)
+	assert.NoError(t, err)
+	assert.Equal(t, "f(y) + y", formatNode(e))
+	s, err := InspectStmtString("return x", rename)
+	assert.NoError(t, err)
+	assert.Equal(t, "return y", formatNode(s))
+	// the root can be replaced
+	e, err = InspectExprString("x", rename)
+	assert.NoError(t, err)
+	assert.Equal(t, "y", formatNode(e))
+
+	var parseErr *ParseError
+	_, err = InspectExprString("f(", rename)
+	assert.True(t, errors.As(err, &parseErr))
+	_, err = InspectStmtString("x := ", rename)
+	assert.True(t, errors.As(err, &parseErr))
+	_, err = InspectStmtString("a(); b()", rename)
+	assert.True(t, errors.As(err, &parseErr))
+	_, err = InspectStmtString("a() }; func g() {", rename)
+	assert.True(t, errors.As(err, &parseErr))
+
+	// an error ending the walk is returned
+	var limitErr *NodeLimitError
+	_, err = InspectExprString("f(x) + x", rename, WithMaxNodes(2))
+	assert.ErrorAs(t, err, &limitErr)
+	_, err = InspectStmtString("return x", rename, WithMaxNodes(1))
+	assert.ErrorAs(t, err, &limitErr)
+}
+
+func TestIdentityWalk(t *testing.T) {
+	// the package's own source and the test samples cover every kind of node between them
+	paths, err := filepath.Glob("*.go")
+	assert.NoError(t, err)
+	samples, err := filepath.Glob("test-samples/*.go.in")
+	assert.NoError(t, err)
+	for _, path := range append(paths, samples...) {
+		src, err := ioutil.ReadFile(path)
+		assert.NoError(t, err, "Error reading input")
+		expected, err := format.Source(src)
+		assert.NoError(t, err, path)
+
+		for _, opts := range [][]Option{nil, {WithReverseOrder()}, {WithScopes()}} {
+			out, err := IdentityWalk(src, opts...)
+			assert.NoError(t, err, path)
+			assert.Equal(t, string(expected), string(out), path)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// InspectFrom walks only the subtree rooted at target, which must be in the AST rooted at root, like Inspect, and
+// returns root. This is much less work than walking all of root again after changing a node deep inside it. The
+// Visitor sees target as it would in a walk of root: the Parent, FieldName and Index of target are those of where
+// root holds it, and the nodes enclosing it are known (for IsConstraintElem and the like). Any changes it makes to
+// target, including deleting it or inserting nodes alongside it, are stored back in its parent. If target is root,
+// this is Inspect, and the replacement for root is returned.
+//
+// Only the nodes in target are walked, so the scopes of those enclosing it aren't opened: with WithScopes, only the
+// declarations within target are in scope.
+//
+// InspectFrom panics if target isn't in the AST rooted at root, or if i wasn't constructed by this package.
+func InspectFrom(i Inspector, root, target ast.Node) ast.Node {
+	impl, ok := i.(*inspectorImpl)
+	if !ok {
+		panic(fmt.Sprintf("astor: InspectFrom can't walk part of an AST with a %T", i))
+	}
+	if root == target {
+		return impl.Inspect(root)
+	}
+	path := pathTo(root, target)
+	if path == nil {
+		panic(fmt.Sprintf("astor: InspectFrom called with a %T which isn't in the AST of its root", target))
+	}
+
+	defer startWalk(i)()
+	outer := impl.slot
+	defer func() {
+		impl.slot, impl.depth, impl.ancestors = outer, 0, impl.ancestors[:0]
+	}()
+	parent := path[len(path)-1]
+	for _, ref := range impl.children(parent) {
+		c := ref.cursor(impl.reverse)
+		found := false
+		for s, child, ok := c.next(); ok; s, child, ok = c.next() {
+			if child != target {
+				// once the list has been edited, its other elements must be added to the new one
+				c.store(child, listEdit{})
+				continue
+			}
+			found = true
+			impl.slot, impl.depth, impl.ancestors = s, len(path), append(impl.ancestors, path...)
+			c.store(impl.inspect(target))
+		}
+		c.finish()
+		if found {
+			break
+		}
+	}
+	return root
+}
+
+// pathTo returns the nodes enclosing target in the AST rooted at root, starting with root, as the Inspector walks
+// it, or nil if target isn't in it.
+func pathTo(root, target ast.Node) []ast.Node {
+	var stack, path []ast.Node
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		switch n {
+		case nil:
+			stack = stack[:len(stack)-1]
+		case target:
+			path = append([]ast.Node{}, stack...)
+			i.Stop()
+		default:
+			stack = append(stack, n)
+		}
+		return true
+	}).Inspect(root)
+	return path
+}
@@ -0,0 +1,45 @@
+package astor
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspectFrom(t *testing.T) {
+	f := parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\ta()\n\tif true {\n\t\tb()\n\t\tc()\n\t}\n}\n\nfunc Baz() {}\n")
+	body := f.Decls[0].(*ast.FuncDecl).Body
+	target := body.List[1].(*ast.IfStmt).Body.List[0]
+
+	var visited []string
+	i := NewInspector(func(i Inspector, n ast.Node) bool {
+		if n == nil {
+			return true
+		}
+		visited = append(visited, Sequence(n)[0])
+		if n == target {
+			assert.Equal(t, body.List[1].(*ast.IfStmt).Body, i.Parent())
+			assert.Equal(t, "List[0]", i.FieldName())
+			i.InsertAfter(&ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent("d")}})
+		}
+		return true
+	})
+	assert.Equal(t, ast.Node(f), InspectFrom(i, f, target))
+	// only the subtree is walked
+	assert.Equal(t, []string{"*ast.ExprStmt", "*ast.CallExpr", "*ast.Ident Name=\"b\""}, visited)
+	assert.Empty(t, Diff(parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\ta()\n\tif true {\n\t\tb()\n\t\td()\n\t\tc()\n\t}\n}\n\nfunc Baz() {}\n"), f))
+
+	// a root replaced by its Visitor
+	replacement := ast.NewIdent("b")
+	assert.Equal(t, ast.Node(replacement), InspectFrom(NewInspector(func(i Inspector, n ast.Node) bool {
+		if n != nil {
+			i.Replace(replacement)
+		}
+		return true
+	}), f, f))
+
+	assert.Panics(t, func() {
+		InspectFrom(i, f, ast.NewIdent("c"))
+	})
+}
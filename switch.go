@@ -0,0 +1,119 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// CaseClauses returns the case clauses of n, an *ast.SwitchStmt or *ast.TypeSwitchStmt, in order. It returns nil for
+// any other statement.
+func CaseClauses(n ast.Stmt) []*ast.CaseClause {
+	var body *ast.BlockStmt
+	switch n := n.(type) {
+	case *ast.SwitchStmt:
+		body = n.Body
+	case *ast.TypeSwitchStmt:
+		body = n.Body
+	}
+	if body == nil {
+		return nil
+	}
+
+	clauses := make([]*ast.CaseClause, 0, len(body.List))
+	for _, s := range body.List {
+		if c, ok := s.(*ast.CaseClause); ok {
+			clauses = append(clauses, c)
+		}
+	}
+	return clauses
+}
+
+// InsertCase adds clause to the switch or type switch i is visiting, at index idx of its clauses, replacing the
+// statement with a copy holding it. The clause added isn't inspected, as with InsertBefore, though the rest of the
+// switch is.
+//
+// Clauses keep the comments a comment map (see WithCommentMap) associates with them as they are inserted, removed and
+// moved, and those of the switch and its body move to the copies, so only the comments of removed clauses are dropped
+// when the file's Comments are rebuilt from the map.
+//
+// InsertCase panics if i isn't visiting a switch, or idx is out of the range of its clauses.
+func InsertCase(i Inspector, idx int, clause *ast.CaseClause) {
+	body := currentSwitch(i, "InsertCase")
+	if idx < 0 || idx > len(body.List) {
+		panic(fmt.Sprintf("astor: InsertCase called with index %d of a switch of %d clauses", idx, len(body.List)))
+	}
+	list := append(append(append([]ast.Stmt{}, body.List[:idx]...), clause), body.List[idx:]...)
+	editSwitch(i, body, list, []ast.Stmt{clause})
+}
+
+// RemoveCase removes the clause at index idx of the clauses of the switch or type switch i is visiting, replacing the
+// statement with a copy without it. The clause removed isn't inspected.
+//
+// RemoveCase panics if i isn't visiting a switch, or idx is out of the range of its clauses.
+func RemoveCase(i Inspector, idx int) {
+	body := currentSwitch(i, "RemoveCase")
+	if idx < 0 || idx >= len(body.List) {
+		panic(fmt.Sprintf("astor: RemoveCase called with index %d of a switch of %d clauses", idx, len(body.List)))
+	}
+	editSwitch(i, body, append(append([]ast.Stmt{}, body.List[:idx]...), body.List[idx+1:]...), nil)
+}
+
+// MoveDefault moves the default clause of the switch or type switch i is visiting to index idx of its clauses (such
+// as the last), replacing the statement with a copy with the clauses reordered. Since a switch only falls back to its
+// default once no case matches, moving it doesn't change what the switch does, unless the clause before or after it
+// ends in a fallthrough. It reports whether the switch has a default clause.
+//
+// MoveDefault panics if i isn't visiting a switch, or idx is out of the range of its clauses.
+func MoveDefault(i Inspector, idx int) bool {
+	body := currentSwitch(i, "MoveDefault")
+	if idx < 0 || idx >= len(body.List) {
+		panic(fmt.Sprintf("astor: MoveDefault called with index %d of a switch of %d clauses", idx, len(body.List)))
+	}
+
+	for l, s := range body.List {
+		if c, ok := s.(*ast.CaseClause); !ok || c.List != nil {
+			continue
+		}
+		if l != idx {
+			list := append(append([]ast.Stmt{}, body.List[:l]...), body.List[l+1:]...)
+			list = append(list[:idx], append([]ast.Stmt{s}, list[idx:]...)...)
+			editSwitch(i, body, list, nil)
+		}
+		return true
+	}
+	return false
+}
+
+// currentSwitch returns the body of the switch or type switch i is visiting.
+func currentSwitch(i Inspector, name string) *ast.BlockStmt {
+	switch n := i.Current().(type) {
+	case *ast.SwitchStmt:
+		return n.Body
+	case *ast.TypeSwitchStmt:
+		return n.Body
+	}
+	panic(fmt.Sprintf("astor: %s called while not visiting a switch", name))
+}
+
+// editSwitch replaces the switch i is visiting, whose body is body, with a copy whose body is a copy holding list, in
+// which added aren't to be inspected.
+func editSwitch(i Inspector, body *ast.BlockStmt, list, added []ast.Stmt) {
+	edited := *body
+	edited.List = list
+	switch n := i.Current().(type) {
+	case *ast.SwitchStmt:
+		s := *n
+		s.Body = &edited
+		i.Replace(&s)
+	case *ast.TypeSwitchStmt:
+		s := *n
+		s.Body = &edited
+		i.Replace(&s)
+	}
+	skipAdded(i, added)
+
+	// the switch's own comments are moved to the copy as it replaces it, but the body isn't replaced by a walk
+	if impl, ok := i.(*inspectorImpl); ok && impl.cmap != nil {
+		impl.moveComments(body, &edited)
+	}
+}
@@ -0,0 +1,92 @@
+package astor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const switchSrc = `package foo
+
+func Bar(x int, y interface{}) {
+	switch x {
+	default:
+		d()
+	// one
+	case 1:
+		a()
+	// two is removed
+	case 2:
+		b()
+	}
+
+	switch y.(type) {
+	case int:
+	}
+}
+`
+
+func TestCaseClauses(t *testing.T) {
+	f := parseEqualSrc(t, switchSrc)
+	body := f.Decls[0].(*ast.FuncDecl).Body
+	assert.Len(t, CaseClauses(body.List[0]), 3)
+	assert.Len(t, CaseClauses(body.List[1]), 1)
+	assert.Nil(t, CaseClauses(&ast.IfStmt{}))
+}
+
+func TestSwitchEdits(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "switch.go", switchSrc, parser.ParseComments)
+	assert.NoError(t, err)
+	cmap := ast.NewCommentMap(fset, f, f.Comments)
+
+	var visited []string
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SwitchStmt:
+			RemoveCase(i, 2)
+			assert.True(t, MoveDefault(i, 1))
+			assert.Panics(t, func() { RemoveCase(i, 2) })
+		case *ast.TypeSwitchStmt:
+			assert.False(t, MoveDefault(i, 0))
+			InsertCase(i, 1, &ast.CaseClause{List: []ast.Expr{ast.NewIdent("string")}})
+			assert.Panics(t, func() { InsertCase(i, 3, &ast.CaseClause{}) })
+		case *ast.Ident:
+			visited = append(visited, n.Name)
+		case *ast.FuncDecl:
+			assert.Panics(t, func() { MoveDefault(i, 0) })
+		}
+		return true
+	}, WithCommentMap(cmap)).Inspect(f)
+	assert.NotContains(t, visited, "b")
+	assert.NotContains(t, visited, "string")
+	assert.Contains(t, visited, "d")
+
+	f.Comments = cmap.Filter(f).Comments()
+	out := new(bytes.Buffer)
+	assert.NoError(t, format.Node(out, fset, f))
+	assert.Empty(t, Diff(parseEqualSrc(t, `package foo
+
+func Bar(x int, y interface{}) {
+	switch x {
+	// one
+	case 1:
+		a()
+	default:
+		d()
+	}
+
+	switch y.(type) {
+	case int:
+	case string:
+	}
+}
+`), f))
+	assert.NotContains(t, out.String(), "two is removed")
+	assert.Contains(t, out.String(), "// one")
+}
@@ -0,0 +1,181 @@
+package astor
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// A TagError is returned when a struct tag isn't a literal in the conventional key:"value" format described by
+// reflect.StructTag. Tag is the tag as it appears in the source.
+type TagError struct {
+	Tag string
+}
+
+func (e *TagError) Error() string {
+	return "astor: malformed struct tag " + e.Tag
+}
+
+// A StructTag is a struct field's tag parsed into its key:"value" pairs, which can be changed and then written back
+// with EditStructTag. The pairs keep the order they were written in; new keys are added at the end.
+type StructTag struct {
+	keys   []string
+	values []string
+}
+
+// ParseStructTag parses the tag literal of a struct field. A nil lit, for a field with no tag, gives an empty
+// StructTag. If lit isn't a string in the conventional format the error is a *TagError.
+func ParseStructTag(lit *ast.BasicLit) (*StructTag, error) {
+	t := &StructTag{}
+	if lit == nil {
+		return t, nil
+	}
+	tag, err := strconv.Unquote(lit.Value)
+	if lit.Kind != token.STRING || err != nil {
+		return nil, &TagError{Tag: lit.Value}
+	}
+
+	// this follows reflect.StructTag.Lookup
+	for tag = strings.TrimLeft(tag, " "); tag != ""; tag = strings.TrimLeft(tag, " ") {
+		l := 0
+		for l < len(tag) && tag[l] > ' ' && tag[l] != ':' && tag[l] != '"' && tag[l] != 0x7f {
+			l++
+		}
+		if l == 0 || l+1 >= len(tag) || tag[l] != ':' || tag[l+1] != '"' {
+			return nil, &TagError{Tag: lit.Value}
+		}
+		key := tag[:l]
+		tag = tag[l+1:]
+
+		l = 1
+		for l < len(tag) && tag[l] != '"' {
+			if tag[l] == '\\' {
+				l++
+			}
+			l++
+		}
+		if l >= len(tag) {
+			return nil, &TagError{Tag: lit.Value}
+		}
+		value, err := strconv.Unquote(tag[:l+1])
+		if err != nil {
+			return nil, &TagError{Tag: lit.Value}
+		}
+		tag = tag[l+1:]
+		t.keys = append(t.keys, key)
+		t.values = append(t.values, value)
+	}
+	return t, nil
+}
+
+// Keys returns the keys of the tag, in order.
+func (t *StructTag) Keys() []string {
+	return append([]string(nil), t.keys...)
+}
+
+// Get returns the value for key, and whether the tag has it.
+func (t *StructTag) Get(key string) (string, bool) {
+	if l := t.index(key); l >= 0 {
+		return t.values[l], true
+	}
+	return "", false
+}
+
+// Set sets the value for key, keeping its place in the tag if it already has it.
+func (t *StructTag) Set(key, value string) {
+	if l := t.index(key); l >= 0 {
+		t.values[l] = value
+		return
+	}
+	t.keys = append(t.keys, key)
+	t.values = append(t.values, value)
+}
+
+// Delete removes key from the tag, if it has it.
+func (t *StructTag) Delete(key string) {
+	if l := t.index(key); l >= 0 {
+		t.keys = append(t.keys[:l], t.keys[l+1:]...)
+		t.values = append(t.values[:l], t.values[l+1:]...)
+	}
+}
+
+func (t *StructTag) index(key string) int {
+	for l, k := range t.keys {
+		if k == key {
+			return l
+		}
+	}
+	return -1
+}
+
+// String returns the tag in the conventional format, as reflect.StructTag would hold it.
+func (t *StructTag) String() string {
+	var b strings.Builder
+	for l, key := range t.keys {
+		if l > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(key)
+		b.WriteByte(':')
+		b.WriteString(strconv.Quote(t.values[l]))
+	}
+	return b.String()
+}
+
+// lit returns the tag as a literal at pos: a raw string unless that can't hold it. An empty tag gives nil.
+func (t *StructTag) lit(pos token.Pos) *ast.BasicLit {
+	if len(t.keys) == 0 {
+		return nil
+	}
+	s := t.String()
+	value := "`" + s + "`"
+	if !strconv.CanBackquote(s) {
+		value = strconv.Quote(s)
+	}
+	return &ast.BasicLit{ValuePos: pos, Kind: token.STRING, Value: value}
+}
+
+// EditStructTag parses the tag of the struct field being visited by i, passes it to f to be changed, and replaces the
+// field with a copy holding the changed tag. i may be visiting either the *ast.Field or its Tag literal, though only
+// the field can be visited when it has no tag yet. A tag whose pairs are all deleted is removed from the field. If the
+// tag can't be parsed the error is a *TagError and f isn't called.
+//
+// EditStructTag panics if i isn't visiting a field or a field's tag.
+func EditStructTag(i Inspector, f func(*StructTag)) error {
+	var old *ast.BasicLit
+	field, ok := i.Current().(*ast.Field)
+	if ok {
+		old = field.Tag
+	} else if field, ok = i.Parent().(*ast.Field); ok && field.Tag != nil && i.Current() == ast.Node(field.Tag) {
+		old = field.Tag
+	} else {
+		panic("astor: EditStructTag called while not visiting a struct field or its tag")
+	}
+
+	t, err := ParseStructTag(old)
+	if err != nil {
+		return err
+	}
+	orig := t.String()
+	f(t)
+	if t.String() == orig {
+		return nil
+	}
+
+	pos := field.Type.End()
+	if old != nil {
+		pos = old.ValuePos
+	}
+	lit := t.lit(pos)
+	if i.Current() == ast.Node(field) {
+		edited := *field
+		edited.Tag = lit
+		i.Replace(&edited)
+	} else if lit == nil {
+		i.Replace(nil)
+	} else {
+		i.Replace(lit)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package astor
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStructTag(t *testing.T) {
+	tag, err := ParseStructTag(&ast.BasicLit{Kind: token.STRING, Value: "`json:\"a,omitempty\"  xml:\"b\\\"c\"`"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"json", "xml"}, tag.Keys())
+	value, ok := tag.Get("xml")
+	assert.True(t, ok)
+	assert.Equal(t, `b"c`, value)
+	_, ok = tag.Get("yaml")
+	assert.False(t, ok)
+
+	tag.Set("yaml", "d")
+	tag.Set("json", "-")
+	tag.Delete("xml")
+	assert.Equal(t, `json:"-" yaml:"d"`, tag.String())
+
+	tag, err = ParseStructTag(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, tag.Keys())
+
+	for _, value := range []string{"`json`", "`json:a`", "`json:\"a`", "1"} {
+		_, err = ParseStructTag(&ast.BasicLit{Kind: token.STRING, Value: value})
+		assert.Equal(t, &TagError{Tag: value}, err, value)
+	}
+}
+
+func TestEditStructTag(t *testing.T) {
+	src := "package foo\n\ntype A struct {\n\tB int `json:\"b\" xml:\"b\"`\n\tC int\n\tD int `json:\"d\"`\n}\n"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "tags.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	visitor := func(i Inspector, n ast.Node) bool {
+		if lit, ok := n.(*ast.BasicLit); ok && i.Parent() != nil {
+			// edit D's tag through its literal
+			if _, ok := i.Parent().(*ast.Field); ok && strings.Contains(lit.Value, "d") {
+				assert.NoError(t, EditStructTag(i, func(tag *StructTag) {
+					tag.Delete("json")
+				}))
+			}
+			return true
+		}
+		if field, ok := n.(*ast.Field); ok && field.Names[0].Name != "D" {
+			assert.NoError(t, EditStructTag(i, func(tag *StructTag) {
+				tag.Set("json", "-")
+			}))
+		}
+		return true
+	}
+	NewInspector(visitor).Inspect(f)
+
+	var out strings.Builder
+	assert.NoError(t, format.Node(&out, fset, f))
+	assert.Equal(t, "package foo\n\ntype A struct {\n\tB int `json:\"-\" xml:\"b\"`\n\tC int `json:\"-\"`\n\tD int\n}\n",
+		out.String())
+}
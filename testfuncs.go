@@ -0,0 +1,130 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TestFuncKind is the kind of function go test runs a function declared in a _test.go file as, if any.
+type TestFuncKind int
+
+const (
+	// NotTestFunc is a function go test doesn't run itself
+	NotTestFunc TestFuncKind = iota
+	// TestFunc is a test, as in func TestXxx(t *testing.T)
+	TestFunc
+	// BenchmarkFunc is a benchmark, as in func BenchmarkXxx(b *testing.B)
+	BenchmarkFunc
+	// FuzzFunc is a fuzz test, as in func FuzzXxx(f *testing.F)
+	FuzzFunc
+	// ExampleFunc is an example, as in func ExampleXxx()
+	ExampleFunc
+	// TestMainFunc is the function which runs the tests of a package, func TestMain(m *testing.M)
+	TestMainFunc
+)
+
+func (k TestFuncKind) String() string {
+	switch k {
+	case NotTestFunc:
+		return "not a test"
+	case TestFunc:
+		return "test"
+	case BenchmarkFunc:
+		return "benchmark"
+	case FuzzFunc:
+		return "fuzz test"
+	case ExampleFunc:
+		return "example"
+	case TestMainFunc:
+		return "TestMain"
+	default:
+		return fmt.Sprintf("TestFuncKind(%d)", int(k))
+	}
+}
+
+// ClassifyTestFunc returns the kind of function go test would run fd as, were it declared in a _test.go file, by its
+// name and signature as go test checks them: a function (not a method, nor generic) named with the prefix of its kind,
+// followed by nothing or by a name which doesn't start with a lower case letter (so TestXxx and Test_xxx, but not
+// Testxxx), taking a single *testing.T, *testing.B or *testing.F and returning nothing; an example, taking and
+// returning nothing; or TestMain, taking a single *testing.M. The testing package is taken to be imported as testing.
+func ClassifyTestFunc(fd *ast.FuncDecl) TestFuncKind {
+	if IsMethod(fd) || fd.Type.TypeParams != nil && len(fd.Type.TypeParams.List) > 0 {
+		return NotTestFunc
+	}
+	if fd.Type.Results != nil && len(fd.Type.Results.List) > 0 {
+		return NotTestFunc
+	}
+
+	name, param := fd.Name.Name, testingParam(fd.Type.Params)
+	switch {
+	case name == "TestMain" && param == "M":
+		return TestMainFunc
+	case testName(name, "Test") && param == "T":
+		return TestFunc
+	case testName(name, "Benchmark") && param == "B":
+		return BenchmarkFunc
+	case testName(name, "Fuzz") && param == "F":
+		return FuzzFunc
+	case testName(name, "Example") && fd.Type.Params.NumFields() == 0:
+		return ExampleFunc
+	}
+	return NotTestFunc
+}
+
+// IsExternalTest reports whether f is in the external test package of a package, whose name is that of the package
+// with a _test suffix, rather than in the package itself.
+func IsExternalTest(f *ast.File) bool {
+	return strings.HasSuffix(f.Name.Name, "_test")
+}
+
+// TestFuncsOnly restricts the walk to the functions of each file which go test runs (see ClassifyTestFunc). Its other
+// top-level declarations, including its imports and any helpers, aren't visited, and are left as they are.
+func TestFuncsOnly() Option {
+	return func(i *inspectorImpl) {
+		i.testFuncsOnly = true
+	}
+}
+
+// testFuncDecl reports whether n, held in s, is part of a test function, as TestFuncsOnly walks it.
+func testFuncDecl(s slot, n ast.Node) bool {
+	if _, ok := s.parent.(*ast.File); !ok || s.field != "Decls" && s.field != "Imports" {
+		return true
+	}
+	fd, ok := n.(*ast.FuncDecl)
+	return ok && ClassifyTestFunc(fd) != NotTestFunc
+}
+
+// testName reports whether name is that of a test function with the passed prefix, such as TestFoo or Test_foo.
+func testName(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
+
+// testingParam returns the name of the type from the testing package that params is a single pointer to (such as T
+// for *testing.T), or "".
+func testingParam(params *ast.FieldList) string {
+	if params.NumFields() != 1 {
+		return ""
+	}
+	star, ok := params.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return ""
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	if x, ok := sel.X.(*ast.Ident); !ok || x.Name != "testing" {
+		return ""
+	}
+	return sel.Sel.Name
+}
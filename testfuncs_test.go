@@ -0,0 +1,98 @@
+package astor
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testFuncsSrc = `package foo_test
+
+import "testing"
+
+var x = 1
+
+func TestMain(m *testing.M) {}
+
+func TestFoo(t *testing.T) {}
+
+func Test_foo(t *testing.T) {}
+
+func Test(t *testing.T) {}
+
+func Testfoo(t *testing.T) {}
+
+func TestBar(b *testing.B) {}
+
+func TestBaz(t *testing.T) error { return nil }
+
+func TestGeneric[T any](t *testing.T) {}
+
+func (s suite) TestMethod(t *testing.T) {}
+
+func BenchmarkFoo(b *testing.B) {}
+
+func FuzzFoo(f *testing.F) {}
+
+func Example() {}
+
+func ExampleFoo_bar() {}
+
+func ExampleBad(t *testing.T) {}
+
+func helper(t *testing.T) {}
+`
+
+func TestClassifyTestFunc(t *testing.T) {
+	f := parseEqualSrc(t, testFuncsSrc)
+	assert.True(t, IsExternalTest(f))
+
+	kinds := map[string]TestFuncKind{}
+	for _, d := range f.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok {
+			kinds[fd.Name.Name] = ClassifyTestFunc(fd)
+		}
+	}
+	assert.Equal(t, map[string]TestFuncKind{
+		"TestMain":       TestMainFunc,
+		"TestFoo":        TestFunc,
+		"Test_foo":       TestFunc,
+		"Test":           TestFunc,
+		"Testfoo":        NotTestFunc,
+		"TestBar":        NotTestFunc,
+		"TestBaz":        NotTestFunc,
+		"TestGeneric":    NotTestFunc,
+		"TestMethod":     NotTestFunc,
+		"BenchmarkFoo":   BenchmarkFunc,
+		"FuzzFoo":        FuzzFunc,
+		"Example":        ExampleFunc,
+		"ExampleFoo_bar": ExampleFunc,
+		"ExampleBad":     NotTestFunc,
+		"helper":         NotTestFunc,
+	}, kinds)
+	assert.Equal(t, "benchmark", BenchmarkFunc.String())
+}
+
+func TestTestFuncsOnly(t *testing.T) {
+	f := parseEqualSrc(t, testFuncsSrc)
+	for _, iterative := range []bool{false, true} {
+		var visited []string
+		i := NewInspector(func(i Inspector, n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				visited = append(visited, n.Name.Name)
+			case *ast.GenDecl:
+				visited = append(visited, n.Tok.String())
+			}
+			return true
+		}, TestFuncsOnly())
+		if iterative {
+			InspectIterative(i, f)
+		} else {
+			i.Inspect(f)
+		}
+		assert.Equal(t, []string{"TestMain", "TestFoo", "Test_foo", "Test", "BenchmarkFoo", "FuzzFoo", "Example",
+			"ExampleFoo_bar"}, visited)
+	}
+}
@@ -0,0 +1,30 @@
+package astor
+
+import (
+	"go/ast"
+)
+
+// Transform rewrites the AST rooted at node (including node itself) with f, returning the modified tree. f is called
+// for each node in pre-order and returns the node's replacement and true to replace it, or false to leave it alone.
+// The children of a replacement are transformed in turn, but the replacement itself isn't passed back to f; so a
+// replacement which wraps the node it replaces will see that node again, and f must not wrap it a second time.
+//
+// As with Replace, a replacement must fit where the node it replaces is held.
+func Transform(node ast.Node, f func(ast.Node) (ast.Node, bool)) ast.Node {
+	t := &transformer{f: f}
+	return NewInspector(t.visit).Inspect(node)
+}
+
+type transformer struct {
+	f func(ast.Node) (ast.Node, bool)
+}
+
+func (t *transformer) visit(i Inspector, n ast.Node) bool {
+	if n == nil {
+		return true
+	}
+	if r, ok := t.f(n); ok {
+		i.Replace(r)
+	}
+	return true
+}
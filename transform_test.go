@@ -0,0 +1,39 @@
+package astor
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransform(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "transform.go", "package foo\n\nvar a = b(c, d)\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var seen []string
+	result := Transform(f, func(n ast.Node) (ast.Node, bool) {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			// the replacement's children are transformed, but not the replacement itself
+			return &ast.CallExpr{Fun: ast.NewIdent("e"), Args: n.Args}, true
+		case *ast.Ident:
+			seen = append(seen, n.Name)
+			if n.Name == "c" || n.Name == "d" {
+				return ast.NewIdent(strings.ToUpper(n.Name)), true
+			}
+		}
+		return nil, false
+	})
+	assert.Same(t, f, result)
+	assert.Equal(t, []string{"foo", "a", "e", "c", "d"}, seen)
+
+	var out strings.Builder
+	assert.NoError(t, format.Node(&out, fset, result))
+	assert.Equal(t, "package foo\n\nvar a = e(C, D)\n", out.String())
+}
@@ -0,0 +1,40 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// ReplaceTyped replaces the node being visited by i with n, like Replace, but checks the type T of the replacement
+// rather than that of n itself: it panics if a T can't always be held where the node is, even if n could be. So a
+// Visitor replacing an expression with an ast.Expr, or an *ast.Ident, is checked whatever node it happens to be passed,
+// and a mistake is found the first time the code runs rather than with the first input to exercise it. A nil n (such
+// as a nil *ast.BlockStmt) removes a node held in a field which may be nil, as Replace(nil) does.
+//
+// ReplaceTyped[ast.Node] can only replace the root of a walk; to replace a node with one whose type is only known at
+// run time, use Replace.
+func ReplaceTyped[T ast.Node](i Inspector, n T) {
+	if !CanReplace[T](i) {
+		s := i.(*inspectorImpl).slot
+		panic(fmt.Sprintf("astor: ReplaceTyped cannot replace %s (%s) with %s", s, s.typ, slotType[T]()))
+	}
+	if isNil(n) {
+		i.Replace(nil)
+		return
+	}
+	i.Replace(n)
+}
+
+// CanReplace reports whether ReplaceTyped can replace the node being visited by i with a T: whether a T can always be
+// held where the node is. It is always true for an Inspector not constructed by this package, which can't tell.
+//
+// CanReplace panics if no node is being visited.
+func CanReplace[T ast.Node](i Inspector) bool {
+	impl, ok := i.(*inspectorImpl)
+	if !ok {
+		return true
+	}
+	impl.enter("CanReplace")
+	defer impl.exit()
+	return impl.slot.typ == nil || slotType[T]().AssignableTo(impl.slot.typ)
+}
@@ -0,0 +1,58 @@
+package astor
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceTyped(t *testing.T) {
+	src := "package foo\n\nfunc Bar() {\n\tif a {\n\t\tb(c, d)\n\t} else {\n\t}\n}\n"
+	f, err := parser.ParseFile(token.NewFileSet(), "typed.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	NewInspector(func(i Inspector, n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			if n.Name == "c" {
+				assert.True(t, CanReplace[ast.Expr](i))
+				assert.True(t, CanReplace[*ast.CallExpr](i))
+				assert.False(t, CanReplace[ast.Stmt](i))
+				assert.False(t, CanReplace[ast.Node](i))
+				ReplaceTyped(i, Call(Sel(Id("log"), "Print"), Str("c")))
+			}
+			if n.Name == "d" {
+				var e ast.Expr = Id("e")
+				ReplaceTyped(i, e)
+			}
+		case *ast.BlockStmt:
+			if i.FieldName() == "Else" {
+				ReplaceTyped[*ast.BlockStmt](i, nil)
+			}
+		}
+		return true
+	}).Inspect(f)
+	assert.Empty(t, Diff(parseEqualSrc(t, "package foo\n\nfunc Bar() {\n\tif a {\n\t\tb(log.Print(\"c\"), e)\n\t}\n}\n"), f))
+
+	// the type of the replacement is checked, not the node passed
+	assert.PanicsWithValue(t, "astor: ReplaceTyped cannot replace *ast.CallExpr.Args[1] (ast.Expr) with ast.Node", func() {
+		NewInspector(func(i Inspector, n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && id.Name == "e" {
+				var replacement ast.Node = Id("f")
+				ReplaceTyped(i, replacement)
+			}
+			return true
+		}).Inspect(f)
+	})
+
+	// the root may be replaced with anything
+	assert.Equal(t, ast.Node(f.Name), NewInspector(func(i Inspector, n ast.Node) bool {
+		if n == ast.Node(f) {
+			ReplaceTyped[ast.Node](i, f.Name)
+		}
+		return false
+	}).Inspect(f))
+}
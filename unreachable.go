@@ -0,0 +1,75 @@
+package astor
+
+import (
+	"go/ast"
+)
+
+// noReturnFuncs are the functions which never return, by package (with "" for builtins).
+var noReturnFuncs = map[string]map[string]bool{
+	"":        {"panic": true},
+	"log":     {"Fatal": true, "Fatalf": true, "Fatalln": true, "Panic": true, "Panicf": true, "Panicln": true},
+	"os":      {"Exit": true},
+	"runtime": {"Goexit": true},
+}
+
+// FindUnreachable returns the statements in the AST rooted at node which follow a terminating statement in the same
+// block (or case clause), in pre-order. A terminating statement is a return, break, continue, goto or fallthrough, or
+// a call to a function which never returns: panic, os.Exit, runtime.Goexit, and log's Fatal and Panic functions.
+// Calls are recognised by name, so a package imported under another name, or a shadowing declaration, fools it. A
+// labelled statement may be the target of a goto, so is taken to be reachable, as are the statements after it.
+func FindUnreachable(node ast.Node) []ast.Stmt {
+	u := &unreachableFinder{}
+	NewInspector(u.visit).Inspect(node)
+	return u.stmts
+}
+
+type unreachableFinder struct {
+	stmts []ast.Stmt
+}
+
+func (u *unreachableFinder) visit(i Inspector, n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.BlockStmt:
+		u.scan(n.List)
+	case *ast.CaseClause:
+		u.scan(n.Body)
+	case *ast.CommClause:
+		u.scan(n.Body)
+	}
+	return true
+}
+
+func (u *unreachableFinder) scan(list []ast.Stmt) {
+	unreachable := false
+	for _, stmt := range list {
+		if _, ok := stmt.(*ast.LabeledStmt); ok {
+			unreachable = false
+		}
+		if unreachable {
+			u.stmts = append(u.stmts, stmt)
+		} else if isTerminating(stmt) {
+			unreachable = true
+		}
+	}
+}
+
+// isTerminating reports whether control never passes from stmt to the statement following it.
+func isTerminating(stmt ast.Stmt) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStmt, *ast.BranchStmt:
+		return true
+	case *ast.ExprStmt:
+		call, ok := stmt.X.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			return noReturnFuncs[""][fun.Name]
+		case *ast.SelectorExpr:
+			pkg, ok := fun.X.(*ast.Ident)
+			return ok && noReturnFuncs[pkg.Name][fun.Sel.Name]
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package astor
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const unreachableSrc = `package foo
+
+import "os"
+
+func Bar(x int) int {
+	switch x {
+	case 1:
+		os.Exit(1)
+		a()
+	case 2:
+		break
+	}
+	for {
+		if x > 0 {
+			continue
+			b()
+		}
+		break
+	}
+	goto done
+	c()
+done:
+	d()
+	return x
+	panic("e")
+	e()
+}
+`
+
+func TestFindUnreachable(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "unreachable.go", unreachableSrc, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var names []string
+	for _, stmt := range FindUnreachable(f) {
+		names = append(names, calledName(stmt))
+	}
+	assert.Equal(t, []string{"c", "panic", "e", "a", "b"}, names)
+}
+
+func TestIsTerminating(t *testing.T) {
+	assert.True(t, isTerminating(&ast.ReturnStmt{}))
+	assert.True(t, isTerminating(&ast.BranchStmt{Tok: token.FALLTHROUGH}))
+	assert.True(t, isTerminating(callStmt("panic")))
+	assert.False(t, isTerminating(callStmt("print")))
+	assert.False(t, isTerminating(&ast.EmptyStmt{}))
+}
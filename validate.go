@@ -0,0 +1,213 @@
+package astor
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+)
+
+// A ValidationError describes a node which makes the AST holding it invalid Go.
+type ValidationError struct {
+	Node   ast.Node
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("astor: invalid %T: %s", e.Node, e.Reason)
+}
+
+// Validate checks the AST rooted at node for rewrites which go/format would print but which don't compile, returning
+// a *ValidationError for each problem found, in pre-order. It checks structure only, without type information, so
+// catches a curated set of mistakes rather than everything the compiler would reject:
+//
+//   - required children which are missing (eg. the X of an *ast.BinaryExpr, or the Cond of an *ast.IfStmt)
+//   - identifiers which aren't valid Go identifiers
+//   - assignments, ++/-- and range clauses to things which can't be assigned to (eg. a literal or call), short
+//     variable declarations of anything but identifiers, and mismatched numbers of operands
+//   - expression statements which aren't calls or receives
+//   - declarations holding specs of the wrong kind for their token, and import declarations inside functions
+//   - goto statements without labels
+func Validate(node ast.Node) []error {
+	v := &validator{}
+	NewInspector(v.visit).Inspect(node)
+	return v.errs
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) fail(n ast.Node, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{Node: n, Reason: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) require(n ast.Node, field string, child ast.Node) {
+	if isNil(child) {
+		v.fail(n, "missing %s", field)
+	}
+}
+
+func (v *validator) visit(i Inspector, node ast.Node) bool {
+	if isNil(node) {
+		// a missing child, reported by its parent
+		return false
+	}
+
+	switch n := node.(type) {
+	case *ast.Ident:
+		if n.Name != "_" && !token.IsIdentifier(n.Name) {
+			v.fail(n, "%q isn't an identifier", n.Name)
+		}
+
+	case *ast.BinaryExpr:
+		v.require(n, "X", n.X)
+		v.require(n, "Y", n.Y)
+	case *ast.UnaryExpr:
+		v.require(n, "X", n.X)
+	case *ast.StarExpr:
+		v.require(n, "X", n.X)
+	case *ast.ParenExpr:
+		v.require(n, "X", n.X)
+	case *ast.SelectorExpr:
+		v.require(n, "X", n.X)
+		v.require(n, "Sel", n.Sel)
+	case *ast.IndexExpr:
+		v.require(n, "X", n.X)
+		v.require(n, "Index", n.Index)
+	case *ast.CallExpr:
+		v.require(n, "Fun", n.Fun)
+	case *ast.KeyValueExpr:
+		v.require(n, "Key", n.Key)
+		v.require(n, "Value", n.Value)
+
+	case *ast.ExprStmt:
+		if !isCallOrReceive(n.X) {
+			v.fail(n, "%T isn't a call or receive, so can't be used as a statement", n.X)
+		}
+	case *ast.IncDecStmt:
+		if !isAssignable(n.X) {
+			v.fail(n, "cannot %s %T", n.Tok, n.X)
+		}
+	case *ast.AssignStmt:
+		v.validateAssign(n)
+	case *ast.RangeStmt:
+		v.require(n, "X", n.X)
+		for _, x := range []ast.Expr{n.Key, n.Value} {
+			if x == nil {
+				continue
+			}
+			if _, ok := x.(*ast.Ident); n.Tok == token.DEFINE && !ok {
+				v.fail(n, "cannot declare %T", x)
+			} else if !isAssignable(x) {
+				v.fail(n, "cannot assign to %T", x)
+			}
+		}
+	case *ast.IfStmt:
+		v.require(n, "Cond", n.Cond)
+	case *ast.BranchStmt:
+		if n.Tok == token.GOTO && n.Label == nil {
+			v.fail(n, "goto without a label")
+		}
+
+	case *ast.GenDecl:
+		for _, spec := range n.Specs {
+			if !specMatches(n.Tok, spec) {
+				v.fail(n, "%s declaration holds %T", n.Tok, spec)
+			}
+		}
+	case *ast.DeclStmt:
+		if d, ok := n.Decl.(*ast.GenDecl); !ok || d.Tok == token.IMPORT {
+			v.fail(n, "%s can't be declared in a function", declName(n.Decl))
+		}
+	case *ast.ValueSpec:
+		if len(n.Names) == 0 {
+			v.fail(n, "no names declared")
+		}
+		if len(n.Values) > 1 && len(n.Values) != len(n.Names) {
+			v.fail(n, "%d names declared with %d values", len(n.Names), len(n.Values))
+		}
+	case *ast.TypeSpec:
+		v.require(n, "Name", n.Name)
+		v.require(n, "Type", n.Type)
+	}
+	return true
+}
+
+func (v *validator) validateAssign(n *ast.AssignStmt) {
+	if len(n.Lhs) == 0 || len(n.Rhs) == 0 {
+		v.fail(n, "%d operands assigned %d values", len(n.Lhs), len(n.Rhs))
+		return
+	}
+	if n.Tok != token.ASSIGN && n.Tok != token.DEFINE {
+		if len(n.Lhs) != 1 || len(n.Rhs) != 1 {
+			v.fail(n, "%s with %d operands and %d values", n.Tok, len(n.Lhs), len(n.Rhs))
+		}
+	} else if len(n.Rhs) > 1 && len(n.Lhs) != len(n.Rhs) {
+		// a single value on the right may be a call with several results
+		v.fail(n, "%d operands assigned %d values", len(n.Lhs), len(n.Rhs))
+	}
+
+	for _, x := range n.Lhs {
+		if _, ok := x.(*ast.Ident); n.Tok == token.DEFINE && !ok {
+			v.fail(n, "cannot declare %T", x)
+		} else if !isAssignable(x) {
+			v.fail(n, "cannot assign to %T", x)
+		}
+	}
+}
+
+// isAssignable reports whether x could be assigned to: a name, index, field or pointer indirection.
+func isAssignable(x ast.Expr) bool {
+	switch x := x.(type) {
+	case *ast.Ident, *ast.IndexExpr, *ast.SelectorExpr, *ast.StarExpr:
+		return true
+	case *ast.ParenExpr:
+		return isAssignable(x.X)
+	}
+	return false
+}
+
+// isCallOrReceive reports whether x is a call or channel receive, which may be used as a statement.
+func isCallOrReceive(x ast.Expr) bool {
+	switch x := x.(type) {
+	case *ast.CallExpr:
+		return true
+	case *ast.UnaryExpr:
+		return x.Op == token.ARROW
+	case *ast.ParenExpr:
+		return isCallOrReceive(x.X)
+	}
+	return false
+}
+
+// specMatches reports whether spec may be held by a declaration with the passed token.
+func specMatches(tok token.Token, spec ast.Spec) bool {
+	switch spec.(type) {
+	case *ast.ImportSpec:
+		return tok == token.IMPORT
+	case *ast.ValueSpec:
+		return tok == token.CONST || tok == token.VAR
+	case *ast.TypeSpec:
+		return tok == token.TYPE
+	}
+	return false
+}
+
+func declName(d ast.Decl) string {
+	switch d := d.(type) {
+	case *ast.GenDecl:
+		return d.Tok.String()
+	case *ast.FuncDecl:
+		return "func"
+	}
+	return fmt.Sprintf("%T", d)
+}
+
+// isNil reports whether n is nil, including a nil pointer held in a non-nil interface.
+func isNil(n ast.Node) bool {
+	if n == nil {
+		return true
+	}
+	return reflect.ValueOf(n).IsNil()
+}
@@ -0,0 +1,61 @@
+package astor
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	src := "package foo\n\nfunc Bar(x []int) {\n\ta, b := x[0], x[1]\n\ta += b\n\tx[0]++\n\tbaz()\n\tfor k := range x {\n\t\t_ = k\n\t}\n}\n"
+	f, err := parser.ParseFile(token.NewFileSet(), "valid.go", src, parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+	assert.Empty(t, Validate(f))
+
+	// Rewrite the function's statements into invalid ones
+	visitor := func(i Inspector, n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				i.Replace(&ast.AssignStmt{
+					Lhs: []ast.Expr{&ast.BasicLit{Kind: token.INT, Value: "1"}, ast.NewIdent("b")},
+					Tok: token.DEFINE,
+					Rhs: n.Rhs,
+				})
+			} else {
+				i.Replace(&ast.AssignStmt{Lhs: n.Lhs, Tok: n.Tok, Rhs: append(n.Rhs, ast.NewIdent("c"))})
+			}
+		case *ast.IncDecStmt:
+			i.Replace(&ast.IncDecStmt{X: &ast.CallExpr{Fun: ast.NewIdent("baz")}, Tok: n.Tok})
+		case *ast.ExprStmt:
+			i.Replace(&ast.ExprStmt{X: ast.NewIdent("baz")})
+		case *ast.RangeStmt:
+			i.Replace(&ast.RangeStmt{Key: ast.NewIdent("2k"), Tok: n.Tok, X: n.X, Body: n.Body})
+			return false
+		}
+		return true
+	}
+	NewInspector(visitor).Inspect(f)
+
+	var reasons []string
+	for _, err := range Validate(f) {
+		reasons = append(reasons, err.Error())
+	}
+	assert.Equal(t, []string{
+		"astor: invalid *ast.AssignStmt: cannot declare *ast.BasicLit",
+		"astor: invalid *ast.AssignStmt: += with 1 operands and 2 values",
+		"astor: invalid *ast.IncDecStmt: cannot ++ *ast.CallExpr",
+		"astor: invalid *ast.ExprStmt: *ast.Ident isn't a call or receive, so can't be used as a statement",
+		"astor: invalid *ast.Ident: \"2k\" isn't an identifier",
+	}, reasons)
+
+	// Missing children are reported by their parents, not visited
+	errs := Validate(&ast.BinaryExpr{X: ast.NewIdent("a"), Op: token.ADD})
+	assert.Equal(t, []error{&ValidationError{Node: errs[0].(*ValidationError).Node, Reason: "missing Y"}}, errs)
+	errs = Validate(&ast.SelectorExpr{X: ast.NewIdent("a")})
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "missing Sel", errs[0].(*ValidationError).Reason)
+}
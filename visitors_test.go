@@ -0,0 +1,137 @@
+package astor
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipIf(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "skip.go", "package foo\n\nfunc Bar() { a() }\n\nvar b = c\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var idents []string
+	depth := 0
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n == nil {
+			depth--
+			return true
+		}
+		depth++
+		if id, ok := n.(*ast.Ident); ok {
+			idents = append(idents, id.Name)
+		}
+		return true
+	}
+	isFunc := func(n ast.Node) bool {
+		_, ok := n.(*ast.FuncDecl)
+		return ok
+	}
+
+	NewInspector(SkipIf(visitor, isFunc)).Inspect(f)
+	assert.Equal(t, []string{"foo", "b", "c"}, idents)
+	// every node passed to the visitor was finished with a nil visit
+	assert.Equal(t, 0, depth)
+}
+
+func TestOnly(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "only.go", "package foo\n\nfunc Bar() { a(b()) }\n\nvar c = d()\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var calls []string
+	depth := 0
+	visitor := func(i Inspector, n ast.Node) bool {
+		if n == nil {
+			depth--
+			return true
+		}
+		call := n.(*ast.CallExpr)
+		name := call.Fun.(*ast.Ident).Name
+		calls = append(calls, name)
+		if name == "d" {
+			i.Delete()
+			return true
+		}
+		depth++
+		return true
+	}
+
+	NewInspector(Only(visitor, (*ast.CallExpr)(nil), (*ast.GoStmt)(nil))).Inspect(f)
+	assert.Equal(t, []string{"a", "b", "d"}, calls)
+	// every node the visitor recursed into was finished with a nil visit, and no others
+	assert.Equal(t, 0, depth)
+}
+
+func TestInspectIdents(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "idents.go", "package foo\n\nfunc Bar(a int) { b(a) }\n\nvar c = a\n", parserFlags)
+	assert.NoError(t, err, "Error parsing input")
+
+	var names []string
+	InspectIdents(f, func(i Inspector, id *ast.Ident) bool {
+		names = append(names, id.Name)
+		if id.Name == "a" {
+			i.Replace(ast.NewIdent("d"))
+		}
+		return true
+	})
+	assert.Equal(t, []string{"foo", "Bar", "a", "int", "b", "a", "c", "a"}, names)
+	assert.Equal(t, []string{"foo", "Bar", "d", "int", "b", "d", "c", "d"}, identNames(f))
+
+	// returning false stops the walk
+	names = nil
+	InspectIdents(f, func(i Inspector, id *ast.Ident) bool {
+		names = append(names, id.Name)
+		return id.Name != "int"
+	})
+	assert.Equal(t, []string{"foo", "Bar", "d", "int"}, names)
+}
+
+func identNames(n ast.Node) []string {
+	var names []string
+	for _, id := range Collect(n, func(n ast.Node) bool { _, ok := n.(*ast.Ident); return ok }) {
+		names = append(names, id.(*ast.Ident).Name)
+	}
+	return names
+}
+
+func benchmarkIdents(b *testing.B, walk func(f *ast.File, match func(*ast.Ident))) {
+	src, err := ioutil.ReadFile("inspect.go")
+	assert.NoError(b, err, "Error reading input")
+	f, err := parser.ParseFile(token.NewFileSet(), "inspect.go", src, parserFlags)
+	assert.NoError(b, err, "Error parsing input")
+	matches := 0
+	match := func(id *ast.Ident) {
+		if id.Name == "inspectorImpl" {
+			matches++
+		}
+	}
+
+	b.ResetTimer()
+	for l := 0; l < b.N; l++ {
+		walk(f, match)
+	}
+}
+
+func BenchmarkIdentsVisitor(b *testing.B) {
+	benchmarkIdents(b, func(f *ast.File, match func(*ast.Ident)) {
+		NewInspector(func(i Inspector, n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				match(id)
+			}
+			return true
+		}).Inspect(f)
+	})
+}
+
+func BenchmarkInspectIdents(b *testing.B) {
+	benchmarkIdents(b, func(f *ast.File, match func(*ast.Ident)) {
+		InspectIdents(f, func(i Inspector, id *ast.Ident) bool {
+			match(id)
+			return true
+		})
+	})
+}